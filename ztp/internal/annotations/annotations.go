@@ -20,5 +20,16 @@ package annotations
 // wiped.
 const Wiped = prefix + "/wiped"
 
+// DependsOn is the annotation that can be added to templates to indicate that an object must be
+// created after other objects generated by the same templates. The value is a comma separated list
+// of references of the form 'Kind/name' for objects in the same namespace or cluster scoped
+// objects, or 'Kind/namespace/name' for objects in other namespaces.
+const DependsOn = prefix + "/depends-on"
+
+// Health is the annotation that can be added to templates to customize how the applier checks if
+// an object is healthy. The value is a jq expression that is evaluated against the current state of
+// the object and that should return true when the object is healthy.
+const Health = prefix + "/health"
+
 // prefix is the prefix for all the annotations.
 const prefix = "ztpfw"
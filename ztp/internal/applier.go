@@ -21,53 +21,113 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/util/retry"
 	clnt "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/annotations"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/jq"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/templating"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
 )
 
 // ApplierBuilder contains the data and logic needed to create an object that knows how create
 // Kubernetes API objects from templates. Don't create instances of this type directly, use the
 // NewApplier function instead.
 type ApplierBuilder struct {
-	logger    logr.Logger
-	client    clnt.WithWatch
-	labels    map[string]string
-	fsys      fs.FS
-	root      string
-	dirs      []string
-	listeners []func(*ApplierEvent)
+	logger       logr.Logger
+	client       clnt.WithWatch
+	labels       map[string]string
+	fsys         fs.FS
+	root         string
+	overlay      string
+	patches      []*ApplierPatch
+	patchFiles   []string
+	dirs         []string
+	listeners    []func(*ApplierEvent)
+	waiters      []func(*WaiterEvent)
+	mode         ApplierMode
+	conflicts    ApplierConflictPolicy
+	fieldManager string
+	dryRun       bool
+	inventory    string
+	prune        bool
+	wait         bool
+	workers      int
+	crdTimeout   time.Duration
+	forceCRDs    bool
+	stageTimeout time.Duration
 }
 
 // Applier knows how to create Kubernetes API objects from templates. Don't create instances of
 // this type directly, use the NewApplier function instead.
 type Applier struct {
-	logger    logr.Logger
-	client    clnt.WithWatch
-	labels    map[string]string
-	jq        *jq.Tool
-	engine    *templating.Engine
-	templates []string
-	listeners []func(*ApplierEvent)
+	logger       logr.Logger
+	client       clnt.WithWatch
+	labels       map[string]string
+	jq           *jq.Tool
+	engine       *templating.Engine
+	patches      []*ApplierPatch
+	templates    []string
+	listeners    []func(*ApplierEvent)
+	mode         ApplierMode
+	conflicts    ApplierConflictPolicy
+	fieldManager string
+	dryRun       bool
+	inventory    string
+	prune        bool
+	wait         bool
+	workers      int
+	crds         *CRDDeleter
+	waiter       *Waiter
+	eventLock    sync.Mutex
 }
 
+// ApplierMode defines the possible ways to create the objects.
+type ApplierMode string
+
+const (
+	// ApplierModeCreate indicates that objects will only be created, and that objects that
+	// already exist will not be modified. This is the default.
+	ApplierModeCreate ApplierMode = "create"
+
+	// ApplierModeServerSide indicates that objects will be created or updated using server
+	// side apply, so that changes in the templates are applied to the existing objects.
+	ApplierModeServerSide ApplierMode = "server-side"
+)
+
+// ApplierConflictPolicy defines what to do when server side apply detects that a field is also
+// managed by some other field manager.
+type ApplierConflictPolicy string
+
+const (
+	// ApplierConflictFail indicates that the apply should fail when there are conflicts. This
+	// is the default.
+	ApplierConflictFail ApplierConflictPolicy = "fail"
+
+	// ApplierConflictForce indicates that the applier should take the ownership of the fields
+	// that are in conflict.
+	ApplierConflictForce ApplierConflictPolicy = "force"
+)
+
 // ApplierEventType defines the possible types of events.
 type ApplierEventType string
 
@@ -82,6 +142,29 @@ const (
 	// ApplierCreateError indicates that an error occurred while trying to create an object.
 	ApplierCreateError ApplierEventType = "CreateError"
 
+	// ApplierObjectUpdated indicates that an object already existed and that some of its fields
+	// have been changed by server side apply. The names of the changed fields are in the Changes
+	// field of the event.
+	ApplierObjectUpdated ApplierEventType = "ObjectUpdated"
+
+	// ApplierObjectUnchanged indicates that an object already existed and that server side apply
+	// didn't change any of its fields.
+	ApplierObjectUnchanged ApplierEventType = "ObjectUnchanged"
+
+	// ApplierApplyError indicates that an error occurred while trying to apply an object using
+	// server side apply, for example because of a conflict with other field manager.
+	ApplierApplyError ApplierEventType = "ApplyError"
+
+	// ApplierObjectDiff indicates that in dry run mode the applier found differences between the
+	// live object and the object that would result from applying or deleting it. The differences
+	// are in the Diff field of the event, using the unified diff format.
+	ApplierObjectDiff ApplierEventType = "ObjectDiff"
+
+	// ApplierObjectStale indicates that an object was created by a previous run, according to
+	// the inventory, but that it is no longer generated by the templates. These objects are
+	// deleted only when pruning is enabled.
+	ApplierObjectStale ApplierEventType = "ObjectStale"
+
 	// ApplierStatusUpdated indicates that the status of an object has been updated.
 	ApplierStatusUpdated ApplierEventType = "StatusUpdated"
 
@@ -102,6 +185,22 @@ const (
 	// to exist before trying to create an object.
 	ApplierWaitingCRD ApplierEventType = "WaitingCRD"
 
+	// ApplierWaitingHealthy indicates that the applier is waiting for an object to be healthy.
+	ApplierWaitingHealthy ApplierEventType = "WaitingHealthy"
+
+	// ApplierObjectHealthy indicates that an object that wasn't healthy when it was first
+	// checked is now healthy.
+	ApplierObjectHealthy ApplierEventType = "ObjectHealthy"
+
+	// ApplierWaitingDrain indicates that the applier is waiting for the objects of the kind
+	// defined by a custom resource definition to disappear before deleting the definition.
+	ApplierWaitingDrain ApplierEventType = "WaitingDrain"
+
+	// ApplierFinalizersRemoved indicates that the finalizers of an object have been removed
+	// because it didn't disappear in time. The names of the removed finalizers are in the
+	// Finalizers field of the event.
+	ApplierFinalizersRemoved ApplierEventType = "FinalizersRemoved"
+
 	// ApplierWaitingDisappear indicates that the applier is waiting for an object to completely
 	// disappear before deleting the namespace.
 	ApplierWaitingDisappear ApplierEventType = "WaitingDisappear"
@@ -109,15 +208,24 @@ const (
 
 // ApplierEvents represents an event generated by the applier to inform of the progress of its work.
 type ApplierEvent struct {
-	Type   ApplierEventType
-	Object *unstructured.Unstructured
-	Error  error
+	Type       ApplierEventType
+	Object     *unstructured.Unstructured
+	Error      error
+	Changes    []string
+	Diff       string
+	Finalizers []string
 }
 
 // NewApplier creates a builder that can then be used to create an object that knows how create
 // Kubernetes API objects from templates.
 func NewApplier() *ApplierBuilder {
-	return &ApplierBuilder{}
+	return &ApplierBuilder{
+		mode:         ApplierModeCreate,
+		conflicts:    ApplierConflictFail,
+		fieldManager: applierDefaultFieldManager,
+		workers:      applierDefaultWorkers,
+		crdTimeout:   crdDeleterDefaultTimeout,
+	}
 }
 
 // SetLogger sets the logger that the renderer will use to write log messages. This is mandatory.
@@ -139,6 +247,31 @@ func (b *ApplierBuilder) SetRoot(value string) *ApplierBuilder {
 	return b
 }
 
+// SetOverlay sets a directory of the local file system containing files that will be layered over
+// the templates filesystem. Paths inside this directory are relative to the root set with the
+// SetRoot method. Files with the same path than a template replace it, and new files are added.
+// New files are written to the log, as they may also be files that were intended to replace a
+// template but have a wrong path. This is optional.
+func (b *ApplierBuilder) SetOverlay(value string) *ApplierBuilder {
+	b.overlay = value
+	return b
+}
+
+// AddPatch adds a patch that will be applied to the rendered objects that match its target,
+// before they are created. This is optional.
+func (b *ApplierBuilder) AddPatch(value *ApplierPatch) *ApplierBuilder {
+	b.patches = append(b.patches, value)
+	return b
+}
+
+// AddPatchFile adds a YAML file containing patches that will be applied to the rendered objects
+// that match their targets, before they are created. See the documentation of the ApplierPatch
+// type for the format of the file. This is optional.
+func (b *ApplierBuilder) AddPatchFile(value string) *ApplierBuilder {
+	b.patchFiles = append(b.patchFiles, value)
+	return b
+}
+
 // SetDir sets a directory within the templates filesystem root that contains templates for the
 // Kubernetes API objects. This is optional. If no directory is specified then all the templates in
 // the filesystem will be used. Note that this removes all previously configured directories, use
@@ -212,6 +345,165 @@ func (b *ApplierBuilder) AddListener(value func(*ApplierEvent)) *ApplierBuilder
 	return b
 }
 
+// AddWaiterListener adds a function that will be called when the applier starts or finishes
+// waiting for objects, for example for CRDs to be established or for objects to be healthy. This
+// is optional.
+func (b *ApplierBuilder) AddWaiterListener(value func(*WaiterEvent)) *ApplierBuilder {
+	b.waiters = append(b.waiters, value)
+	return b
+}
+
+// SetMode sets the way objects will be created. The default is ApplierModeCreate, which only
+// creates objects that don't exist yet.
+func (b *ApplierBuilder) SetMode(value ApplierMode) *ApplierBuilder {
+	b.mode = value
+	return b
+}
+
+// SetConflictPolicy sets what to do when server side apply finds fields that are managed by other
+// field manager. The default is ApplierConflictFail.
+func (b *ApplierBuilder) SetConflictPolicy(value ApplierConflictPolicy) *ApplierBuilder {
+	b.conflicts = value
+	return b
+}
+
+// SetFieldManager sets the name of the field manager used for server side apply. The default is
+// 'ztp'.
+func (b *ApplierBuilder) SetFieldManager(value string) *ApplierBuilder {
+	b.fieldManager = value
+	return b
+}
+
+// SetDryRun sets the dry run flag. When it is true the applier doesn't create, update or delete
+// objects, it only compares the live objects with the result that the operation would have, and
+// generates events containing the differences. The default is false.
+func (b *ApplierBuilder) SetDryRun(value bool) *ApplierBuilder {
+	b.dryRun = value
+	return b
+}
+
+// SetInventory sets the name of the inventory where the applier records the objects that it
+// creates. The inventory is a config map in the 'ztpfw-inventory' namespace, and it is used to
+// find the objects that were created by previous runs but that are no longer generated by the
+// templates. This is optional, by default there is no inventory.
+func (b *ApplierBuilder) SetInventory(value string) *ApplierBuilder {
+	b.inventory = value
+	return b
+}
+
+// SetPrune enables or disables pruning. When enabled, objects recorded in the inventory that are no
+// longer generated by the templates are deleted. This requires an inventory. The default is false.
+func (b *ApplierBuilder) SetPrune(value bool) *ApplierBuilder {
+	b.prune = value
+	return b
+}
+
+// SetWait sets the flag that indicates if the ApplyObjects method should wait till all the objects
+// are healthy. How that is checked depends on the kind of object, and can be customized adding
+// to the template the 'ztpfw/health' annotation containing a jq expression. The maximum time to
+// wait is controlled by the context. The default is false.
+func (b *ApplierBuilder) SetWait(value bool) *ApplierBuilder {
+	b.wait = value
+	return b
+}
+
+// SetWorkers sets the maximum number of objects that will be created concurrently. Objects are
+// created only after the objects that they depend on, like their namespace, the CRD that defines
+// their kind, their owners or the objects listed in the 'ztpfw/depends-on' annotation. Objects that
+// don't depend on each other are created concurrently. The default is four.
+func (b *ApplierBuilder) SetWorkers(value int) *ApplierBuilder {
+	b.workers = value
+	return b
+}
+
+// SetCRDTimeout sets the maximum time to wait for the objects of a custom resource definition to
+// disappear before deleting the definition. The default is five minutes.
+func (b *ApplierBuilder) SetCRDTimeout(value time.Duration) *ApplierBuilder {
+	b.crdTimeout = value
+	return b
+}
+
+// SetForceFinalizers enables or disables the removal of the finalizers of the objects that don't
+// disappear before deleting their custom resource definition. The default is false.
+func (b *ApplierBuilder) SetForceFinalizers(value bool) *ApplierBuilder {
+	b.forceCRDs = value
+	return b
+}
+
+// SetStageTimeout sets the maximum time to wait for each object to be healthy, for CRDs to be
+// established and for objects to disappear. When it expires the error contains a snapshot of the
+// object. The default is zero, which means that only the deadline of the context is used.
+func (b *ApplierBuilder) SetStageTimeout(value time.Duration) *ApplierBuilder {
+	b.stageTimeout = value
+	return b
+}
+
+// SetFlags sets the command line flags that indicate how to configure the applier. This is
+// optional.
+func (b *ApplierBuilder) SetFlags(flags *pflag.FlagSet) *ApplierBuilder {
+	if flags.Changed(applierModeFlagName) {
+		value, err := flags.GetString(applierModeFlagName)
+		if err == nil {
+			b.mode = ApplierMode(value)
+		}
+	}
+	if flags.Changed(applierConflictsFlagName) {
+		value, err := flags.GetString(applierConflictsFlagName)
+		if err == nil {
+			b.conflicts = ApplierConflictPolicy(value)
+		}
+	}
+	if flags.Changed(applierWorkersFlagName) {
+		value, err := flags.GetInt(applierWorkersFlagName)
+		if err == nil {
+			b.workers = value
+		}
+	}
+	if flags.Changed(crdDeleterTimeoutFlagName) {
+		value, err := flags.GetDuration(crdDeleterTimeoutFlagName)
+		if err == nil {
+			b.crdTimeout = value
+		}
+	}
+	if flags.Changed(crdDeleterForceFlagName) {
+		value, err := flags.GetBool(crdDeleterForceFlagName)
+		if err == nil {
+			b.forceCRDs = value
+		}
+	}
+	if flags.Changed(waiterTimeoutFlagName) {
+		value, err := flags.GetDuration(waiterTimeoutFlagName)
+		if err == nil {
+			b.stageTimeout = value
+		}
+	}
+	if flags.Changed(dryRunFlagName) {
+		value, err := flags.GetBool(dryRunFlagName)
+		if err == nil {
+			b.dryRun = value
+		}
+	}
+	if flags.Changed(applierPruneFlagName) {
+		value, err := flags.GetBool(applierPruneFlagName)
+		if err == nil {
+			b.prune = value
+		}
+	}
+	if flags.Changed(applierOverlayFlagName) {
+		value, err := flags.GetString(applierOverlayFlagName)
+		if err == nil {
+			b.overlay = value
+		}
+	}
+	if flags.Changed(applierPatchFlagName) {
+		values, err := flags.GetStringSlice(applierPatchFlagName)
+		if err == nil {
+			b.patchFiles = append(b.patchFiles, values...)
+		}
+	}
+	return b
+}
+
 // Build uses the data stored in the builder to create a new applier.
 func (b *ApplierBuilder) Build() (result *Applier, err error) {
 	// Check parameters:
@@ -227,6 +519,39 @@ func (b *ApplierBuilder) Build() (result *Applier, err error) {
 		err = errors.New("template filesystem is mandatory")
 		return
 	}
+	switch b.mode {
+	case ApplierModeCreate, ApplierModeServerSide:
+	default:
+		err = fmt.Errorf(
+			"unknown apply mode '%s', valid values are '%s' and '%s'",
+			b.mode, ApplierModeCreate, ApplierModeServerSide,
+		)
+		return
+	}
+	switch b.conflicts {
+	case ApplierConflictFail, ApplierConflictForce:
+	default:
+		err = fmt.Errorf(
+			"unknown conflict policy '%s', valid values are '%s' and '%s'",
+			b.conflicts, ApplierConflictFail, ApplierConflictForce,
+		)
+		return
+	}
+	if b.fieldManager == "" {
+		err = errors.New("field manager is mandatory")
+		return
+	}
+	if b.prune && b.inventory == "" {
+		err = errors.New("pruning requires an inventory")
+		return
+	}
+	if b.workers < 1 {
+		err = fmt.Errorf(
+			"number of workers should be at least one, but it is %d",
+			b.workers,
+		)
+		return
+	}
 
 	// Create the jq tool:
 	jq, err := jq.NewTool().
@@ -237,7 +562,7 @@ func (b *ApplierBuilder) Build() (result *Applier, err error) {
 		return
 	}
 
-	// Create the filesystem:
+	// Create the filesystem, layering the overlay directory if needed:
 	fsys := b.fsys
 	if b.root != "" {
 		fsys, err = fs.Sub(b.fsys, b.root)
@@ -245,6 +570,60 @@ func (b *ApplierBuilder) Build() (result *Applier, err error) {
 			return
 		}
 	}
+	if b.overlay != "" {
+		var info os.FileInfo
+		info, err = os.Stat(b.overlay)
+		if err != nil {
+			err = fmt.Errorf("failed to check templates overlay: %w", err)
+			return
+		}
+		if !info.IsDir() {
+			err = fmt.Errorf("templates overlay '%s' isn't a directory", b.overlay)
+			return
+		}
+		overlay := &applierOverlayFS{
+			base:    fsys,
+			overlay: os.DirFS(b.overlay),
+		}
+		var added []string
+		added, err = overlay.added()
+		if err != nil {
+			err = fmt.Errorf("failed to check templates overlay: %w", err)
+			return
+		}
+		if len(added) > 0 {
+			b.logger.Info(
+				"Templates overlay contains files that don't replace any template, "+
+					"check that their paths are relative to the templates directory",
+				"overlay", b.overlay,
+				"root", b.root,
+				"files", added,
+			)
+		}
+		fsys = overlay
+	}
+
+	// Load and check the patches:
+	patches := make([]*ApplierPatch, len(b.patches))
+	for i, patch := range b.patches {
+		clone := *patch
+		clone.source = strconv.Itoa(i)
+		patches[i] = &clone
+	}
+	for _, file := range b.patchFiles {
+		var loaded []*ApplierPatch
+		loaded, err = b.loadPatches(file)
+		if err != nil {
+			return
+		}
+		patches = append(patches, loaded...)
+	}
+	for _, patch := range patches {
+		err = b.checkPatch(patch)
+		if err != nil {
+			return
+		}
+	}
 
 	// Create the templating engine:
 	engine, err := b.createEngine(fsys)
@@ -260,13 +639,52 @@ func (b *ApplierBuilder) Build() (result *Applier, err error) {
 
 	// Create and populate the object:
 	result = &Applier{
-		logger:    b.logger,
-		client:    b.client,
-		labels:    maps.Clone(b.labels),
-		jq:        jq,
-		engine:    engine,
-		templates: templates,
-		listeners: slices.Clone(b.listeners),
+		logger:       b.logger,
+		client:       b.client,
+		labels:       maps.Clone(b.labels),
+		jq:           jq,
+		engine:       engine,
+		templates:    templates,
+		patches:      patches,
+		listeners:    slices.Clone(b.listeners),
+		mode:         b.mode,
+		conflicts:    b.conflicts,
+		fieldManager: b.fieldManager,
+		dryRun:       b.dryRun,
+		inventory:    b.inventory,
+		prune:        b.prune,
+		wait:         b.wait,
+		workers:      b.workers,
+	}
+
+	// Create the object that waits for objects:
+	waiterBuilder := NewWaiter().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		SetTimeout(b.stageTimeout)
+	for _, listener := range b.waiters {
+		waiterBuilder.AddListener(listener)
+	}
+	result.waiter, err = waiterBuilder.Build()
+	if err != nil {
+		result = nil
+		err = fmt.Errorf("failed to create waiter: %w", err)
+		return
+	}
+
+	// Create the object that deletes custom resource definitions, sending the events to the
+	// listeners of the applier:
+	result.crds, err = NewCRDDeleter().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		SetListener(result.fireEvent).
+		SetTimeout(b.crdTimeout).
+		SetForce(b.forceCRDs).
+		Build()
+	if err != nil {
+		result = nil
+		err = fmt.Errorf("failed to create CRD deleter: %w", err)
+		return
 	}
 	return
 }
@@ -313,28 +731,48 @@ func (a *Applier) Apply(ctx context.Context, data any) error {
 	return a.ApplyObjects(ctx, objects)
 }
 
-// ApplyObjects creates the given objects.
+// ApplyObjects creates the given objects. If waiting has been enabled with the SetWait method it
+// also waits till all of them are healthy.
 func (a *Applier) ApplyObjects(ctx context.Context, objects []*unstructured.Unstructured) error {
-	// Namespaces and custom resource definitions need to be created first as other objects will
-	// depend on them, so first we need to classify the rendered objects.
-	namespaces, crds, others := a.classifyObjects(objects)
+	// In dry run mode we only need to compare the objects. Namespaces and custom resource
+	// definitions go first, as that is the order in which they would be created.
+	if a.dryRun {
+		namespaces, crds, others := a.classifyObjects(objects)
+		all := append(append(namespaces, crds...), others...)
+		err := a.diffObjects(ctx, all, false)
+		if err != nil {
+			return err
+		}
+		if a.inventory != "" {
+			return a.updateInventory(ctx, objects)
+		}
+		return nil
+	}
 
-	// Create the namespaces:
-	err := a.applyNamespaces(ctx, namespaces)
+	// Create the objects, concurrently when they don't depend on each other:
+	nodes, err := a.buildGraph(ctx, objects)
 	if err != nil {
 		return err
 	}
-
-	// Create the custom resource definitions:
-	err = a.applyCRDs(ctx, crds)
+	err = a.applyGraph(ctx, nodes)
 	if err != nil {
 		return err
 	}
 
-	// Create the rest of the objects:
-	err = a.applyObjects(ctx, others)
-	if err != nil {
-		return err
+	// Update the inventory, and prune the objects that are no longer generated:
+	if a.inventory != "" {
+		err = a.updateInventory(ctx, objects)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Wait till all the objects are healthy:
+	if a.wait {
+		err = a.waitHealthy(ctx, objects)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -349,8 +787,22 @@ func (a *Applier) Delete(ctx context.Context, data any) error {
 	return a.DeleteObjects(ctx, objects)
 }
 
-// DeleteObjects deletes the given objects.
+// DeleteObjects deletes the given objects, and the inventory if there is one.
 func (a *Applier) DeleteObjects(ctx context.Context, objects []*unstructured.Unstructured) error {
+	err := a.deleteAll(ctx, objects)
+	if err != nil {
+		return err
+	}
+	if a.inventory != "" && !a.dryRun {
+		err = a.deleteInventory(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Applier) deleteAll(ctx context.Context, objects []*unstructured.Unstructured) error {
 	// Templates are usually in a logical creation order and we want to use the reverse order
 	// for deletion:
 	for i, j := 0, len(objects)-1; i < j; i, j = i+1, j-1 {
@@ -363,6 +815,13 @@ func (a *Applier) DeleteObjects(ctx context.Context, objects []*unstructured.Uns
 	// starting.
 	namespaces, crds, others := a.classifyObjects(objects)
 
+	// In dry run mode we only need to compare the objects, in the same order that they would be
+	// deleted:
+	if a.dryRun {
+		all := append(append(others, namespaces...), crds...)
+		return a.diffObjects(ctx, all, true)
+	}
+
 	// Delete the regular objects:
 	err := a.deleteObjects(ctx, others)
 	if err != nil {
@@ -397,6 +856,10 @@ func (a *Applier) Render(ctx context.Context, data any) (results []*unstructured
 		}
 		results = append(results, objects...)
 	}
+	err = a.patchObjects(results)
+	if err != nil {
+		results = nil
+	}
 	return
 }
 
@@ -429,106 +892,78 @@ func (a *Applier) isCRD(object *unstructured.Unstructured) bool {
 	return matchesGroup && matchesKind
 }
 
-func (a *Applier) applyNamespaces(ctx context.Context,
-	namespaces []*unstructured.Unstructured) error {
-	return a.applyObjects(ctx, namespaces)
-}
-
-func (a *Applier) applyCRDs(ctx context.Context, crds []*unstructured.Unstructured) error {
-	// Some CRD templates may have status, but we don't want to apply that:
-	for _, crd := range crds {
-		delete(crd.Object, "status")
-	}
-
-	// Create the objects:
-	err := a.applyObjects(ctx, crds)
-	if err != nil {
-		return err
+// waitCRDs waits till the custom resource definitions for the given kinds are established. The
+// definitions themselves are optional, they are only used for the snapshot in case of timeout.
+func (a *Applier) waitCRDs(ctx context.Context, crds []*unstructured.Unstructured,
+	gvks ...schema.GroupVersionKind) error {
+	kinds := []string{}
+	for _, gvk := range gvks {
+		if !slices.Contains(kinds, gvk.Kind) {
+			kinds = append(kinds, gvk.Kind)
+		}
 	}
-
-	// Wait till all the CRDs have been established, as otherwise creating objects of the
-	// corresponding kind will fail:
-	if len(crds) > 0 {
-		var all []schema.GroupVersionKind
-		for _, crd := range crds {
-			gvks, err := a.getCRDGVKs(ctx, crd)
+	sort.Strings(kinds)
+	var name string
+	if len(kinds) == 1 {
+		name = fmt.Sprintf("CRD '%s' to be established", kinds[0])
+	} else {
+		name = fmt.Sprintf("CRDs %s to be established", logging.All(kinds))
+	}
+	return a.waiter.Wait(ctx, &WaitStage{
+		Name:    name,
+		Objects: crds,
+		Check: func(ctx context.Context, _ []*unstructured.Unstructured) (done bool,
+			err error) {
+			established, err := a.establishedGVKs(ctx)
 			if err != nil {
-				return err
+				return
 			}
-			all = append(all, gvks...)
-		}
-		err = a.waitCRDs(ctx, all...)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+			for _, gvk := range gvks {
+				if !established[gvk] {
+					return
+				}
+			}
+			done = true
+			return
+		},
+	})
 }
 
-func (a *Applier) waitCRDs(ctx context.Context, gvks ...schema.GroupVersionKind) error {
-	pending := map[schema.GroupVersionKind]bool{}
-	for _, gvk := range gvks {
-		pending[gvk] = true
-	}
+// establishedGVKs returns the set of kinds that are defined by custom resource definitions that
+// are established.
+func (a *Applier) establishedGVKs(ctx context.Context) (result map[schema.GroupVersionKind]bool,
+	err error) {
 	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(CustomResourceDefinitionGVK)
-	watch, err := a.client.Watch(ctx, list)
+	list.SetGroupVersionKind(CustomResourceDefinitionListGVK)
+	err = a.client.List(ctx, list)
 	if err != nil {
-		return err
+		return
 	}
-	defer watch.Stop()
-	for event := range watch.ResultChan() {
-		if event.Type == apiwatch.Bookmark {
-			continue
-		}
-		crd, ok := event.Object.(*unstructured.Unstructured)
-		if !ok {
-			continue
-		}
-		gvks, err := a.getCRDGVKs(ctx, crd)
-		if err != nil {
-			return err
-		}
-		var established string
+	established := map[schema.GroupVersionKind]bool{}
+	for i := range list.Items {
+		crd := &list.Items[i]
+		var status string
 		err = a.jq.Query(
 			`.status.conditions[]? | select(.type == "Established") | .status`,
-			crd.Object, &established,
+			crd.Object, &status,
 		)
 		if err != nil {
-			return err
+			return
 		}
-		if established != "True" {
+		if status != "True" {
 			continue
 		}
-		for _, gvk := range gvks {
-			delete(pending, gvk)
-		}
-		if len(pending) == 0 {
-			return nil
-		}
-	}
-	if len(pending) > 0 {
-		kinds := make([]string, len(pending))
-		i := 0
-		for gk := range pending {
-			kinds[i] = gk.Kind
-			i++
+		var gvks []schema.GroupVersionKind
+		gvks, err = a.getCRDGVKs(ctx, crd)
+		if err != nil {
+			return
 		}
-		sort.Strings(kinds)
-		if len(kinds) == 1 {
-			return fmt.Errorf(
-				"timed out while waiting for CRD '%s' to be established",
-				kinds[0],
-			)
-		} else {
-			return fmt.Errorf(
-				"timed out while waiting for CRDs %s to be establised",
-				logging.All(kinds),
-			)
+		for _, gvk := range gvks {
+			established[gvk] = true
 		}
 	}
-	return nil
+	result = established
+	return
 }
 
 func (a *Applier) getCRDGVKs(ctx context.Context,
@@ -544,33 +979,14 @@ func (a *Applier) getCRDGVKs(ctx context.Context,
 	return
 }
 
-func (a *Applier) applyObjects(ctx context.Context, objects []*unstructured.Unstructured) error {
-	for _, object := range objects {
-		err := a.applyObject(ctx, object)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (a *Applier) applyObject(ctx context.Context, object *unstructured.Unstructured) error {
-	// Create a copy of the object so that we don't alter the original:
-	copy := &unstructured.Unstructured{}
-	err := a.deepCopy(object.Object, &copy.Object)
+	// Create the copy of the object that is sent to the server:
+	copy, err := a.applyCopy(object)
 	if err != nil {
 		a.fireError(ApplierCreateError, object, err)
 		return err
 	}
 
-	// Add the labels:
-	labels := copy.GetLabels()
-	if labels == nil {
-		labels = map[string]string{}
-	}
-	maps.Copy(labels, a.labels)
-	copy.SetLabels(labels)
-
 	// Create the object:
 	err = a.createObject(ctx, copy)
 	if err != nil {
@@ -586,6 +1002,46 @@ func (a *Applier) applyObject(ctx context.Context, object *unstructured.Unstruct
 	return nil
 }
 
+// applierInternalAnnotations are the annotations that the templates use to give instructions to
+// the applier. They are removed before sending the objects to the server.
+var applierInternalAnnotations = []string{
+	annotations.DependsOn,
+	annotations.Health,
+}
+
+// applyCopy creates the copy of the given object that is sent to the server, so that we don't
+// alter the original. It adds the configured labels and removes the internal annotations. Note
+// that the original object still contains those annotations, as they are needed to build the
+// dependency graph and to check the health of the object.
+func (a *Applier) applyCopy(object *unstructured.Unstructured) (result *unstructured.Unstructured,
+	err error) {
+	copy := &unstructured.Unstructured{}
+	err = a.deepCopy(object.Object, &copy.Object)
+	if err != nil {
+		return
+	}
+	if len(a.labels) > 0 {
+		labels := copy.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, a.labels)
+		copy.SetLabels(labels)
+	}
+	values := copy.GetAnnotations()
+	if len(values) > 0 {
+		for _, name := range applierInternalAnnotations {
+			delete(values, name)
+		}
+		if len(values) == 0 {
+			values = nil
+		}
+		copy.SetAnnotations(values)
+	}
+	result = copy
+	return
+}
+
 func (a *Applier) applyStatus(ctx context.Context, object *unstructured.Unstructured) error {
 	// Do nothing if there is no status:
 	status, ok := object.Object["status"]
@@ -627,6 +1083,9 @@ func (a *Applier) createObject(ctx context.Context, object *unstructured.Unstruc
 	// correspond to a CRD that hasn't been created yet. This function does the basic object
 	// creation, without that logic, so that we can reuse it.
 	createObject := func() error {
+		if a.mode == ApplierModeServerSide {
+			return a.serverSideApply(ctx, object)
+		}
 		err := a.client.Create(ctx, object)
 		if err == nil {
 			a.fireInfo(ApplierObjectCreated, object)
@@ -639,18 +1098,6 @@ func (a *Applier) createObject(ctx context.Context, object *unstructured.Unstruc
 		return err
 	}
 
-	// This function checks if the given error is the one returned by the server when the CRD
-	// doesn't exist. Note that the server will return a no found error when the CRD is already
-	// established but it isn't yet usable, and an internal server error when the webhooks
-	// aren't yet ready.
-	isNoCRD := func(err error) bool {
-		_, isNoKind := err.(*meta.NoKindMatchError)
-		if isNoKind {
-			return true
-		}
-		return apierrors.IsNotFound(err) || apierrors.IsInternalError(err)
-	}
-
 	// If the creation fails because the CRD doesn't exist, chances are that it is because the
 	// corresponding CRD is created by an operator that haven't created it yet. So we wait for
 	// the CRD to be available.
@@ -658,11 +1105,14 @@ func (a *Applier) createObject(ctx context.Context, object *unstructured.Unstruc
 	if err == nil {
 		return nil
 	}
-	if !isNoCRD(err) {
+	if !a.isNoCRD(err) {
+		if a.mode == ApplierModeServerSide {
+			a.fireError(ApplierApplyError, object, err)
+		}
 		return err
 	}
 	a.fireInfo(ApplierWaitingCRD, object)
-	err = a.waitCRDs(ctx, object.GroupVersionKind())
+	err = a.waitCRDs(ctx, nil, object.GroupVersionKind())
 	if err != nil {
 		return err
 	}
@@ -678,7 +1128,7 @@ func (a *Applier) createObject(ctx context.Context, object *unstructured.Unstruc
 		if err == nil {
 			return nil
 		}
-		if !isNoCRD(err) {
+		if !a.isNoCRD(err) {
 			return backoff.Permanent(err)
 		}
 		return err
@@ -697,6 +1147,350 @@ func (a *Applier) createObject(ctx context.Context, object *unstructured.Unstruc
 	)
 }
 
+// isNoCRD checks if the given error is the one returned by the server when the CRD doesn't exist.
+// Note that the server will return a no found error when the CRD is already established but it
+// isn't yet usable, and an internal server error when the webhooks aren't yet ready.
+func (a *Applier) isNoCRD(err error) bool {
+	_, isNoKind := err.(*meta.NoKindMatchError)
+	if isNoKind {
+		return true
+	}
+	return apierrors.IsNotFound(err) || apierrors.IsInternalError(err)
+}
+
+// serverSideApply creates or updates the object using server side apply and fires the event that
+// describes what happened. Errors are returned without firing events, so that the caller can
+// decide if the operation needs to be retried.
+func (a *Applier) serverSideApply(ctx context.Context, object *unstructured.Unstructured) error {
+	// Get the current state of the object, so that we can later compare it to the result and
+	// find out what changed:
+	before := &unstructured.Unstructured{}
+	before.SetGroupVersionKind(object.GroupVersionKind())
+	err := a.client.Get(ctx, clnt.ObjectKeyFromObject(object), before)
+	if apierrors.IsNotFound(err) {
+		before = nil
+	} else if err != nil {
+		return err
+	}
+
+	// Apply the object. Note that the patch method replaces the content of the object with
+	// the result returned by the server, so after this it will contain the new state.
+	options := []clnt.PatchOption{
+		clnt.FieldOwner(a.fieldManager),
+	}
+	if a.conflicts == ApplierConflictForce {
+		options = append(options, clnt.ForceOwnership)
+	}
+	err = a.client.Patch(ctx, object, clnt.Apply, options...)
+	if err != nil {
+		return err
+	}
+
+	// Report what happened:
+	if before == nil {
+		a.fireInfo(ApplierObjectCreated, object)
+		return nil
+	}
+	changes := applierChanges(before.Object, object.Object)
+	if len(changes) == 0 {
+		a.fireInfo(ApplierObjectUnchanged, object)
+		return nil
+	}
+	a.fireEvent(&ApplierEvent{
+		Type:    ApplierObjectUpdated,
+		Object:  object,
+		Changes: changes,
+	})
+	return nil
+}
+
+// applierChanges compares two versions of an object and returns the sorted list of paths of the
+// fields that are different. Fields that the server changes in every update, like the resource
+// version or the managed fields, are ignored. Lists are compared as a whole.
+func applierChanges(before, after map[string]any) []string {
+	var changes []string
+	applierCompare(nil, before, after, &changes)
+	sort.Strings(changes)
+	return changes
+}
+
+func applierCompare(path []string, before, after any, changes *[]string) {
+	if applierIgnoredPaths[strings.Join(path, ".")] {
+		return
+	}
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		keys := map[string]bool{}
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			applierCompare(append(slices.Clip(path), key), beforeMap[key], afterMap[key],
+				changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, applierPath(path))
+	}
+}
+
+func applierPath(path []string) string {
+	buffer := &strings.Builder{}
+	for i, field := range path {
+		if strings.Contains(field, ".") {
+			fmt.Fprintf(buffer, "[%q]", field)
+			continue
+		}
+		if i > 0 {
+			buffer.WriteString(".")
+		}
+		buffer.WriteString(field)
+	}
+	return buffer.String()
+}
+
+// applierIgnoredPaths contains the paths of the fields that aren't considered when checking what
+// changed in an object.
+var applierIgnoredPaths = map[string]bool{
+	"metadata.generation":      true,
+	"metadata.managedFields":   true,
+	"metadata.resourceVersion": true,
+	"status":                   true,
+}
+
+func (a *Applier) diffObjects(ctx context.Context, objects []*unstructured.Unstructured,
+	deleting bool) error {
+	for _, object := range objects {
+		err := a.diffObject(ctx, object, deleting)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffObject compares the live version of the object with the result of applying or deleting it,
+// and fires an event with the differences.
+func (a *Applier) diffObject(ctx context.Context, object *unstructured.Unstructured,
+	deleting bool) error {
+	errorType := ApplierCreateError
+	if deleting {
+		errorType = ApplierDeleteError
+	}
+
+	// Get the live object:
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(object.GroupVersionKind())
+	err := a.client.Get(ctx, clnt.ObjectKeyFromObject(object), live)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		live = nil
+	} else if err != nil {
+		a.fireError(errorType, object, err)
+		return err
+	}
+
+	// Calculate the result of the operation:
+	var result *unstructured.Unstructured
+	switch {
+	case deleting && live == nil:
+		a.fireInfo(ApplierObjectNotExist, object)
+		return nil
+	case deleting:
+	case a.mode == ApplierModeCreate && live != nil:
+		a.fireInfo(ApplierObjectExist, object)
+		return nil
+	default:
+		result, err = a.dryRunApply(ctx, object)
+		if err != nil {
+			if a.mode == ApplierModeServerSide {
+				errorType = ApplierApplyError
+			}
+			a.fireError(errorType, object, err)
+			return err
+		}
+	}
+
+	// Compare the live object and the result:
+	path := fmt.Sprintf("%s/%s", object.GetKind(), object.GetName())
+	if object.GetNamespace() != "" {
+		path = fmt.Sprintf("%s/%s", object.GetNamespace(), path)
+	}
+	fromName := "/dev/null"
+	if live != nil {
+		fromName = "live/" + path
+	}
+	toName := "/dev/null"
+	if result != nil {
+		toName = "merged/" + path
+	}
+	from, err := a.diffCopy(live)
+	if err != nil {
+		return err
+	}
+	to, err := a.diffCopy(result)
+	if err != nil {
+		return err
+	}
+	if object.GroupVersionKind().GroupKind() == applierSecretGK {
+		a.maskSecret(from, to)
+	}
+	fromText, err := a.diffText(from)
+	if err != nil {
+		return err
+	}
+	toText, err := a.diffText(to)
+	if err != nil {
+		return err
+	}
+	diff := text.Diff(fromName, toName, fromText, toText)
+	if diff == "" {
+		a.fireInfo(ApplierObjectUnchanged, object)
+		return nil
+	}
+	a.fireEvent(&ApplierEvent{
+		Type:   ApplierObjectDiff,
+		Object: object,
+		Diff:   diff,
+	})
+	return nil
+}
+
+// dryRunApply asks the server what would be the result of applying the given object, without
+// actually changing anything. When the server can't answer, for example because the namespace or
+// the CRD don't exist yet, the rendered object is used as the result.
+func (a *Applier) dryRunApply(ctx context.Context,
+	object *unstructured.Unstructured) (result *unstructured.Unstructured, err error) {
+	copy, err := a.applyCopy(object)
+	if err != nil {
+		return
+	}
+	switch a.mode {
+	case ApplierModeServerSide:
+		options := []clnt.PatchOption{
+			clnt.FieldOwner(a.fieldManager),
+			clnt.DryRunAll,
+		}
+		if a.conflicts == ApplierConflictForce {
+			options = append(options, clnt.ForceOwnership)
+		}
+		err = a.client.Patch(ctx, copy, clnt.Apply, options...)
+	default:
+		err = a.client.Create(ctx, copy, clnt.DryRunAll)
+	}
+	if err != nil && a.isNoCRD(err) {
+		a.logger.V(1).Info(
+			"Server can't do dry run, will use rendered object",
+			"kind", object.GetKind(),
+			"namespace", object.GetNamespace(),
+			"name", object.GetName(),
+			"error", err,
+		)
+		copy, err = a.applyCopy(object)
+	}
+	if err != nil {
+		return
+	}
+	result = copy
+	return
+}
+
+// diffCopy makes a copy of the given object that can be modified before generating the text used to
+// compare objects, removing the fields that are set by the server and that would only add noise to
+// the differences.
+func (a *Applier) diffCopy(object *unstructured.Unstructured) (result map[string]any, err error) {
+	if object == nil {
+		return
+	}
+	err = a.deepCopy(object.Object, &result)
+	if err != nil {
+		return
+	}
+	delete(result, "status")
+	metadata, ok := result["metadata"].(map[string]any)
+	if ok {
+		for _, field := range applierDiffIgnoredMetadata {
+			delete(metadata, field)
+		}
+	}
+	return
+}
+
+// diffText generates the text used to compare objects.
+func (a *Applier) diffText(object map[string]any) (result string, err error) {
+	if object == nil {
+		return
+	}
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(object)
+	if err != nil {
+		return
+	}
+	result = buffer.String()
+	return
+}
+
+// maskSecret replaces the values of the given versions of a secret with asterisks, so that they
+// aren't written to the console or to the log. Like in 'kubectl diff' values that are different
+// in both versions are marked with 'before' and 'after', so that the difference is still visible.
+func (a *Applier) maskSecret(from, to map[string]any) {
+	for _, field := range applierSecretFields {
+		var fromValues, toValues map[string]any
+		if from != nil {
+			fromValues, _ = from[field].(map[string]any)
+		}
+		if to != nil {
+			toValues, _ = to[field].(map[string]any)
+		}
+		keys := append(maps.Keys(fromValues), maps.Keys(toValues)...)
+		for _, key := range keys {
+			fromValue, inFrom := fromValues[key]
+			toValue, inTo := toValues[key]
+			if inFrom && inTo && !reflect.DeepEqual(fromValue, toValue) {
+				fromValues[key] = applierSecretMask + " (before)"
+				toValues[key] = applierSecretMask + " (after)"
+				continue
+			}
+			if inFrom {
+				fromValues[key] = applierSecretMask
+			}
+			if inTo {
+				toValues[key] = applierSecretMask
+			}
+		}
+	}
+}
+
+// applierSecretGK is the type of the objects whose values are masked in the differences.
+var applierSecretGK = schema.GroupKind{
+	Kind: "Secret",
+}
+
+// applierSecretFields are the fields of secrets whose values are masked in the differences.
+var applierSecretFields = []string{
+	"data",
+	"stringData",
+}
+
+// applierSecretMask is the text that replaces the values of secrets in the differences.
+const applierSecretMask = "***"
+
+// applierDiffIgnoredMetadata contains the metadata fields that aren't included in the differences.
+var applierDiffIgnoredMetadata = []string{
+	"creationTimestamp",
+	"generation",
+	"managedFields",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
 func (a *Applier) deleteObjects(ctx context.Context, objects []*unstructured.Unstructured) error {
 	var errs []error
 	for _, object := range objects {
@@ -773,10 +1567,9 @@ func (a *Applier) deleteNamespace(ctx context.Context, namespace *unstructured.U
 }
 
 func (a *Applier) deleteCRDs(ctx context.Context, crds []*unstructured.Unstructured) error {
-	// It would probably be better to wait till there are no objects of these types before
-	// deleting them, but this is an operation that is rarely used, and usually only in
-	// development environments, so it is not worth the complication at the moment.
-	return a.deleteObjects(ctx, crds)
+	// The deleter first deletes the objects of these types and waits till they disappear, so
+	// that controllers still have a chance to process their finalizers.
+	return a.crds.Delete(ctx, crds...)
 }
 
 func (a *Applier) waitDisappear(ctx context.Context, object *unstructured.Unstructured) error {
@@ -792,47 +1585,20 @@ func (a *Applier) waitDisappear(ctx context.Context, object *unstructured.Unstru
 		return err
 	}
 
-	// If the object hasn't disappeared yet then we need to wait till we receive the delete
-	// event. But if the object has finalizers the object will not disappear inmediately after
-	// receiving that event. In that case the API server will send a second delete event when
-	// the last finalizer is removed. So each time we need to explicitly try to retrieve the
-	// object to check if it has really disappeared.
+	// If the object hasn't disappeared yet then we need to wait. Note that if the object has
+	// finalizers it will not disappear inmediately, so we need to explicitly check that it no
+	// longer exists.
 	a.fireInfo(ApplierWaitingDisappear, object)
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(current.GroupVersionKind())
-	watch, err := a.client.Watch(
-		ctx,
-		list,
-		clnt.InNamespace(current.GetNamespace()),
-		clnt.MatchingFields{
-			"metadata.name": current.GetName(),
-		},
-		&clnt.ListOptions{
-			Raw: &metav1.ListOptions{
-				ResourceVersion: current.GetResourceVersion(),
-			},
+	return a.waiter.Wait(ctx, &WaitStage{
+		Name: fmt.Sprintf(
+			"object '%s/%s' of kind '%s' to disappear",
+			object.GetNamespace(), object.GetName(), object.GetKind(),
+		),
+		Objects: []*unstructured.Unstructured{object},
+		Check: func(ctx context.Context, current []*unstructured.Unstructured) (bool, error) {
+			return current[0] == nil, nil
 		},
-	)
-	if err != nil {
-		return err
-	}
-	defer watch.Stop()
-	for event := range watch.ResultChan() {
-		if event.Type != apiwatch.Deleted {
-			continue
-		}
-		err = a.client.Get(ctx, key, current)
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return fmt.Errorf(
-		"timed out while waiting for object '%s/%s' of kind '%s' to disappear",
-		current.GetNamespace(), current.GetName(), current.GetKind(),
-	)
+	})
 }
 
 func (a *Applier) renderObjects(ctx context.Context, data any,
@@ -922,6 +1688,16 @@ func (a *Applier) fireEvent(event *ApplierEvent) {
 			logger.Info("Object exists", fields...)
 		case ApplierCreateError:
 			logger.Error(event.Error, "Object error", fields...)
+		case ApplierObjectUpdated:
+			logger.Info("Object updated", append(fields, "changes", event.Changes)...)
+		case ApplierObjectUnchanged:
+			logger.Info("Object unchanged", fields...)
+		case ApplierApplyError:
+			logger.Error(event.Error, "Apply error", fields...)
+		case ApplierObjectDiff:
+			logger.Info("Object differences", append(fields, "diff", event.Diff)...)
+		case ApplierObjectStale:
+			logger.Info("Object stale", fields...)
 		case ApplierStatusUpdated:
 			logger.Info("Status updated", fields...)
 		case ApplierStatusError:
@@ -930,11 +1706,28 @@ func (a *Applier) fireEvent(event *ApplierEvent) {
 			logger.Info("Object deleted", fields...)
 		case ApplierWaitingCRD:
 			logger.Info("Waiting for CRD", fields...)
+		case ApplierWaitingHealthy:
+			logger.Info("Waiting for object to be healthy", fields...)
+		case ApplierObjectHealthy:
+			logger.Info("Object healthy", fields...)
+		case ApplierWaitingDrain:
+			logger.Info("Waiting for objects to be deleted", fields...)
+		case ApplierFinalizersRemoved:
+			logger.Info("Finalizers removed", append(fields, "finalizers", event.Finalizers)...)
 		default:
 			logger.Info("Event", fields...)
 		}
 	}
+	a.eventLock.Lock()
+	defer a.eventLock.Unlock()
 	for _, listener := range a.listeners {
 		listener(event)
 	}
 }
+
+// applierDefaultWorkers is the number of objects that are created concurrently by default.
+const applierDefaultWorkers = 4
+
+// applierDefaultFieldManager is the name of the field manager used by default for server side
+// apply.
+const applierDefaultFieldManager = "ztp"
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// AddApplierFlags adds the applier flags to the given flag set.
+func AddApplierFlags(set *pflag.FlagSet) {
+	_ = set.String(
+		applierModeFlagName,
+		string(ApplierModeCreate),
+		"How objects are created. With '"+string(ApplierModeCreate)+"' only objects "+
+			"that don't exist are created, and existing objects are left untouched. "+
+			"With '"+string(ApplierModeServerSide)+"' objects are created or updated "+
+			"using server side apply, so that changes in the templates are also "+
+			"applied to objects that already exist.",
+	)
+	_ = set.String(
+		applierConflictsFlagName,
+		string(ApplierConflictFail),
+		"What to do when server side apply finds fields that are managed by other "+
+			"tools. With '"+string(ApplierConflictFail)+"' the apply fails, and with '"+
+			string(ApplierConflictForce)+"' the fields are overwritten and their "+
+			"ownership taken.",
+	)
+	_ = set.Int(
+		applierWorkersFlagName,
+		applierDefaultWorkers,
+		"Maximum number of objects that are created concurrently. Objects that depend "+
+			"on other objects are always created after them.",
+	)
+	_ = set.Bool(
+		applierPruneFlagName,
+		false,
+		"Delete the objects that were created by previous runs but that are no "+
+			"longer generated, for example because a node has been removed from "+
+			"the configuration.",
+	)
+	_ = set.String(
+		applierOverlayFlagName,
+		"",
+		"Directory containing files that are layered over the templates built into the "+
+			"tool. Paths are relative to the templates directory of each command, where "+
+			"the templates of the objects are inside the 'objects' directory, for "+
+			"example 'objects/030-agentclusterinstall.yaml' for the 'create cluster' "+
+			"command. Files with the same path than a built-in template replace it, "+
+			"and new files are added. Files that don't replace a built-in template are "+
+			"written to the log.",
+	)
+	_ = set.StringSlice(
+		applierPatchFlagName,
+		nil,
+		"File containing patches that are applied to the generated objects before they "+
+			"are created. Each YAML document of the file contains a 'target' with the "+
+			"'kind' and optionally the 'group', 'version', 'namespace' and 'name' of "+
+			"the objects, a 'type' that can be 'strategic', 'merge' or 'json', and the "+
+			"'patch' itself. Can be used multiple times.",
+	)
+	AddDryRunFlag(set)
+	AddWaiterFlags(set)
+}
+
+// AddDryRunFlag adds the flag that indicates that commands should only show the changes that they
+// would make, without actually making them. Commands that call AddApplierFlags don't need to call
+// this.
+func AddDryRunFlag(set *pflag.FlagSet) {
+	_ = set.Bool(
+		dryRunFlagName,
+		false,
+		"Don't create, update or delete anything, only show the differences between "+
+			"the objects that exist and the objects that would result from running "+
+			"the command.",
+	)
+}
+
+// DryRunFlag returns the value of the dry run flag, or false if the flag hasn't been added to the
+// given flag set.
+func DryRunFlag(flags *pflag.FlagSet) bool {
+	value, err := flags.GetBool(dryRunFlagName)
+	if err != nil {
+		return false
+	}
+	return value
+}
+
+// Names of the flags:
+const (
+	applierModeFlagName      = "apply-mode"
+	applierConflictsFlagName = "apply-conflicts"
+	applierPruneFlagName     = "prune"
+	applierWorkersFlagName   = "apply-workers"
+	applierOverlayFlagName   = "templates-overlay"
+	applierPatchFlagName     = "templates-patch"
+	dryRunFlagName           = "dry-run"
+)
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/annotations"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+)
+
+// applierNode is a node of the dependency graph that the applier uses to decide the order in
+// which objects are created.
+type applierNode struct {
+	object  *unstructured.Unstructured
+	deps    []*applierNode
+	users   []*applierNode
+	pending int
+}
+
+// applierResult is used by the workers to report the result of applying a node.
+type applierResult struct {
+	node *applierNode
+	err  error
+}
+
+// buildGraph creates the dependency graph for the given objects. An object depends on the
+// namespace that contains it, on the CRD that defines its kind, on its owners and on the objects
+// listed in the 'ztpfw/depends-on' annotation, but only if those are also part of the given
+// objects. The nodes are returned in the same order than the objects.
+func (a *Applier) buildGraph(ctx context.Context,
+	objects []*unstructured.Unstructured) (result []*applierNode, err error) {
+	// Create the nodes and index them by kind, namespace and name, and the CRDs also by the
+	// group and kind that they define:
+	nodes := make([]*applierNode, len(objects))
+	index := map[string]*applierNode{}
+	crds := map[schema.GroupKind]*applierNode{}
+	for i, object := range objects {
+		node := &applierNode{
+			object: object,
+		}
+		nodes[i] = node
+		index[applierNodeKey(object.GetKind(), object.GetNamespace(), object.GetName())] = node
+		if a.isCRD(object) {
+			var gvks []schema.GroupVersionKind
+			gvks, err = a.getCRDGVKs(ctx, object)
+			if err != nil {
+				return
+			}
+			for _, gvk := range gvks {
+				crds[gvk.GroupKind()] = node
+			}
+		}
+	}
+
+	// Add the edges:
+	for _, node := range nodes {
+		object := node.object
+		namespace := object.GetNamespace()
+		if namespace != "" {
+			dep, ok := index[applierNodeKey(NamespaceGVK.Kind, "", namespace)]
+			if ok {
+				a.addEdge(node, dep)
+			}
+		}
+		dep, ok := crds[object.GroupVersionKind().GroupKind()]
+		if ok {
+			a.addEdge(node, dep)
+		}
+		for _, owner := range object.GetOwnerReferences() {
+			dep, ok := index[applierNodeKey(owner.Kind, namespace, owner.Name)]
+			if !ok {
+				dep, ok = index[applierNodeKey(owner.Kind, "", owner.Name)]
+			}
+			if ok {
+				a.addEdge(node, dep)
+			}
+		}
+		value, ok := object.GetAnnotations()[annotations.DependsOn]
+		if ok {
+			err = a.addExplicitEdges(node, index, value)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// Check that there are no cycles:
+	err = a.checkCycles(nodes)
+	if err != nil {
+		return
+	}
+
+	result = nodes
+	return
+}
+
+// addExplicitEdges adds the edges for the references contained in the value of the
+// 'ztpfw/depends-on' annotation.
+func (a *Applier) addExplicitEdges(node *applierNode, index map[string]*applierNode,
+	value string) error {
+	object := node.object
+	for _, ref := range strings.Split(value, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		parts := strings.Split(ref, "/")
+		var dep *applierNode
+		var ok bool
+		switch len(parts) {
+		case 2:
+			dep, ok = index[applierNodeKey(parts[0], object.GetNamespace(), parts[1])]
+			if !ok {
+				dep, ok = index[applierNodeKey(parts[0], "", parts[1])]
+			}
+		case 3:
+			dep, ok = index[applierNodeKey(parts[0], parts[1], parts[2])]
+		default:
+			return fmt.Errorf(
+				"dependency '%s' of object '%s/%s' of kind '%s' isn't valid, it "+
+					"should be 'Kind/name' or 'Kind/namespace/name'",
+				ref, object.GetNamespace(), object.GetName(), object.GetKind(),
+			)
+		}
+		if !ok {
+			return fmt.Errorf(
+				"object '%s/%s' of kind '%s' depends on '%s', but that object "+
+					"isn't generated by the templates",
+				object.GetNamespace(), object.GetName(), object.GetKind(), ref,
+			)
+		}
+		a.addEdge(node, dep)
+	}
+	return nil
+}
+
+func (a *Applier) addEdge(node, dep *applierNode) {
+	if node == dep {
+		return
+	}
+	for _, existing := range node.deps {
+		if existing == dep {
+			return
+		}
+	}
+	node.deps = append(node.deps, dep)
+	node.pending++
+	dep.users = append(dep.users, node)
+}
+
+// checkCycles checks that the graph doesn't contain cycles, removing repeatedly the nodes that
+// don't have dependencies. If there are nodes left at the end then they are part of a cycle.
+func (a *Applier) checkCycles(nodes []*applierNode) error {
+	pending := map[*applierNode]int{}
+	var queue []*applierNode
+	for _, node := range nodes {
+		pending[node] = node.pending
+		if node.pending == 0 {
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		delete(pending, node)
+		for _, user := range node.users {
+			pending[user]--
+			if pending[user] == 0 {
+				queue = append(queue, user)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	var names []string
+	for _, node := range nodes {
+		_, ok := pending[node]
+		if ok {
+			names = append(names, fmt.Sprintf(
+				"%s/%s/%s",
+				node.object.GetKind(), node.object.GetNamespace(), node.object.GetName(),
+			))
+		}
+	}
+	return fmt.Errorf(
+		"objects %s have circular dependencies",
+		logging.All(names),
+	)
+}
+
+// applyGraph applies the objects of the graph using the configured number of workers. An object is
+// applied only when all its dependencies have been applied, so objects that don't depend on each
+// other are applied concurrently. When an object fails no more objects are started, and the error
+// is returned after the objects that were already started finish.
+func (a *Applier) applyGraph(ctx context.Context, nodes []*applierNode) error {
+	// Cancel the objects in progress if something fails:
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the workers:
+	ready := make(chan *applierNode, len(nodes))
+	results := make(chan applierResult, len(nodes))
+	group := &sync.WaitGroup{}
+	for i := 0; i < a.workers; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for node := range ready {
+				results <- applierResult{
+					node: node,
+					err:  a.applyNode(ctx, node),
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(ready)
+		group.Wait()
+	}()
+
+	// Send to the workers the nodes that don't have dependencies, then the nodes whose
+	// dependencies have all been completed:
+	started := 0
+	for _, node := range nodes {
+		if node.pending == 0 {
+			ready <- node
+			started++
+		}
+	}
+	var first error
+	for finished := 0; finished < started; finished++ {
+		result := <-results
+		if result.err != nil {
+			if first == nil {
+				first = result.err
+				cancel()
+			}
+			continue
+		}
+		if first != nil {
+			continue
+		}
+		for _, user := range result.node.users {
+			user.pending--
+			if user.pending == 0 {
+				ready <- user
+				started++
+			}
+		}
+	}
+	return first
+}
+
+// applyNode applies the object of the given node. For CRDs it also waits till they are established,
+// as otherwise creating objects of the corresponding kinds would fail.
+func (a *Applier) applyNode(ctx context.Context, node *applierNode) error {
+	object := node.object
+	if !a.isCRD(object) {
+		return a.applyObject(ctx, object)
+	}
+
+	// Some CRD templates may have status, but we don't want to apply that:
+	delete(object.Object, "status")
+	err := a.applyObject(ctx, object)
+	if err != nil {
+		return err
+	}
+	gvks, err := a.getCRDGVKs(ctx, object)
+	if err != nil {
+		return err
+	}
+	return a.waitCRDs(ctx, []*unstructured.Unstructured{object}, gvks...)
+}
+
+// applierNodeKey calculates the key used to find the nodes of the dependency graph. Note that it
+// doesn't contain the group because the references used in owner references and in the
+// 'ztpfw/depends-on' annotation only contain the kind.
+func applierNodeKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
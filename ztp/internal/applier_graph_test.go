@@ -0,0 +1,342 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Applier graph", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+		events []*ApplierEvent
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		events = nil
+	})
+
+	// makeApplier creates an applier with the given number of workers that saves the events.
+	makeApplier := func(workers int) *Applier {
+		tmp, fsys := TmpFS("objects.yaml", "")
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		applier, err := NewApplier().
+			SetLogger(logger).
+			SetClient(client).
+			SetFS(fsys).
+			SetWorkers(workers).
+			SetListener(func(event *ApplierEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return applier
+	}
+
+	// makeObjects decodes the given YAML text.
+	makeObjects := func(applier *Applier, data string) []*unstructured.Unstructured {
+		objects, err := applier.decodeObjects(
+			strings.NewReader(text.Dedent(data)),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return objects
+	}
+
+	// depNames returns the names of the dependencies of each node.
+	depNames := func(nodes []*applierNode) map[string][]string {
+		result := map[string][]string{}
+		for _, node := range nodes {
+			names := []string{}
+			for _, dep := range node.deps {
+				names = append(names, dep.object.GetName())
+			}
+			result[node.object.GetName()] = names
+		}
+		return result
+	}
+
+	It("Rejects zero workers", func() {
+		_, err := NewApplier().
+			SetLogger(logger).
+			SetClient(client).
+			SetFS(os.DirFS(".")).
+			SetWorkers(0).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(
+			"number of workers should be at least one, but it is 0",
+		))
+	})
+
+	It("Finds the dependencies", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: my-config
+			  annotations:
+			    ztpfw/depends-on: Secret/my-secret, Example/other-ns/my-example
+			---
+			apiVersion: v1
+			kind: Secret
+			metadata:
+			  namespace: my-ns
+			  name: my-secret
+			  ownerReferences:
+			  - apiVersion: example.com/v1
+			    kind: Example
+			    name: my-owner
+			    uid: 123
+			---
+			apiVersion: example.com/v1
+			kind: Example
+			metadata:
+			  namespace: my-ns
+			  name: my-owner
+			---
+			apiVersion: example.com/v1
+			kind: Example
+			metadata:
+			  namespace: other-ns
+			  name: my-example
+			---
+			apiVersion: apiextensions.k8s.io/v1
+			kind: CustomResourceDefinition
+			metadata:
+			  name: examples.example.com
+			spec:
+			  group: example.com
+			  names:
+			    kind: Example
+			  versions:
+			  - name: v1
+			---
+			apiVersion: v1
+			kind: Namespace
+			metadata:
+			  name: my-ns
+		`)
+		nodes, err := applier.buildGraph(ctx, objects)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(depNames(nodes)).To(Equal(map[string][]string{
+			"my-config":            {"my-ns", "my-secret", "my-example"},
+			"my-secret":            {"my-ns", "my-owner"},
+			"my-owner":             {"my-ns", "examples.example.com"},
+			"my-example":           {"examples.example.com"},
+			"examples.example.com": {},
+			"my-ns":                {},
+		}))
+	})
+
+	It("Rejects circular dependencies", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: first
+			  annotations:
+			    ztpfw/depends-on: ConfigMap/second
+			---
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: second
+			  annotations:
+			    ztpfw/depends-on: ConfigMap/first
+			---
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: third
+		`)
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(
+			"objects 'ConfigMap/my-ns/first' and 'ConfigMap/my-ns/second' have " +
+				"circular dependencies",
+		))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("Rejects dependencies on objects that aren't generated", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: my-config
+			  annotations:
+			    ztpfw/depends-on: Secret/my-secret
+		`)
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(
+			"object 'my-ns/my-config' of kind 'ConfigMap' depends on 'Secret/my-secret', " +
+				"but that object isn't generated by the templates",
+		))
+	})
+
+	It("Rejects dependencies with wrong format", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: my-config
+			  annotations:
+			    ztpfw/depends-on: my-secret
+		`)
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(
+			"dependency 'my-secret' of object 'my-ns/my-config' of kind 'ConfigMap' " +
+				"isn't valid, it should be 'Kind/name' or 'Kind/namespace/name'",
+		))
+	})
+
+	It("Creates objects after their dependencies", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: first
+			  annotations:
+			    ztpfw/depends-on: ConfigMap/second
+			---
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: second
+			  annotations:
+			    ztpfw/depends-on: ConfigMap/third
+			---
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: third
+		`)
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, event := range events {
+			Expect(event.Type).To(Equal(ApplierObjectCreated))
+			names = append(names, event.Object.GetName())
+		}
+		Expect(names).To(Equal([]string{"third", "second", "first"}))
+	})
+
+	It("Creates independent objects concurrently", func() {
+		applier := makeApplier(4)
+		buffer := &strings.Builder{}
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(buffer, text.Dedent(`
+				---
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  namespace: my-ns
+				  name: my-config-%d
+			`), i)
+		}
+		objects := makeObjects(applier, buffer.String())
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(20))
+		for _, object := range objects {
+			err = client.Get(ctx, clnt.ObjectKeyFromObject(object), object.DeepCopy())
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("Doesn't send the internal annotations to the server", func() {
+		applier := makeApplier(1)
+		objects := makeObjects(applier, `
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: first
+			  annotations:
+			    ztpfw/depends-on: ConfigMap/second
+			    ztpfw/health: "true"
+			    my-annotation: my-value
+			---
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  namespace: my-ns
+			  name: second
+			  annotations:
+			    ztpfw/depends-on: ""
+		`)
+		err := applier.ApplyObjects(ctx, objects)
+		Expect(err).ToNot(HaveOccurred())
+		first := &unstructured.Unstructured{}
+		first.SetGroupVersionKind(objects[0].GroupVersionKind())
+		err = client.Get(ctx, clnt.ObjectKeyFromObject(objects[0]), first)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.GetAnnotations()).To(Equal(map[string]string{
+			"my-annotation": "my-value",
+		}))
+		second := &unstructured.Unstructured{}
+		second.SetGroupVersionKind(objects[1].GroupVersionKind())
+		err = client.Get(ctx, clnt.ObjectKeyFromObject(objects[1]), second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.GetAnnotations()).To(BeEmpty())
+
+		// The rendered objects should still have the annotations:
+		Expect(objects[0].GetAnnotations()).To(HaveKey("ztpfw/depends-on"))
+		Expect(objects[0].GetAnnotations()).To(HaveKey("ztpfw/health"))
+	})
+})
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/annotations"
+)
+
+// waitHealthy waits till all the given objects are healthy.
+func (a *Applier) waitHealthy(ctx context.Context, objects []*unstructured.Unstructured) error {
+	for _, object := range objects {
+		err := a.waitObjectHealthy(ctx, object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Applier) waitObjectHealthy(ctx context.Context, object *unstructured.Unstructured) error {
+	// Most objects will be healthy inmediately, so check first without sending any event:
+	healthy, err := a.checkHealth(ctx, object)
+	if err != nil {
+		return err
+	}
+	if healthy {
+		return nil
+	}
+
+	// Check again periodically till the object is healthy or the stage times out:
+	a.fireInfo(ApplierWaitingHealthy, object)
+	err = a.waiter.Wait(ctx, &WaitStage{
+		Name: fmt.Sprintf(
+			"object '%s/%s' of kind '%s' to be healthy",
+			object.GetNamespace(), object.GetName(), object.GetKind(),
+		),
+		Objects: []*unstructured.Unstructured{object},
+		Check: func(ctx context.Context, current []*unstructured.Unstructured) (bool, error) {
+			return a.isHealthy(ctx, object, current[0])
+		},
+	})
+	if err != nil {
+		return err
+	}
+	a.fireInfo(ApplierObjectHealthy, object)
+	return nil
+}
+
+// checkHealth retrieves the current state of the object and checks if it is healthy.
+func (a *Applier) checkHealth(ctx context.Context, object *unstructured.Unstructured) (result bool,
+	err error) {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(object.GroupVersionKind())
+	err = a.client.Get(ctx, clnt.ObjectKeyFromObject(object), current)
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	result, err = a.isHealthy(ctx, object, current)
+	return
+}
+
+// isHealthy checks if the current state of the object is healthy. The jq expression in the health
+// annotation has preference, then the check specific for the kind, and finally the generic check of
+// the conditions, which is different for built-in objects and for custom resources. Objects that
+// don't exist aren't healthy.
+func (a *Applier) isHealthy(ctx context.Context, object, current *unstructured.Unstructured) (
+	result bool, err error) {
+	if current == nil {
+		return
+	}
+	expr, ok := object.GetAnnotations()[annotations.Health]
+	if ok {
+		result, err = a.evalHealth(expr, current)
+		return
+	}
+	gk := current.GroupVersionKind().GroupKind()
+	if gk == SubscriptionGVK.GroupKind() {
+		result, err = a.checkSubscriptionHealth(ctx, current)
+		return
+	}
+	expr, ok = applierHealthExprs[gk]
+	if !ok {
+		if applierBuiltinGroup(gk.Group) {
+			expr = applierHealthBuiltinExpr
+		} else {
+			expr = applierHealthCustomExpr
+		}
+	}
+	result, err = a.evalHealth(expr, current)
+	return
+}
+
+// checkSubscriptionHealth checks that the cluster service version installed by the subscription is
+// in the 'Succeeded' phase.
+func (a *Applier) checkSubscriptionHealth(ctx context.Context,
+	subscription *unstructured.Unstructured) (result bool, err error) {
+	var name string
+	err = a.jq.Query(`.status.installedCSV // ""`, subscription.Object, &name)
+	if err != nil || name == "" {
+		return
+	}
+	csv := &unstructured.Unstructured{}
+	csv.SetGroupVersionKind(ClusterServiceVersionGVK)
+	key := clnt.ObjectKey{
+		Namespace: subscription.GetNamespace(),
+		Name:      name,
+	}
+	err = a.client.Get(ctx, key, csv)
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	result, err = a.evalHealth(`.status.phase == "Succeeded"`, csv)
+	return
+}
+
+func (a *Applier) evalHealth(expr string, object *unstructured.Unstructured) (result bool,
+	err error) {
+	err = a.jq.Query(expr, object.Object, &result)
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to check health of object '%s/%s' of kind '%s': %w",
+			object.GetNamespace(), object.GetName(), object.GetKind(), err,
+		)
+	}
+	return
+}
+
+// applierHealthExprs contains the jq expressions used to check the health of objects of specific
+// kinds.
+var applierHealthExprs = map[schema.GroupKind]string{
+	OperatorGroupGVK.GroupKind(): `.status.lastUpdated != null`,
+	CustomResourceDefinitionGVK.GroupKind(): `any(
+		.status.conditions[]?;
+		.type == "Established" and .status == "True"
+	)`,
+	{Group: "apps", Kind: "DaemonSet"}: `(.status.observedGeneration // 0) >= (.metadata.generation // 0)
+		and (.status.numberAvailable // 0) >= (.status.desiredNumberScheduled // 0)`,
+	{Group: "apps", Kind: "Deployment"}: `(.status.observedGeneration // 0) >= (.metadata.generation // 0)
+		and (.status.availableReplicas // 0) >= (.spec.replicas // 1)`,
+	{Group: "apps", Kind: "StatefulSet"}: `(.status.observedGeneration // 0) >= (.metadata.generation // 0)
+		and (.status.readyReplicas // 0) >= (.spec.replicas // 1)`,
+}
+
+// applierHealthBuiltinExpr is the jq expression used to check the health of built-in objects that
+// don't have a specific check. It requires the 'Ready' and 'Available' conditions to be true if they
+// are present. Objects that don't have any of these conditions, like config maps or secrets, are
+// considered healthy.
+const applierHealthBuiltinExpr = `all(
+	.status.conditions[]? | select(.type == "Ready" or .type == "Available");
+	.status == "True"
+)`
+
+// applierHealthCustomExpr is the jq expression used to check the health of custom resources that
+// don't have a specific check. It requires at least one 'Ready' or 'Available' condition, and all of
+// them to be true. Note that this means that custom resources that don't have any of these
+// conditions will never be healthy, so they need the health annotation.
+const applierHealthCustomExpr = `[
+	.status.conditions[]? | select(.type == "Ready" or .type == "Available")
+] | length > 0 and all(.status == "True")`
+
+// applierBuiltinGroup checks if the given API group is one of the groups built into Kubernetes. Those
+// are the core group, the groups without dots, like 'apps' or 'batch', and the ones that end with
+// '.k8s.io'.
+func applierBuiltinGroup(group string) bool {
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
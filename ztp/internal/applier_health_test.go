@@ -0,0 +1,284 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
+)
+
+var _ = Describe("Applier health", func() {
+	var (
+		ctx     context.Context
+		client  clnt.WithWatch
+		events  []ApplierEventType
+		applier *Applier
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		var logger logr.Logger
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		events = nil
+		tmp, fsys := TmpFS("objects.yaml", "")
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		applier, err = NewApplier().
+			SetLogger(logger).
+			SetClient(client).
+			SetFS(fsys).
+			SetWait(true).
+			SetListener(func(event *ApplierEvent) {
+				events = append(events, event.Type)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// makeObject creates an unstructured object from the given map.
+	makeObject := func(data map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: data,
+		}
+	}
+
+	It("Doesn't wait for objects that are already healthy", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-deployment",
+			},
+			"spec": map[string]any{
+				"replicas": 2,
+			},
+			"status": map[string]any{
+				"availableReplicas": 2,
+			},
+		})
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]ApplierEventType{
+			ApplierObjectCreated,
+			ApplierStatusUpdated,
+		}))
+	})
+
+	It("Considers healthy objects without conditions", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-config",
+			},
+		})
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]ApplierEventType{
+			ApplierObjectCreated,
+		}))
+	})
+
+	It("Waits till the ready condition is true", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-example",
+			},
+			"status": map[string]any{
+				"conditions": []any{
+					map[string]any{
+						"type":   "Ready",
+						"status": "False",
+					},
+				},
+			},
+		})
+
+		// Mark the object as ready after a while:
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(100 * time.Millisecond)
+			current := object.DeepCopy()
+			err := client.Get(ctx, clnt.ObjectKeyFromObject(object), current)
+			Expect(err).ToNot(HaveOccurred())
+			err = unstructured.SetNestedSlice(current.Object, []any{
+				map[string]any{
+					"type":   "Ready",
+					"status": "True",
+				},
+			}, "status", "conditions")
+			Expect(err).ToNot(HaveOccurred())
+			err = client.Update(ctx, current)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]ApplierEventType{
+			ApplierObjectCreated,
+			ApplierStatusUpdated,
+			ApplierWaitingHealthy,
+			ApplierObjectHealthy,
+		}))
+	})
+
+	It("Waits for custom resources that don't have status yet", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-example",
+			},
+		})
+
+		// Add the ready condition after a while:
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(100 * time.Millisecond)
+			current := object.DeepCopy()
+			err := client.Get(ctx, clnt.ObjectKeyFromObject(object), current)
+			Expect(err).ToNot(HaveOccurred())
+			err = unstructured.SetNestedSlice(current.Object, []any{
+				map[string]any{
+					"type":   "Ready",
+					"status": "True",
+				},
+			}, "status", "conditions")
+			Expect(err).ToNot(HaveOccurred())
+			err = client.Update(ctx, current)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(ContainElements(
+			ApplierObjectCreated,
+			ApplierWaitingHealthy,
+			ApplierObjectHealthy,
+		))
+		Expect(events).To(HaveLen(3))
+	})
+
+	It("Uses the expression from the annotation", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-example",
+				"annotations": map[string]any{
+					"ztpfw/health": ".status.ready",
+				},
+			},
+			"status": map[string]any{
+				"ready": false,
+			},
+		})
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).To(HaveOccurred())
+		var waitErr *WaitError
+		Expect(errors.As(err, &waitErr)).To(BeTrue())
+		Expect(waitErr.Stage).To(Equal(
+			"object 'my-ns/my-example' of kind 'Example' to be healthy",
+		))
+		Expect(waitErr.Snapshots).To(HaveLen(1))
+		Expect(waitErr.Report()).To(ContainSubstring("ready: false"))
+		Expect(events).To(Equal([]ApplierEventType{
+			ApplierObjectCreated,
+			ApplierStatusUpdated,
+			ApplierWaitingHealthy,
+		}))
+	})
+
+	It("Fails if the expression from the annotation is wrong", func() {
+		object := makeObject(map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-example",
+				"annotations": map[string]any{
+					"ztpfw/health": ".status.ready ==",
+				},
+			},
+		})
+		err := applier.ApplyObjects(ctx, []*unstructured.Unstructured{object})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix(
+			"failed to check health of object 'my-ns/my-example' of kind 'Example'",
+		))
+	})
+
+	It("Checks the phase of the cluster service version of subscriptions", func() {
+		csv := makeObject(map[string]any{
+			"apiVersion": "operators.coreos.com/v1alpha1",
+			"kind":       "ClusterServiceVersion",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-operator.v1",
+			},
+			"status": map[string]any{
+				"phase": "Succeeded",
+			},
+		})
+		err := client.Create(ctx, csv)
+		Expect(err).ToNot(HaveOccurred())
+		subscription := makeObject(map[string]any{
+			"apiVersion": "operators.coreos.com/v1alpha1",
+			"kind":       "Subscription",
+			"metadata": map[string]any{
+				"namespace": "my-ns",
+				"name":      "my-operator",
+			},
+			"status": map[string]any{
+				"installedCSV": "my-operator.v1",
+			},
+		})
+		err = applier.ApplyObjects(ctx, []*unstructured.Unstructured{subscription})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]ApplierEventType{
+			ApplierObjectCreated,
+			ApplierStatusUpdated,
+		}))
+	})
+})
@@ -0,0 +1,229 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/labels"
+)
+
+// applierInventoryItem is the reference to an object stored in the inventory.
+type applierInventoryItem struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Namespace  string `yaml:"namespace,omitempty"`
+	Name       string `yaml:"name"`
+}
+
+// applierInventoryItemFor creates the inventory item that references the given object.
+func applierInventoryItemFor(object *unstructured.Unstructured) applierInventoryItem {
+	return applierInventoryItem{
+		APIVersion: object.GetAPIVersion(),
+		Kind:       object.GetKind(),
+		Namespace:  object.GetNamespace(),
+		Name:       object.GetName(),
+	}
+}
+
+// key returns the string that identifies the object. Note that it doesn't contain the version,
+// because changing the version used in a template doesn't mean that the object is a different one.
+func (i applierInventoryItem) key() string {
+	gv, _ := schema.ParseGroupVersion(i.APIVersion)
+	return fmt.Sprintf("%s/%s/%s/%s", gv.Group, i.Kind, i.Namespace, i.Name)
+}
+
+// object creates an object that contains only the type, namespace and name, enough to delete it.
+func (i applierInventoryItem) object() *unstructured.Unstructured {
+	result := &unstructured.Unstructured{}
+	result.SetAPIVersion(i.APIVersion)
+	result.SetKind(i.Kind)
+	result.SetNamespace(i.Namespace)
+	result.SetName(i.Name)
+	return result
+}
+
+// updateInventory compares the objects recorded in the inventory by previous runs with the objects
+// generated now, and prunes or reports the objects that are no longer generated. Then it saves the
+// updated inventory.
+func (a *Applier) updateInventory(ctx context.Context, objects []*unstructured.Unstructured) error {
+	// Find the objects that are in the inventory but that are no longer generated:
+	previous, err := a.loadInventory(ctx)
+	if err != nil {
+		return err
+	}
+	current := make([]applierInventoryItem, len(objects))
+	keys := map[string]bool{}
+	for i, object := range objects {
+		current[i] = applierInventoryItemFor(object)
+		keys[current[i].key()] = true
+	}
+	var stale []applierInventoryItem
+	for _, item := range previous {
+		if !keys[item.key()] {
+			stale = append(stale, item)
+		}
+	}
+
+	// Prune or report the stale objects:
+	if len(stale) > 0 {
+		staleObjects := make([]*unstructured.Unstructured, len(stale))
+		for i, item := range stale {
+			staleObjects[i] = item.object()
+		}
+		switch {
+		case a.prune && a.dryRun:
+			err = a.diffObjects(ctx, staleObjects, true)
+		case a.prune:
+			err = a.deleteAll(ctx, staleObjects)
+		default:
+			for _, object := range staleObjects {
+				a.fireInfo(ApplierObjectStale, object)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the inventory. Objects that haven't been pruned are kept, so that they can be pruned
+	// in a later run.
+	if a.dryRun {
+		return nil
+	}
+	if !a.prune {
+		current = append(current, stale...)
+	}
+	return a.saveInventory(ctx, current)
+}
+
+func (a *Applier) loadInventory(ctx context.Context) (result []applierInventoryItem, err error) {
+	configMap := &corev1.ConfigMap{}
+	key := clnt.ObjectKey{
+		Namespace: applierInventoryNamespace,
+		Name:      a.inventory,
+	}
+	err = a.client.Get(ctx, key, configMap)
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to load inventory '%s': %w", a.inventory, err)
+		return
+	}
+	err = yaml.Unmarshal([]byte(configMap.Data[applierInventoryKey]), &result)
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to parse key '%s' of inventory config map '%s/%s': %w",
+			applierInventoryKey, key.Namespace, key.Name, err,
+		)
+		return
+	}
+	return
+}
+
+func (a *Applier) saveInventory(ctx context.Context, items []applierInventoryItem) error {
+	// Sort the items, so that the content of the config map doesn't change when the order of
+	// the templates changes:
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].key() < items[j].key()
+	})
+	data, err := yaml.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	// Make sure that the namespace exists:
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: applierInventoryNamespace,
+			Labels: map[string]string{
+				labels.ZTPFW: "",
+			},
+		},
+	}
+	err = a.client.Create(ctx, namespace)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf(
+			"failed to create namespace for inventory '%s': %w",
+			a.inventory, err,
+		)
+	}
+
+	// Create or update the config map:
+	configMap := &corev1.ConfigMap{}
+	key := clnt.ObjectKey{
+		Namespace: applierInventoryNamespace,
+		Name:      a.inventory,
+	}
+	err = a.client.Get(ctx, key, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+				Labels: map[string]string{
+					labels.ZTPFW: "",
+				},
+			},
+			Data: map[string]string{
+				applierInventoryKey: string(data),
+			},
+		}
+		err = a.client.Create(ctx, configMap)
+	} else if err == nil {
+		update := configMap.DeepCopy()
+		update.Data = map[string]string{
+			applierInventoryKey: string(data),
+		}
+		err = a.client.Patch(ctx, update, clnt.MergeFrom(configMap))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save inventory '%s': %w", a.inventory, err)
+	}
+	return nil
+}
+
+func (a *Applier) deleteInventory(ctx context.Context) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: applierInventoryNamespace,
+			Name:      a.inventory,
+		},
+	}
+	err := a.client.Delete(ctx, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete inventory '%s': %w", a.inventory, err)
+	}
+	return nil
+}
+
+// applierInventoryNamespace is the namespace where the applier saves the inventories of the
+// objects that it created.
+const applierInventoryNamespace = "ztpfw-inventory"
+
+// applierInventoryKey is the key of the inventory config map that contains the list of objects.
+const applierInventoryKey = "objects"
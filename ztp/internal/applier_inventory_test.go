@@ -0,0 +1,199 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Applier inventory", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+		events []*ApplierEvent
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		events = nil
+	})
+
+	// makeFS creates a templates filesystem that generates one config map for each of the
+	// given names.
+	makeFS := func(names ...string) fs.FS {
+		buffer := &bytes.Buffer{}
+		for _, name := range names {
+			buffer.WriteString(text.Dedent(`
+				---
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  namespace: my-ns
+				  name: ` + name + `
+			`))
+		}
+		tmp, fsys := TmpFS("objects.yaml", buffer.String())
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		return fsys
+	}
+
+	// makeApplier creates an applier that uses the 'my' inventory and saves the events.
+	makeApplier := func(fsys fs.FS, prune bool) *Applier {
+		applier, err := NewApplier().
+			SetLogger(logger).
+			SetClient(client).
+			SetFS(fsys).
+			SetInventory("my").
+			SetPrune(prune).
+			SetListener(func(event *ApplierEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return applier
+	}
+
+	// getInventory returns the names of the objects in the inventory.
+	getInventory := func() []string {
+		configMap := &corev1.ConfigMap{}
+		err := client.Get(ctx, clnt.ObjectKey{
+			Namespace: applierInventoryNamespace,
+			Name:      "my",
+		}, configMap)
+		Expect(err).ToNot(HaveOccurred())
+		var items []applierInventoryItem
+		err = yaml.Unmarshal([]byte(configMap.Data[applierInventoryKey]), &items)
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, len(items))
+		for i, item := range items {
+			Expect(item.APIVersion).To(Equal("v1"))
+			Expect(item.Kind).To(Equal("ConfigMap"))
+			Expect(item.Namespace).To(Equal("my-ns"))
+			names[i] = item.Name
+		}
+		return names
+	}
+
+	// exists checks if the config map with the given name exists.
+	exists := func(name string) bool {
+		err := client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my-ns",
+			Name:      name,
+		}, &corev1.ConfigMap{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return true
+	}
+
+	It("Can't enable pruning without inventory", func() {
+		_, err := NewApplier().
+			SetLogger(logger).
+			SetClient(client).
+			SetFS(makeFS("a")).
+			SetPrune(true).
+			Build()
+		Expect(err).To(MatchError("pruning requires an inventory"))
+	})
+
+	It("Records the created objects", func() {
+		applier := makeApplier(makeFS("b", "a"), false)
+		err := applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getInventory()).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Reports objects that are no longer generated", func() {
+		applier := makeApplier(makeFS("a", "b"), false)
+		err := applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Apply again without one of the objects:
+		events = nil
+		applier = makeApplier(makeFS("a"), false)
+		err = applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal(ApplierObjectExist))
+		Expect(events[1].Type).To(Equal(ApplierObjectStale))
+		Expect(events[1].Object.GetName()).To(Equal("b"))
+
+		// The object should still exist and be in the inventory:
+		Expect(exists("b")).To(BeTrue())
+		Expect(getInventory()).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Prunes objects that are no longer generated", func() {
+		applier := makeApplier(makeFS("a", "b"), false)
+		err := applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Apply again without one of the objects and with pruning enabled:
+		events = nil
+		applier = makeApplier(makeFS("a"), true)
+		err = applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal(ApplierObjectExist))
+		Expect(events[1].Type).To(Equal(ApplierObjectDeleted))
+		Expect(events[1].Object.GetName()).To(Equal("b"))
+
+		// The object should have been deleted and removed from the inventory:
+		Expect(exists("a")).To(BeTrue())
+		Expect(exists("b")).To(BeFalse())
+		Expect(getInventory()).To(Equal([]string{"a"}))
+	})
+
+	It("Deletes the inventory when objects are deleted", func() {
+		applier := makeApplier(makeFS("a"), false)
+		err := applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		err = applier.Delete(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: applierInventoryNamespace,
+			Name:      "my",
+		}, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
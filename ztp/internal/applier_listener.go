@@ -18,11 +18,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/go-logr/logr"
 	"github.com/iancoleman/strcase"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
 )
 
 // ApplierListenerBuilder contains the data and logic needed to create an object that listens for
@@ -30,13 +33,16 @@ import (
 type ApplierListenerBuilder struct {
 	logger  logr.Logger
 	console *Console
+	cluster string
 }
 
 // ApplierListener knows how to write to the console human friendly representations of applier
-// events.
+// events. When the output format of the console is ConsoleOutputJSONL it writes them as JSON
+// objects instead, together with the events of the waiter and the enricher.
 type ApplierListener struct {
 	logger  logr.Logger
 	console *Console
+	cluster string
 }
 
 // NewApplierListener creates a builder that can then be used to create a listener.
@@ -56,6 +62,13 @@ func (b *ApplierListenerBuilder) SetConsole(value *Console) *ApplierListenerBuil
 	return b
 }
 
+// SetCluster sets the name of the cluster that the events are related to. It will be included in
+// the JSON representation of the events. This is optional.
+func (b *ApplierListenerBuilder) SetCluster(value string) *ApplierListenerBuilder {
+	b.cluster = value
+	return b
+}
+
 // Build uses the data stored in the builder to create a new listener.
 func (b *ApplierListenerBuilder) Build() (result *ApplierListener, err error) {
 	// Check parameters:
@@ -72,6 +85,7 @@ func (b *ApplierListenerBuilder) Build() (result *ApplierListener, err error) {
 	result = &ApplierListener{
 		logger:  b.logger,
 		console: b.console,
+		cluster: b.cluster,
 	}
 	return
 }
@@ -79,6 +93,12 @@ func (b *ApplierListenerBuilder) Build() (result *ApplierListener, err error) {
 // Func is the listener function that should be passed to the SetListener or AddListener methods of
 // the applier builder.
 func (l *ApplierListener) Func(event *ApplierEvent) {
+	// Write the structured representation if that is the selected output format:
+	if l.console.Output() == ConsoleOutputJSONL {
+		l.writeApplierEvent(event)
+		return
+	}
+
 	// Get the friendlyKind and name to use in the messages:
 	friendlyKind := l.friendlyKind(event.Object)
 	friendlyName := l.friendlyName(event.Object)
@@ -99,6 +119,27 @@ func (l *ApplierListener) Func(event *ApplierEvent) {
 			"%s '%s' already exists",
 			capitalizedKind, friendlyName,
 		)
+	case ApplierObjectUpdated:
+		l.console.Info(
+			"Updated %s '%s', changed %s",
+			friendlyKind, friendlyName, logging.All(event.Changes),
+		)
+	case ApplierObjectUnchanged:
+		l.console.Info(
+			"%s '%s' is up to date",
+			capitalizedKind, friendlyName,
+		)
+	case ApplierObjectDiff:
+		l.console.Info(
+			"Differences for %s '%s':",
+			friendlyKind, friendlyName,
+		)
+		l.console.Print(event.Diff)
+	case ApplierObjectStale:
+		l.console.Warn(
+			"%s '%s' is no longer generated and can be pruned",
+			capitalizedKind, friendlyName,
+		)
 	case ApplierObjectNotExist:
 		l.console.Warn(
 			"%s '%s' doesn't exist",
@@ -109,6 +150,11 @@ func (l *ApplierListener) Func(event *ApplierEvent) {
 			"Failed to create %s '%s': %v",
 			friendlyKind, friendlyName, event.Error,
 		)
+	case ApplierApplyError:
+		l.console.Error(
+			"Failed to apply %s '%s': %v",
+			friendlyKind, friendlyName, event.Error,
+		)
 	case ApplierDeleteError:
 		l.console.Error(
 			"Failed to delete %s '%s': %v",
@@ -134,6 +180,26 @@ func (l *ApplierListener) Func(event *ApplierEvent) {
 			"Waiting for CRD before creating %s '%s'",
 			friendlyKind, friendlyName,
 		)
+	case ApplierWaitingHealthy:
+		l.console.Info(
+			"Waiting for %s '%s' to be healthy",
+			friendlyKind, friendlyName,
+		)
+	case ApplierObjectHealthy:
+		l.console.Info(
+			"%s '%s' is now healthy",
+			capitalizedKind, friendlyName,
+		)
+	case ApplierWaitingDrain:
+		l.console.Info(
+			"Waiting for objects of %s '%s' to be deleted",
+			friendlyKind, friendlyName,
+		)
+	case ApplierFinalizersRemoved:
+		l.console.Warn(
+			"Removed finalizers %s from %s '%s'",
+			logging.All(event.Finalizers), friendlyKind, friendlyName,
+		)
 	case ApplierWaitingDisappear:
 		l.console.Info(
 			"Waiting for %s '%s' to disappear before deleting namespace",
@@ -149,6 +215,86 @@ func (l *ApplierListener) Func(event *ApplierEvent) {
 	}
 }
 
+// WaiterFunc is the listener function that should be passed to the AddWaiterListener method of the
+// applier builder or to the SetListener and AddListener methods of the waiter builder. Wait
+// transitions are only written when the output format is ConsoleOutputJSONL, because for the human
+// friendly format the applier and the commands already report what they wait for.
+func (l *ApplierListener) WaiterFunc(event *WaiterEvent) {
+	if l.console.Output() != ConsoleOutputJSONL {
+		return
+	}
+	record := &ConsoleEvent{
+		Source:  ConsoleSourceWaiter,
+		Type:    string(event.Type),
+		Cluster: l.cluster,
+		Stage:   event.Stage,
+	}
+	if len(event.Objects) == 1 {
+		l.setObject(record, event.Objects[0])
+	}
+	for _, object := range event.Objects {
+		record.Objects = append(record.Objects, l.objectReference(object))
+	}
+	if event.Elapsed > 0 {
+		record.Elapsed = event.Elapsed.Round(time.Millisecond).String()
+	}
+	if event.Error != nil {
+		record.Error = event.Error.Error()
+	}
+	l.console.Event(record)
+}
+
+// EnricherFunc is the listener function that should be passed to the SetListener or AddListener
+// methods of the enricher builder. Like wait transitions, the decisions of the enricher are only
+// written when the output format is ConsoleOutputJSONL.
+func (l *ApplierListener) EnricherFunc(event *EnricherEvent) {
+	if l.console.Output() != ConsoleOutputJSONL {
+		return
+	}
+	record := &ConsoleEvent{
+		Source:  ConsoleSourceEnricher,
+		Type:    string(event.Type),
+		Cluster: event.Cluster,
+		Step:    event.Step,
+	}
+	if record.Cluster == "" {
+		record.Cluster = l.cluster
+	}
+	if event.Error != nil {
+		record.Error = event.Error.Error()
+	}
+	l.console.Event(record)
+}
+
+func (l *ApplierListener) writeApplierEvent(event *ApplierEvent) {
+	record := &ConsoleEvent{
+		Source:     ConsoleSourceApplier,
+		Type:       string(event.Type),
+		Cluster:    l.cluster,
+		Changes:    event.Changes,
+		Finalizers: event.Finalizers,
+		Message:    event.Diff,
+	}
+	l.setObject(record, event.Object)
+	if event.Error != nil {
+		record.Error = event.Error.Error()
+	}
+	l.console.Event(record)
+}
+
+func (l *ApplierListener) setObject(record *ConsoleEvent, object *unstructured.Unstructured) {
+	gvk := object.GroupVersionKind()
+	record.Group = gvk.Group
+	record.Version = gvk.Version
+	record.Kind = gvk.Kind
+	record.Namespace = object.GetNamespace()
+	record.Name = object.GetName()
+}
+
+func (l *ApplierListener) objectReference(object *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s", object.GetKind(), l.friendlyName(object))
+}
+
 func (l *ApplierListener) friendlyKind(object *unstructured.Unstructured) string {
 	kind := object.GetKind()
 	result, ok := applierFriendlyKinds[kind]
@@ -199,6 +345,7 @@ var applierFriendlyKinds = map[string]string{
 	"IPAddressPool":            "IP address pool",
 	"InfraEnv":                 "infrastructure environment",
 	"L2Advertisement":          "L2 advertisement",
+	"LVMCluster":               "LVM cluster",
 	"MetalLB":                  "metal load balancer",
 	"MultiClusterEngine":       "multicluster engine",
 	"NMState":                  "nmstate",
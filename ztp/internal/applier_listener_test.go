@@ -16,7 +16,10 @@ package internal
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
@@ -199,6 +202,71 @@ var _ = Describe("Applier listener", func() {
 			},
 			"I: Waiting for CRD before creating example 'my-ns/my-example'\n",
 		),
+		Entry(
+			"Waiting for object to be healthy",
+			&ApplierEvent{
+				Type: ApplierWaitingHealthy,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "LVMCluster",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-cluster",
+						},
+					},
+				},
+			},
+			"I: Waiting for LVM cluster 'my-ns/my-cluster' to be healthy\n",
+		),
+		Entry(
+			"Object healthy",
+			&ApplierEvent{
+				Type: ApplierObjectHealthy,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "Deployment",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-deployment",
+						},
+					},
+				},
+			},
+			"I: Deployment 'my-ns/my-deployment' is now healthy\n",
+		),
+		Entry(
+			"Waiting for objects of CRD to be deleted",
+			&ApplierEvent{
+				Type: ApplierWaitingDrain,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "CustomResourceDefinition",
+						"metadata": map[string]any{
+							"name": "examples.example.com",
+						},
+					},
+				},
+			},
+			"I: Waiting for objects of CRD 'examples.example.com' to be deleted\n",
+		),
+		Entry(
+			"Finalizers removed",
+			&ApplierEvent{
+				Type: ApplierFinalizersRemoved,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "Example",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-example",
+						},
+					},
+				},
+				Finalizers: []string{"example.com/a", "example.com/b"},
+			},
+			"W: Removed finalizers 'example.com/a' and 'example.com/b' from example "+
+				"'my-ns/my-example'\n",
+		),
 		Entry(
 			"Exception in friendly name (CRD instead of custom resource definition)",
 			&ApplierEvent{
@@ -229,5 +297,234 @@ var _ = Describe("Applier listener", func() {
 			},
 			"I: Created something with multiple words 'my-thing'\n",
 		),
+		Entry(
+			"Object updated",
+			&ApplierEvent{
+				Type: ApplierObjectUpdated,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "ConfigMap",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-config",
+						},
+					},
+				},
+				Changes: []string{
+					"data.x",
+					"data.y",
+				},
+			},
+			"I: Updated configmap 'my-ns/my-config', changed 'data.x' and 'data.y'\n",
+		),
+		Entry(
+			"Object unchanged",
+			&ApplierEvent{
+				Type: ApplierObjectUnchanged,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "ConfigMap",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-config",
+						},
+					},
+				},
+			},
+			"I: Configmap 'my-ns/my-config' is up to date\n",
+		),
+		Entry(
+			"Object stale",
+			&ApplierEvent{
+				Type: ApplierObjectStale,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "BareMetalHost",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-host",
+						},
+					},
+				},
+			},
+			"W: Bare metal host 'my-ns/my-host' is no longer generated and can be "+
+				"pruned\n",
+		),
+		Entry(
+			"Object differences",
+			&ApplierEvent{
+				Type: ApplierObjectDiff,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "ConfigMap",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-config",
+						},
+					},
+				},
+				Diff: "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-x: 1\n+x: 2\n",
+			},
+			"I: Differences for configmap 'my-ns/my-config':\n"+
+				"--- a\n+++ b\n@@ -1,1 +1,1 @@\n-x: 1\n+x: 2\n",
+		),
 	)
+
+	Describe("JSON lines output", func() {
+		var (
+			buffer   *bytes.Buffer
+			listener *ApplierListener
+		)
+
+		BeforeEach(func() {
+			var err error
+			buffer = &bytes.Buffer{}
+			console, err := NewConsole().
+				SetLogger(logger).
+				SetOut(buffer).
+				SetErr(buffer).
+				SetOutput(ConsoleOutputJSONL).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			listener, err = NewApplierListener().
+				SetLogger(logger).
+				SetConsole(console).
+				SetCluster("my-cluster").
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		// decode parses the JSON object written to the buffer, and checks that there is
+		// exactly one.
+		decode := func() map[string]any {
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			Expect(lines).To(HaveLen(1))
+			var result map[string]any
+			err := json.Unmarshal([]byte(lines[0]), &result)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(HaveKey("time"))
+			delete(result, "time")
+			return result
+		}
+
+		It("Writes applier events", func() {
+			listener.Func(&ApplierEvent{
+				Type: ApplierApplyError,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"apiVersion": "apps/v1",
+						"kind":       "Deployment",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-deployment",
+						},
+					},
+				},
+				Error: errors.New("my error"),
+			})
+			Expect(decode()).To(Equal(map[string]any{
+				"source":    "applier",
+				"type":      "ApplyError",
+				"cluster":   "my-cluster",
+				"group":     "apps",
+				"version":   "v1",
+				"kind":      "Deployment",
+				"namespace": "my-ns",
+				"name":      "my-deployment",
+				"error":     "my error",
+			}))
+		})
+
+		It("Writes wait transitions", func() {
+			listener.WaiterFunc(&WaiterEvent{
+				Type:  WaiterStageCompleted,
+				Stage: "my stage",
+				Objects: []*unstructured.Unstructured{{
+					Object: map[string]any{
+						"apiVersion": "v1",
+						"kind":       "ConfigMap",
+						"metadata": map[string]any{
+							"namespace": "my-ns",
+							"name":      "my-config",
+						},
+					},
+				}},
+				Elapsed: 2 * time.Second,
+			})
+			Expect(decode()).To(Equal(map[string]any{
+				"source":    "waiter",
+				"type":      "StageCompleted",
+				"cluster":   "my-cluster",
+				"version":   "v1",
+				"kind":      "ConfigMap",
+				"namespace": "my-ns",
+				"name":      "my-config",
+				"stage":     "my stage",
+				"objects":   []any{"ConfigMap/my-ns/my-config"},
+				"elapsed":   "2s",
+			}))
+		})
+
+		It("Writes enricher decisions", func() {
+			listener.EnricherFunc(&EnricherEvent{
+				Type:    EnricherStepFailed,
+				Step:    "my-step",
+				Cluster: "your-cluster",
+				Error:   errors.New("my error"),
+			})
+			Expect(decode()).To(Equal(map[string]any{
+				"source":  "enricher",
+				"type":    "StepFailed",
+				"cluster": "your-cluster",
+				"step":    "my-step",
+				"error":   "my error",
+			}))
+		})
+
+		It("Writes console messages", func() {
+			listener.Func(&ApplierEvent{
+				Type: ApplierObjectCreated,
+				Object: &unstructured.Unstructured{
+					Object: map[string]any{
+						"kind": "Namespace",
+						"metadata": map[string]any{
+							"name": "my-ns",
+						},
+					},
+				},
+			})
+			buffer.Reset()
+			listener.console.Warn("Hello!")
+			Expect(decode()).To(Equal(map[string]any{
+				"source":  "console",
+				"type":    "Message",
+				"level":   "warn",
+				"message": "Hello!",
+			}))
+		})
+	})
+
+	It("Doesn't write wait transitions or enricher decisions in text mode", func() {
+		buffer := &bytes.Buffer{}
+		console, err := NewConsole().
+			SetLogger(logger).
+			SetOut(buffer).
+			SetErr(buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		listener, err := NewApplierListener().
+			SetLogger(logger).
+			SetConsole(console).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		listener.WaiterFunc(&WaiterEvent{
+			Type:  WaiterStageStarted,
+			Stage: "my stage",
+		})
+		listener.EnricherFunc(&EnricherEvent{
+			Type: EnricherStepStarted,
+			Step: "my-step",
+		})
+		Expect(buffer.String()).To(BeEmpty())
+	})
 })
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// applierOverlayFS is a file system that layers the files of an overlay file system over the files
+// of a base file system. Files that exist in the overlay replace the files with the same path in
+// the base, and files that only exist in the overlay are added. The contents of directories that
+// exist in both are merged.
+type applierOverlayFS struct {
+	base    fs.FS
+	overlay fs.FS
+}
+
+// Make sure that we implement the interfaces used by the templating engine:
+var _ fs.ReadDirFS = (*applierOverlayFS)(nil)
+
+// Open is the implementation of the fs.FS interface.
+func (f *applierOverlayFS) Open(name string) (result fs.File, err error) {
+	// Regular files in the overlay take precedence. For directories we return the one from the
+	// base, if it exists, as the merged contents are returned by the ReadDir method.
+	info, err := fs.Stat(f.overlay, name)
+	if err == nil && !info.IsDir() {
+		result, err = f.overlay.Open(name)
+		return
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	result, err = f.base.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		result, err = f.overlay.Open(name)
+	}
+	return
+}
+
+// ReadDir is the implementation of the fs.ReadDirFS interface.
+func (f *applierOverlayFS) ReadDir(name string) (results []fs.DirEntry, err error) {
+	baseEntries, baseErr := fs.ReadDir(f.base, name)
+	if baseErr != nil && !errors.Is(baseErr, fs.ErrNotExist) {
+		err = baseErr
+		return
+	}
+	overlayEntries, overlayErr := fs.ReadDir(f.overlay, name)
+	if overlayErr != nil && !errors.Is(overlayErr, fs.ErrNotExist) {
+		err = overlayErr
+		return
+	}
+	if baseErr != nil && overlayErr != nil {
+		err = baseErr
+		return
+	}
+	index := map[string]fs.DirEntry{}
+	for _, entry := range baseEntries {
+		index[entry.Name()] = entry
+	}
+	for _, entry := range overlayEntries {
+		index[entry.Name()] = entry
+	}
+	results = make([]fs.DirEntry, 0, len(index))
+	for _, entry := range index {
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name() < results[j].Name()
+	})
+	return
+}
+
+// added returns the paths of the regular files of the overlay that don't replace any file of the
+// base. These are usually new templates, but they may also be the result of using a wrong path for
+// a file that was intended to replace a template.
+func (f *applierOverlayFS) added() (results []string, err error) {
+	err = fs.WalkDir(f.overlay, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		_, err = fs.Stat(f.base, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			results = append(results, path)
+			return nil
+		}
+		return err
+	})
+	return
+}
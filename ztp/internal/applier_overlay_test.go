@@ -0,0 +1,421 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Applier overlay", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+	})
+
+	// makeDir creates a temporary directory containing the given files, and removes it when the
+	// test finishes.
+	makeDir := func(args ...any) string {
+		for i := 1; i < len(args); i += 2 {
+			args[i] = text.Dedent(args[i].(string))
+		}
+		tmp, _ := TmpFS(args...)
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		return tmp
+	}
+
+	// findObject finds the object with the given kind and name.
+	findObject := func(objects []*unstructured.Unstructured,
+		kind, name string) *unstructured.Unstructured {
+		for _, object := range objects {
+			if object.GetKind() == kind && object.GetName() == name {
+				return object
+			}
+		}
+		return nil
+	}
+
+	Describe("Files", func() {
+		var templates string
+
+		BeforeEach(func() {
+			templates = makeDir(
+				"templates/objects/0010-first.yaml",
+				`
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  name: first
+				data:
+				  value: {{ .Value }}
+				`,
+				"templates/objects/0020-second.yaml",
+				`
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  name: second
+				`,
+			)
+		})
+
+		It("Replaces files and adds new ones", func() {
+			overlay := makeDir(
+				"objects/0010-first.yaml",
+				`
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  name: first
+				data:
+				  value: {{ .Value }}
+				  other: replaced
+				`,
+				"objects/0030-third.yaml",
+				`
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  name: third
+				`,
+			)
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				SetRoot("templates").
+				SetDir("objects").
+				SetOverlay(overlay).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			objects, err := applier.Render(ctx, map[string]any{
+				"Value": "my-value",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(objects).To(HaveLen(3))
+			Expect(objects[0].GetName()).To(Equal("first"))
+			Expect(objects[0].Object["data"]).To(Equal(map[string]any{
+				"value": "my-value",
+				"other": "replaced",
+			}))
+			Expect(objects[1].GetName()).To(Equal("second"))
+			Expect(objects[2].GetName()).To(Equal("third"))
+		})
+
+		It("Writes to the log the files that don't replace any template", func() {
+			overlay := makeDir(
+				"0010-first.yaml",
+				`
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  name: first
+				`,
+			)
+			buffer := &bytes.Buffer{}
+			logger, err := logging.NewLogger().
+				SetWriter(io.MultiWriter(buffer, GinkgoWriter)).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				SetRoot("templates").
+				SetDir("objects").
+				SetOverlay(overlay).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("don't replace any template"))
+			Expect(buffer.String()).To(ContainSubstring(`"files":["0010-first.yaml"]`))
+		})
+
+		It("Fails if the overlay doesn't exist", func() {
+			_, err := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				SetRoot("templates").
+				SetOverlay(filepath.Join(templates, "junk")).
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to check templates overlay"))
+		})
+	})
+
+	Describe("Patches", func() {
+		var templates string
+
+		BeforeEach(func() {
+			templates = makeDir(
+				"objects.yaml",
+				`
+				apiVersion: apps/v1
+				kind: Deployment
+				metadata:
+				  namespace: my-ns
+				  name: my-deployment
+				spec:
+				  template:
+				    spec:
+				      containers:
+				      - name: first
+				        image: first:1
+				      - name: second
+				        image: second:1
+				---
+				apiVersion: extensions.hive.openshift.io/v1beta1
+				kind: AgentClusterInstall
+				metadata:
+				  namespace: my-cluster
+				  name: my-cluster
+				spec:
+				  manifestsConfigMapRef:
+				    name: my-manifests
+				  fips: true
+				---
+				apiVersion: extensions.hive.openshift.io/v1beta1
+				kind: AgentClusterInstall
+				metadata:
+				  namespace: your-cluster
+				  name: your-cluster
+				spec:
+				  fips: true
+				`,
+			)
+		})
+
+		// render creates an applier with the given patches and renders the objects.
+		render := func(patches ...*ApplierPatch) ([]*unstructured.Unstructured, error) {
+			builder := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates))
+			for _, patch := range patches {
+				builder.AddPatch(patch)
+			}
+			applier, err := builder.Build()
+			Expect(err).ToNot(HaveOccurred())
+			return applier.Render(ctx, nil)
+		}
+
+		It("Applies strategic merge patches", func() {
+			objects, err := render(&ApplierPatch{
+				Target: ApplierPatchTarget{
+					Kind: "Deployment",
+				},
+				Patch: map[string]any{
+					"spec": map[string]any{
+						"template": map[string]any{
+							"spec": map[string]any{
+								"containers": []any{
+									map[string]any{
+										"name":  "second",
+										"image": "second:2",
+									},
+								},
+							},
+						},
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			deployment := findObject(objects, "Deployment", "my-deployment")
+			Expect(deployment).ToNot(BeNil())
+			containers, _, _ := unstructured.NestedSlice(
+				deployment.Object, "spec", "template", "spec", "containers",
+			)
+			Expect(containers).To(ConsistOf(
+				map[string]any{
+					"name":  "first",
+					"image": "first:1",
+				},
+				map[string]any{
+					"name":  "second",
+					"image": "second:2",
+				},
+			))
+		})
+
+		It("Uses merge patches for kinds without strategic merge information", func() {
+			objects, err := render(&ApplierPatch{
+				Target: ApplierPatchTarget{
+					Kind: "AgentClusterInstall",
+				},
+				Patch: "spec: {fips: false}",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			for _, name := range []string{"my-cluster", "your-cluster"} {
+				install := findObject(objects, "AgentClusterInstall", name)
+				Expect(install).ToNot(BeNil())
+				fips, _, _ := unstructured.NestedBool(install.Object, "spec", "fips")
+				Expect(fips).To(BeFalse())
+			}
+		})
+
+		It("Applies JSON patches only to the objects that match the target", func() {
+			objects, err := render(&ApplierPatch{
+				Target: ApplierPatchTarget{
+					Group: "extensions.hive.openshift.io",
+					Kind:  "AgentClusterInstall",
+					Name:  "my-*",
+				},
+				Type: ApplierJSONPatch,
+				Patch: []any{
+					map[string]any{
+						"op":   "remove",
+						"path": "/spec/fips",
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			mine := findObject(objects, "AgentClusterInstall", "my-cluster")
+			Expect(mine).ToNot(BeNil())
+			_, found, _ := unstructured.NestedFieldNoCopy(mine.Object, "spec", "fips")
+			Expect(found).To(BeFalse())
+			yours := findObject(objects, "AgentClusterInstall", "your-cluster")
+			Expect(yours).ToNot(BeNil())
+			_, found, _ = unstructured.NestedFieldNoCopy(yours.Object, "spec", "fips")
+			Expect(found).To(BeTrue())
+		})
+
+		It("Reports patches that can't be applied", func() {
+			_, err := render(&ApplierPatch{
+				Target: ApplierPatchTarget{
+					Kind: "Deployment",
+				},
+				Type: ApplierJSONPatch,
+				Patch: []any{
+					map[string]any{
+						"op":   "remove",
+						"path": "/spec/junk",
+					},
+				},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(
+				"failed to apply patch 0 to object " +
+					"'my-ns/my-deployment' of kind 'Deployment'",
+			))
+		})
+
+		It("Loads patches from files", func() {
+			dir := makeDir(
+				"patches.yaml",
+				`
+				target:
+				  kind: AgentClusterInstall
+				  namespace: your-cluster
+				type: merge
+				patch: |
+				  metadata:
+				    annotations:
+				      example.com/my: value
+				---
+				target:
+				  kind: Deployment
+				type: json
+				patch:
+				- op: replace
+				  path: /spec/template/spec/containers/0/image
+				  value: first:2
+				`,
+			)
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				AddPatchFile(filepath.Join(dir, "patches.yaml")).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			objects, err := applier.Render(ctx, nil)
+			Expect(err).ToNot(HaveOccurred())
+			yours := findObject(objects, "AgentClusterInstall", "your-cluster")
+			Expect(yours).ToNot(BeNil())
+			Expect(yours.GetAnnotations()).To(HaveKeyWithValue("example.com/my", "value"))
+			mine := findObject(objects, "AgentClusterInstall", "my-cluster")
+			Expect(mine).ToNot(BeNil())
+			Expect(mine.GetAnnotations()).To(BeEmpty())
+			deployment := findObject(objects, "Deployment", "my-deployment")
+			Expect(deployment).ToNot(BeNil())
+			containers, _, _ := unstructured.NestedSlice(
+				deployment.Object, "spec", "template", "spec", "containers",
+			)
+			Expect(containers[0]).To(HaveKeyWithValue("image", "first:2"))
+		})
+
+		It("Rejects patches with unknown types", func() {
+			_, err := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				AddPatch(&ApplierPatch{
+					Target: ApplierPatchTarget{
+						Kind: "Deployment",
+					},
+					Type:  "junk",
+					Patch: map[string]any{},
+				}).
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(
+				"type 'junk' of patch 0 isn't valid, valid values " +
+					"are 'strategic', 'merge' and 'json'",
+			))
+		})
+
+		It("Rejects patches without kind", func() {
+			_, err := NewApplier().
+				SetLogger(logger).
+				SetClient(client).
+				SetFS(os.DirFS(templates)).
+				AddPatch(&ApplierPatch{
+					Patch: map[string]any{},
+				}).
+				Build()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("target kind of patch 0 is mandatory"))
+		})
+	})
+})
@@ -0,0 +1,261 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ApplierPatchType is the type of a patch that the applier applies to rendered objects.
+type ApplierPatchType string
+
+const (
+	// ApplierStrategicMergePatch is a Kubernetes strategic merge patch. For kinds that don't
+	// have strategic merge information, like custom resources, it behaves like a JSON merge
+	// patch.
+	ApplierStrategicMergePatch ApplierPatchType = "strategic"
+
+	// ApplierMergePatch is a JSON merge patch as described in RFC 7386.
+	ApplierMergePatch ApplierPatchType = "merge"
+
+	// ApplierJSONPatch is a JSON patch as described in RFC 6902.
+	ApplierJSONPatch ApplierPatchType = "json"
+)
+
+// ApplierPatch is a patch that the applier applies to the rendered objects that match the target,
+// before creating them. Patch files contain one or more of these as YAML documents, for example:
+//
+//	target:
+//	  kind: AgentClusterInstall
+//	  name: "*"
+//	type: merge
+//	patch:
+//	  metadata:
+//	    annotations:
+//	      example.com/my: value
+//
+// The patch can also be a string containing YAML or JSON text. The default type is strategic.
+type ApplierPatch struct {
+	Target ApplierPatchTarget `yaml:"target"`
+	Type   ApplierPatchType   `yaml:"type"`
+	Patch  any                `yaml:"patch"`
+
+	// source describes where the patch comes from, for use in messages.
+	source string
+}
+
+// ApplierPatchTarget selects the objects that a patch applies to. The kind is mandatory. The rest
+// of the fields are optional, and the namespace and name can be shell patterns like '*' or
+// 'my-*'.
+type ApplierPatchTarget struct {
+	Group     string `yaml:"group"`
+	Version   string `yaml:"version"`
+	Kind      string `yaml:"kind"`
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+}
+
+// loadPatches loads the patches contained in the given file.
+func (b *ApplierBuilder) loadPatches(file string) (results []*ApplierPatch, err error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	decoder := yaml.NewDecoder(reader)
+	for i := 0; ; i++ {
+		patch := &ApplierPatch{}
+		err = decoder.Decode(patch)
+		if errors.Is(err, io.EOF) {
+			err = nil
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to decode patch file '%s': %w", file, err)
+			return
+		}
+		patch.source = fmt.Sprintf("%d of file '%s'", i, file)
+		results = append(results, patch)
+	}
+	return
+}
+
+// checkPatch checks that the given patch is valid, and replaces the patch text with the
+// corresponding structure if needed.
+func (b *ApplierBuilder) checkPatch(patch *ApplierPatch) error {
+	if patch.Target.Kind == "" {
+		return fmt.Errorf("target kind of patch %s is mandatory", patch.source)
+	}
+	for _, pattern := range []string{patch.Target.Namespace, patch.Target.Name} {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf(
+				"target pattern '%s' of patch %s isn't valid: %w",
+				pattern, patch.source, err,
+			)
+		}
+	}
+	if patch.Type == "" {
+		patch.Type = ApplierStrategicMergePatch
+	}
+	switch patch.Type {
+	case ApplierStrategicMergePatch, ApplierMergePatch, ApplierJSONPatch:
+	default:
+		return fmt.Errorf(
+			"type '%s' of patch %s isn't valid, valid values are '%s', '%s' and '%s'",
+			patch.Type, patch.source, ApplierStrategicMergePatch, ApplierMergePatch,
+			ApplierJSONPatch,
+		)
+	}
+	if text, ok := patch.Patch.(string); ok {
+		var value any
+		err := yaml.Unmarshal([]byte(text), &value)
+		if err != nil {
+			return fmt.Errorf("failed to parse text of patch %s: %w", patch.source, err)
+		}
+		patch.Patch = value
+	}
+	if patch.Patch == nil {
+		return fmt.Errorf("content of patch %s is mandatory", patch.source)
+	}
+	if patch.Type == ApplierJSONPatch {
+		data, err := json.Marshal(patch.Patch)
+		if err != nil {
+			return err
+		}
+		_, err = jsonpatch.DecodePatch(data)
+		if err != nil {
+			return fmt.Errorf("JSON patch %s isn't valid: %w", patch.source, err)
+		}
+	}
+	return nil
+}
+
+// patchObjects applies the patches to the objects that match their targets.
+func (a *Applier) patchObjects(objects []*unstructured.Unstructured) error {
+	for _, patch := range a.patches {
+		matched := false
+		for _, object := range objects {
+			if !a.matchesPatch(object, patch) {
+				continue
+			}
+			err := a.applyPatch(object, patch)
+			if err != nil {
+				return fmt.Errorf(
+					"failed to apply patch %s to object '%s/%s' of kind '%s': %w",
+					patch.source, object.GetNamespace(), object.GetName(),
+					object.GetKind(), err,
+				)
+			}
+			a.logger.V(1).Info(
+				"Applied patch",
+				"patch", patch.source,
+				"kind", object.GetKind(),
+				"namespace", object.GetNamespace(),
+				"name", object.GetName(),
+			)
+			matched = true
+		}
+		if !matched {
+			a.logger.V(1).Info(
+				"Patch doesn't match any object",
+				"patch", patch.source,
+			)
+		}
+	}
+	return nil
+}
+
+func (a *Applier) matchesPatch(object *unstructured.Unstructured, patch *ApplierPatch) bool {
+	target := patch.Target
+	gvk := object.GroupVersionKind()
+	if gvk.Kind != target.Kind {
+		return false
+	}
+	if target.Group != "" && gvk.Group != target.Group {
+		return false
+	}
+	if target.Version != "" && gvk.Version != target.Version {
+		return false
+	}
+	if target.Namespace != "" {
+		matches, _ := path.Match(target.Namespace, object.GetNamespace())
+		if !matches {
+			return false
+		}
+	}
+	if target.Name != "" {
+		matches, _ := path.Match(target.Name, object.GetName())
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Applier) applyPatch(object *unstructured.Unstructured, patch *ApplierPatch) error {
+	original, err := json.Marshal(object.Object)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(patch.Patch)
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	switch patch.Type {
+	case ApplierStrategicMergePatch:
+		var schema runtime.Object
+		schema, err = scheme.Scheme.New(object.GroupVersionKind())
+		if runtime.IsNotRegisteredError(err) {
+			patched, err = jsonpatch.MergePatch(original, data)
+		} else if err == nil {
+			patched, err = strategicpatch.StrategicMergePatch(original, data, schema)
+		}
+	case ApplierMergePatch:
+		patched, err = jsonpatch.MergePatch(original, data)
+	case ApplierJSONPatch:
+		var decoded jsonpatch.Patch
+		decoded, err = jsonpatch.DecodePatch(data)
+		if err == nil {
+			patched, err = decoded.Apply(original)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Note that we use the YAML decoder here, instead of the JSON decoder, so that numbers are
+	// decoded in the same way than when the templates are rendered.
+	var result map[string]any
+	err = yaml.Unmarshal(patched, &result)
+	if err != nil {
+		return err
+	}
+	object.Object = result
+	return nil
+}
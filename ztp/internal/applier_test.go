@@ -27,12 +27,15 @@ import (
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/ginkgo/v2/dsl/decorators"
+	. "github.com/onsi/ginkgo/v2/dsl/table"
 	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
 	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
@@ -121,6 +124,35 @@ var _ = Describe("Applier", func() {
 			Expect(msg).To(ContainSubstring("mandatory"))
 			Expect(applier).To(BeNil())
 		})
+		It("Can't be created with an unknown apply mode", func() {
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetFS(fsys).
+				SetClient(client).
+				SetMode("junk").
+				Build()
+			Expect(err).To(HaveOccurred())
+			msg := err.Error()
+			Expect(msg).To(ContainSubstring("junk"))
+			Expect(msg).To(ContainSubstring("create"))
+			Expect(msg).To(ContainSubstring("server-side"))
+			Expect(applier).To(BeNil())
+		})
+
+		It("Can't be created with an unknown conflict policy", func() {
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetFS(fsys).
+				SetClient(client).
+				SetConflictPolicy("junk").
+				Build()
+			Expect(err).To(HaveOccurred())
+			msg := err.Error()
+			Expect(msg).To(ContainSubstring("junk"))
+			Expect(msg).To(ContainSubstring("fail"))
+			Expect(msg).To(ContainSubstring("force"))
+			Expect(applier).To(BeNil())
+		})
 	})
 
 	Describe("Usage", func() {
@@ -311,6 +343,180 @@ var _ = Describe("Applier", func() {
 			Expect(data).To(HaveKeyWithValue("my-ip", "192.168.122.1"))
 		})
 
+		It("Updates existing objects with server side apply", func() {
+			// Prepare the templates:
+			name := fmt.Sprintf("my-%s", uuid.NewString())
+			tmp, fsys := TmpFS(
+				"objects.yaml",
+				text.Dedent(`
+					apiVersion: v1
+					kind: Namespace
+					metadata:
+					  name: {{ .Name }}
+					---
+					apiVersion: v1
+					kind: ConfigMap
+					metadata:
+					  namespace: {{ .Name }}
+					  name: my-object
+					data:
+					  x: {{ .X }}
+					  y: "1"
+				`),
+			)
+			defer func() {
+				err := os.RemoveAll(tmp)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// Create the applier, with a listener that saves the events:
+			var events []*ApplierEvent
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetFS(fsys).
+				SetClient(client).
+				SetMode(ApplierModeServerSide).
+				SetListener(func(event *ApplierEvent) {
+					events = append(events, event)
+				}).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err := applier.Delete(ctx, map[string]any{
+					"Name": name,
+					"X":    "0",
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// The first time the objects should be created:
+			err = applier.Apply(ctx, map[string]any{
+				"Name": name,
+				"X":    "1",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(ApplierObjectCreated))
+			Expect(events[1].Type).To(Equal(ApplierObjectCreated))
+
+			// The second time with the same data nothing should change:
+			events = nil
+			err = applier.Apply(ctx, map[string]any{
+				"Name": name,
+				"X":    "1",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(ApplierObjectUnchanged))
+			Expect(events[1].Type).To(Equal(ApplierObjectUnchanged))
+
+			// The third time with different data the config map should be updated:
+			events = nil
+			err = applier.Apply(ctx, map[string]any{
+				"Name": name,
+				"X":    "2",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(ApplierObjectUnchanged))
+			Expect(events[1].Type).To(Equal(ApplierObjectUpdated))
+			Expect(events[1].Changes).To(ConsistOf("data.x"))
+
+			// Check the new value:
+			object := &corev1.ConfigMap{}
+			key := clnt.ObjectKey{
+				Namespace: name,
+				Name:      "my-object",
+			}
+			err = client.Get(ctx, key, object)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object.Data).To(HaveKeyWithValue("x", "2"))
+		})
+
+		It("Fails on conflicts unless forced", func() {
+			// Prepare the templates:
+			name := fmt.Sprintf("my-%s", uuid.NewString())
+			data := map[string]any{
+				"Name": name,
+			}
+			tmp, fsys := TmpFS(
+				"objects.yaml",
+				text.Dedent(`
+					apiVersion: v1
+					kind: Namespace
+					metadata:
+					  name: {{ .Name }}
+					---
+					apiVersion: v1
+					kind: ConfigMap
+					metadata:
+					  namespace: {{ .Name }}
+					  name: my-object
+					data:
+					  x: "1"
+				`),
+			)
+			defer func() {
+				err := os.RemoveAll(tmp)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// Create the objects and then change the value using a different field
+			// manager:
+			applier, err := NewApplier().
+				SetLogger(logger).
+				SetFS(fsys).
+				SetClient(client).
+				SetMode(ApplierModeServerSide).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				err := applier.Delete(ctx, data)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			err = applier.Apply(ctx, data)
+			Expect(err).ToNot(HaveOccurred())
+			object := &corev1.ConfigMap{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: name,
+					Name:      "my-object",
+				},
+				Data: map[string]string{
+					"x": "2",
+				},
+			}
+			err = client.Patch(
+				ctx, object, clnt.Apply,
+				clnt.FieldOwner("other"), clnt.ForceOwnership,
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Applying again should fail because of the conflict:
+			err = applier.Apply(ctx, data)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsConflict(err)).To(BeTrue())
+
+			// Applying with the force policy should take the ownership back:
+			forced, err := NewApplier().
+				SetLogger(logger).
+				SetFS(fsys).
+				SetClient(client).
+				SetMode(ApplierModeServerSide).
+				SetConflictPolicy(ApplierConflictForce).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = forced.Apply(ctx, data)
+			Expect(err).ToNot(HaveOccurred())
+			key := clnt.ObjectKeyFromObject(object)
+			err = client.Get(ctx, key, object)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object.Data).To(HaveKeyWithValue("x", "1"))
+		})
+
 		It("Deletes namespace only when objects are gone", func() {
 			// Prepare a namespace with an object that has a finalizer, so the applier
 			// will have to wait till that finalizer is removed before removing the
@@ -574,3 +780,340 @@ var _ = Describe("Applier", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 })
+
+var _ = DescribeTable(
+	"Applier changes",
+	func(before, after string, expected []string) {
+		var beforeObject, afterObject map[string]any
+		err := yaml.Unmarshal([]byte(text.Dedent(before)), &beforeObject)
+		Expect(err).ToNot(HaveOccurred())
+		err = yaml.Unmarshal([]byte(text.Dedent(after)), &afterObject)
+		Expect(err).ToNot(HaveOccurred())
+		actual := applierChanges(beforeObject, afterObject)
+		if expected == nil {
+			Expect(actual).To(BeEmpty())
+		} else {
+			Expect(actual).To(Equal(expected))
+		}
+	},
+	Entry(
+		"Nothing changed",
+		`
+		metadata:
+		  name: my-object
+		  resourceVersion: "1"
+		data:
+		  x: "1"
+		`,
+		`
+		metadata:
+		  name: my-object
+		  resourceVersion: "1"
+		data:
+		  x: "1"
+		`,
+		nil,
+	),
+	Entry(
+		"Ignores resource version, generation, managed fields and status",
+		`
+		metadata:
+		  name: my-object
+		  resourceVersion: "1"
+		  generation: 1
+		  managedFields:
+		  - manager: ztp
+		status:
+		  ready: false
+		`,
+		`
+		metadata:
+		  name: my-object
+		  resourceVersion: "2"
+		  generation: 2
+		  managedFields:
+		  - manager: ztp
+		  - manager: other
+		status:
+		  ready: true
+		`,
+		nil,
+	),
+	Entry(
+		"Changed, added and removed fields",
+		`
+		spec:
+		  replicas: 1
+		  removed: true
+		`,
+		`
+		spec:
+		  replicas: 2
+		  added: true
+		`,
+		[]string{
+			"spec.added",
+			"spec.removed",
+			"spec.replicas",
+		},
+	),
+	Entry(
+		"Lists are compared as a whole",
+		`
+		spec:
+		  items:
+		  - a
+		  - b
+		`,
+		`
+		spec:
+		  items:
+		  - a
+		  - c
+		`,
+		[]string{
+			"spec.items",
+		},
+	),
+	Entry(
+		"Quotes fields that contain dots",
+		`
+		metadata:
+		  labels:
+		    example.com/x: "1"
+		`,
+		`
+		metadata:
+		  labels:
+		    example.com/x: "2"
+		`,
+		[]string{
+			`metadata.labels["example.com/x"]`,
+		},
+	),
+)
+
+var _ = Describe("Applier dry run", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+		fsys   fs.FS
+		events []*ApplierEvent
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Create a context:
+		ctx = context.Background()
+
+		// Create the logger:
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Create a fake client that already contains one of the objects:
+		client = fake.NewClientBuilder().
+			WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "my-ns",
+					Name:      "existing",
+				},
+				Data: map[string]string{
+					"x": "1",
+				},
+			}).
+			Build()
+
+		// Create the templates:
+		var tmp string
+		tmp, fsys = TmpFS(
+			"objects.yaml",
+			text.Dedent(`
+				apiVersion: v1
+				kind: Namespace
+				metadata:
+				  name: my-ns
+				---
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  namespace: my-ns
+				  name: existing
+				data:
+				  x: "2"
+				---
+				apiVersion: v1
+				kind: ConfigMap
+				metadata:
+				  namespace: my-ns
+				  name: new
+				data:
+				  z: "1"
+			`),
+		)
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		events = nil
+	})
+
+	// createApplier creates an applier in dry run mode that saves the events.
+	createApplier := func() *Applier {
+		applier, err := NewApplier().
+			SetLogger(logger).
+			SetFS(fsys).
+			SetClient(client).
+			SetDryRun(true).
+			SetListener(func(event *ApplierEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return applier
+	}
+
+	It("Shows the objects that would be created", func() {
+		applier := createApplier()
+		err := applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+
+		// The namespace doesn't exist, so the result should be the complete object:
+		Expect(events[0].Type).To(Equal(ApplierObjectDiff))
+		Expect(events[0].Object.GetName()).To(Equal("my-ns"))
+		Expect(events[0].Diff).To(ContainSubstring("--- /dev/null\n"))
+		Expect(events[0].Diff).To(ContainSubstring("+++ merged/Namespace/my-ns\n"))
+		Expect(events[0].Diff).To(ContainSubstring("+  name: my-ns\n"))
+
+		// The existing config map isn't changed in create mode:
+		Expect(events[1].Type).To(Equal(ApplierObjectExist))
+		Expect(events[1].Object.GetName()).To(Equal("existing"))
+
+		// The new config map should be complete:
+		Expect(events[2].Type).To(Equal(ApplierObjectDiff))
+		Expect(events[2].Object.GetName()).To(Equal("new"))
+		Expect(events[2].Diff).To(ContainSubstring("+++ merged/my-ns/ConfigMap/new\n"))
+		Expect(events[2].Diff).To(ContainSubstring("+  z: \"1\"\n"))
+
+		// Check that nothing was created:
+		object := &corev1.ConfigMap{}
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my-ns",
+			Name:      "new",
+		}, object)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Doesn't show the values of secrets", func() {
+		// Replace the client and the templates with ones that contain secrets:
+		client = fake.NewClientBuilder().
+			WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "my-ns",
+					Name:      "existing",
+				},
+				Data: map[string][]byte{
+					"same":    []byte("my-same-value"),
+					"changed": []byte("my-old-value"),
+					"removed": []byte("my-removed-value"),
+				},
+			}).
+			Build()
+		var tmp string
+		tmp, fsys = TmpFS(
+			"objects.yaml",
+			text.Dedent(`
+				apiVersion: v1
+				kind: Secret
+				metadata:
+				  namespace: my-ns
+				  name: existing
+				data:
+				  same: bXktc2FtZS12YWx1ZQ==
+				  changed: bXktbmV3LXZhbHVl
+				  added: bXktYWRkZWQtdmFsdWU=
+				---
+				apiVersion: v1
+				kind: Secret
+				metadata:
+				  namespace: my-ns
+				  name: new
+				stringData:
+				  password: my-password
+			`),
+		)
+		DeferCleanup(func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		// Check that the differences don't contain the values, neither in plain text nor
+		// encoded:
+		applier, err := NewApplier().
+			SetLogger(logger).
+			SetFS(fsys).
+			SetClient(client).
+			SetDryRun(true).
+			SetMode(ApplierModeServerSide).
+			SetListener(func(event *ApplierEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = applier.Apply(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		for _, event := range events {
+			Expect(event.Type).To(Equal(ApplierObjectDiff))
+			for _, value := range []string{
+				"my-same-value", "bXktc2FtZS12YWx1ZQ==",
+				"my-old-value", "bXktb2xkLXZhbHVl",
+				"my-new-value", "bXktbmV3LXZhbHVl",
+				"my-removed-value", "bXktcmVtb3ZlZC12YWx1ZQ==",
+				"my-added-value", "bXktYWRkZWQtdmFsdWU=",
+				"my-password",
+			} {
+				Expect(event.Diff).ToNot(ContainSubstring(value))
+			}
+		}
+
+		// Check that the changes are still visible:
+		Expect(events[0].Diff).To(ContainSubstring("-  changed: '*** (before)'\n"))
+		Expect(events[0].Diff).To(ContainSubstring("+  changed: '*** (after)'\n"))
+		Expect(events[0].Diff).To(ContainSubstring("+  added: '***'\n"))
+		Expect(events[0].Diff).To(ContainSubstring("-  removed: '***'\n"))
+		Expect(events[0].Diff).To(ContainSubstring("   same: '***'\n"))
+		Expect(events[1].Diff).To(ContainSubstring("+  password: '***'\n"))
+	})
+
+	It("Shows the objects that would be deleted", func() {
+		applier := createApplier()
+		err := applier.Delete(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+
+		// The config maps are deleted first, and only the existing one has differences:
+		Expect(events[0].Type).To(Equal(ApplierObjectNotExist))
+		Expect(events[0].Object.GetName()).To(Equal("new"))
+		Expect(events[1].Type).To(Equal(ApplierObjectDiff))
+		Expect(events[1].Object.GetName()).To(Equal("existing"))
+		Expect(events[1].Diff).To(ContainSubstring("--- live/my-ns/ConfigMap/existing\n"))
+		Expect(events[1].Diff).To(ContainSubstring("+++ /dev/null\n"))
+		Expect(events[1].Diff).To(ContainSubstring("-  x: \"1\"\n"))
+		Expect(events[2].Type).To(Equal(ApplierObjectNotExist))
+		Expect(events[2].Object.GetName()).To(Equal("my-ns"))
+
+		// Check that nothing was deleted:
+		object := &corev1.ConfigMap{}
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my-ns",
+			Name:      "existing",
+		}, object)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
@@ -31,7 +31,6 @@ import (
 	"golang.org/x/crypto/ssh"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	apiwatch "k8s.io/apimachinery/pkg/watch"
@@ -442,56 +441,6 @@ func (c *Client) Watch(ctx context.Context, list clnt.ObjectList,
 	return
 }
 
-// DeleteCRDGroup deletes all the custom resource definitions in the given group. Returns the number
-// of CRDs actually deleted.
-func (c *Client) DeleteCRDGroup(ctx context.Context, group string) (n int, err error) {
-	all := &unstructured.UnstructuredList{}
-	all.SetGroupVersionKind(CustomResourceDefinitionListGVK)
-	err = c.delegate.List(ctx, all)
-	if err != nil {
-		return
-	}
-	var matching []*unstructured.Unstructured
-	for _, item := range all.Items {
-		var (
-			key string
-			ok  bool
-		)
-		key, ok, err = unstructured.NestedString(item.Object, "spec", "group")
-		if err != nil {
-			return
-		}
-		if !ok || key != group {
-			continue
-		}
-		crd := &unstructured.Unstructured{}
-		crd.SetGroupVersionKind(item.GroupVersionKind())
-		crd.SetName(item.GetName())
-		matching = append(matching, crd)
-	}
-	c.logger.V(1).Info(
-		"CRDs to delete",
-		"group", group,
-		"count", len(matching),
-	)
-	if len(matching) == 0 {
-		return
-	}
-	for _, crd := range matching {
-		err = c.Delete(ctx, crd)
-		if err != nil {
-			return
-		}
-		n++
-		c.logger.V(1).Info(
-			"Deleted CRD",
-			"group", group,
-			"name", crd.GetName(),
-		)
-	}
-	return
-}
-
 // AddLabel adds a label with the given name and value to an object.
 func (c *Client) AddLabel(ctx context.Context, object clnt.Object, name, value string) error {
 	return c.AddLabels(ctx, object, map[string]string{name: value})
@@ -16,7 +16,38 @@ package cluster
 
 import (
 	"embed"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
 )
 
 //go:embed templates
 var templatesFS embed.FS
+
+// enricherSteps are the enricher steps that calculate the information used by the templates. Note
+// that the steps that need the cluster to exist, like the ones that find the external API and
+// ingress addresses, aren't included because the cluster doesn't exist yet when it is created.
+var enricherSteps = []string{
+	internal.EnricherStepArchitecture,
+	internal.EnricherStepSNO,
+	internal.EnricherStepPullSecret,
+	internal.EnricherStepSSHKeys,
+	internal.EnricherStepDNSDomain,
+	internal.EnricherStepInternalAPIIP,
+	internal.EnricherStepInternalIngressIP,
+	internal.EnricherStepInternalNodeIPs,
+	internal.EnricherStepCheckNetworks,
+	internal.EnricherStepClusterImageSet,
+	internal.EnricherStepHostnames,
+}
+
+// deleteEnricherSteps are the enricher steps that calculate the information needed to render the
+// templates when deleting clusters. Only the things that are part of the identity of the objects,
+// or that are needed for the templates to render at all, are included. In particular the internal
+// node IP addresses aren't included because that would save the allocations again, while the
+// delete command releases them. The cluster image set isn't included either, so that image sets
+// shared with other clusters aren't deleted.
+var deleteEnricherSteps = []string{
+	internal.EnricherStepSNO,
+	internal.EnricherStepSecrets,
+	internal.EnricherStepHostnames,
+}
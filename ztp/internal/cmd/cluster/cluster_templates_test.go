@@ -0,0 +1,203 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/annotations"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+var _ = Describe("Cluster templates", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// render renders the cluster templates for the given cluster.
+	render := func(cluster *models.Cluster) []*unstructured.Unstructured {
+		logger, err := logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		applier, err := internal.NewApplier().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build()).
+			SetFS(templatesFS).
+			SetRoot("templates").
+			SetDir("objects").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		objects, err := applier.Render(ctx, map[string]any{
+			"Cluster": cluster,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return objects
+	}
+
+	// edges returns the dependencies declared with the 'ztpfw/depends-on' annotation, as
+	// strings like 'InfraEnv/my -> ClusterDeployment/my'. It checks that all the dependencies
+	// are objects generated by the templates.
+	edges := func(objects []*unstructured.Unstructured) []string {
+		index := map[string]bool{}
+		for _, object := range objects {
+			key := fmt.Sprintf(
+				"%s/%s/%s",
+				object.GetKind(), object.GetNamespace(), object.GetName(),
+			)
+			index[key] = true
+		}
+		var results []string
+		for _, object := range objects {
+			value, ok := object.GetAnnotations()[annotations.DependsOn]
+			if !ok {
+				continue
+			}
+			for _, ref := range strings.Split(value, ",") {
+				ref = strings.TrimSpace(ref)
+				parts := strings.Split(ref, "/")
+				Expect(parts).To(HaveLen(2))
+				namespaced := fmt.Sprintf(
+					"%s/%s/%s",
+					parts[0], object.GetNamespace(), parts[1],
+				)
+				global := fmt.Sprintf("%s//%s", parts[0], parts[1])
+				Expect(index[namespaced] || index[global]).To(
+					BeTrue(),
+					"Object '%s/%s' depends on '%s', but it isn't generated",
+					object.GetKind(), object.GetName(), ref,
+				)
+				results = append(results, fmt.Sprintf(
+					"%s/%s -> %s",
+					object.GetKind(), object.GetName(), ref,
+				))
+			}
+		}
+		return results
+	}
+
+	// makeCluster creates a cluster with the information that the enricher would add.
+	makeCluster := func(releaseImage string) *models.Cluster {
+		cluster := &models.Cluster{
+			Name:         "my",
+			Architecture: models.ArchitectureX86_64,
+			ImageSet:     "my-image-set",
+			ReleaseImage: releaseImage,
+			PullSecret:   []byte("{}"),
+			API: models.API{
+				InternalIP: net.ParseIP("192.168.7.242"),
+			},
+			Ingress: models.Ingress{
+				InternalIP: net.ParseIP("192.168.7.243"),
+			},
+			DNS: models.DNS{
+				Domain: "example.com",
+			},
+			SSH: models.SSH{
+				PublicKey: []byte("ssh-rsa AAAA"),
+			},
+			ClusterNetworks: []*models.ClusterNetwork{{
+				CIDR: &net.IPNet{
+					IP:   net.ParseIP("10.128.0.0"),
+					Mask: net.CIDRMask(14, 32),
+				},
+				HostPrefix: 23,
+			}},
+			MachineNetworks: []*models.MachineNetwork{{
+				CIDR: &net.IPNet{
+					IP:   net.ParseIP("192.168.7.0"),
+					Mask: net.CIDRMask(24, 32),
+				},
+			}},
+			ServiceNetworks: []*models.ServiceNetwork{{
+				CIDR: &net.IPNet{
+					IP:   net.ParseIP("172.30.0.0"),
+					Mask: net.CIDRMask(16, 32),
+				},
+			}},
+		}
+		for i := 0; i < 3; i++ {
+			cluster.Nodes = append(cluster.Nodes, &models.Node{
+				Kind:     models.NodeKindControlPlane,
+				Name:     fmt.Sprintf("master%d", i),
+				Hostname: fmt.Sprintf("ztpfw-my-master-%d", i),
+				RootDisk: "/dev/sda",
+				BMC: models.BMC{
+					URL:  fmt.Sprintf("redfish-virtualmedia+http://bmc/%d", i),
+					User: models.NewSecret("user"),
+					Pass: models.NewSecret("pass"),
+				},
+				InternalNIC: &models.NIC{
+					Name: "eth1",
+					MAC:  fmt.Sprintf("00:00:00:00:01:0%d", i),
+				},
+				InternalIPs: []*models.IP{{
+					Address: net.ParseIP(fmt.Sprintf("192.168.7.1%d", i)),
+					Prefix:  24,
+				}},
+				ExternalNIC: &models.NIC{
+					Name: "eth0",
+					MAC:  fmt.Sprintf("00:00:00:00:00:0%d", i),
+				},
+			})
+		}
+		return cluster
+	}
+
+	It("Declares the dependencies between the cluster objects", func() {
+		objects := render(makeCluster("quay.io/openshift-release-dev/ocp-release:4.12.0"))
+		Expect(edges(objects)).To(ConsistOf(
+			"AgentClusterInstall/my -> ConfigMap/my-manifests-override",
+			"AgentClusterInstall/my -> ClusterImageSet/my-image-set",
+			"ClusterDeployment/my -> AgentClusterInstall/my",
+			"ClusterDeployment/my -> Secret/pull-secret-edgecluster-cluster",
+			"ManagedCluster/my -> ClusterDeployment/my",
+			"InfraEnv/my -> ClusterDeployment/my",
+			"InfraEnv/my -> Secret/pull-secret-edgecluster-cluster",
+			"InfraEnv/my -> NMStateConfig/ztpfw-my-master-0",
+			"InfraEnv/my -> NMStateConfig/ztpfw-my-master-1",
+			"InfraEnv/my -> NMStateConfig/ztpfw-my-master-2",
+			"BareMetalHost/ztpfw-my-master-0 -> Secret/ztpfw-my-master-0-bmc-secret",
+			"BareMetalHost/ztpfw-my-master-0 -> InfraEnv/my",
+			"BareMetalHost/ztpfw-my-master-1 -> Secret/ztpfw-my-master-1-bmc-secret",
+			"BareMetalHost/ztpfw-my-master-1 -> InfraEnv/my",
+			"BareMetalHost/ztpfw-my-master-2 -> Secret/ztpfw-my-master-2-bmc-secret",
+			"BareMetalHost/ztpfw-my-master-2 -> InfraEnv/my",
+		))
+	})
+
+	It("Doesn't depend on the image set if it isn't generated", func() {
+		objects := render(makeCluster(""))
+		Expect(edges(objects)).To(ContainElement(
+			"AgentClusterInstall/my -> ConfigMap/my-manifests-override",
+		))
+		Expect(edges(objects)).ToNot(ContainElement(
+			"AgentClusterInstall/my -> ClusterImageSet/my-image-set",
+		))
+	})
+})
@@ -16,6 +16,8 @@ package cluster
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -24,7 +26,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	apiwatch "k8s.io/apimachinery/pkg/watch"
 	clnt "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
@@ -48,8 +49,9 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	_ = flags.StringP(
-		outputFlagName,
+		outputDirFlagName,
 		"o",
 		"",
 		"Base directory for output files, including the generated SSH keys. If not specified then "+
@@ -66,13 +68,15 @@ func Create() *cobra.Command {
 
 // CreateCommand contains the data and logic needed to run the `create cluster` command.
 type CreateCommand struct {
-	logger  logr.Logger
-	flags   *pflag.FlagSet
-	jq      *jq.Tool
-	console *internal.Console
-	config  *models.Config
-	client  *internal.Client
-	applier *internal.Applier
+	logger   logr.Logger
+	flags    *pflag.FlagSet
+	jq       *jq.Tool
+	console  *internal.Console
+	config   *models.Config
+	client   *internal.Client
+	waiter   *internal.Waiter
+	listener *internal.ApplierListener
+	ipam     *internal.IPAM
 }
 
 // NewCreateCommand creates a new runner that knows how to execute the `create cluster` command.
@@ -133,11 +137,26 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 	}
 	defer c.client.Close()
 
+	// Create the listener for the events of the enricher:
+	c.listener, err = internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(cmd.Flags()).
+		SetSteps(enricherSteps...).
+		SetListener(c.listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -155,30 +174,15 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
-	// Create the applier:
-	listener, err := internal.NewApplierListener().
+	// Create the IPAM:
+	c.ipam, err = internal.NewIPAM().
 		SetLogger(c.logger).
-		SetConsole(c.console).
-		Build()
-	if err != nil {
-		c.console.Error(
-			"Failed to create listener: %v",
-			err,
-		)
-		return exit.Error(1)
-	}
-	c.applier, err = internal.NewApplier().
-		SetLogger(c.logger).
-		SetListener(listener.Func).
 		SetClient(c.client).
-		SetFS(templatesFS).
-		SetRoot("templates").
-		SetDir("objects").
-		AddLabel(labels.ZTPFW, "").
+		SetDryRun(internal.DryRunFlag(c.flags)).
 		Build()
 	if err != nil {
 		c.console.Error(
-			"Failed to create applier: %v",
+			"Failed to create IPAM: %v",
 			err,
 		)
 		return exit.Error(1)
@@ -196,12 +200,18 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		}
 	}
 
+	// In dry run mode the clusters haven't been created, so there are no output files to write
+	// and nothing to wait for:
+	if internal.DryRunFlag(c.flags) {
+		return nil
+	}
+
 	// Write the output files:
-	output, err := c.flags.GetString(outputFlagName)
+	output, err := c.flags.GetString(outputDirFlagName)
 	if err != nil {
 		c.console.Error(
 			"Failed to get value of flag '--%s': %v",
-			outputFlagName, err,
+			outputDirFlagName, err,
 		)
 		return exit.Error(1)
 	}
@@ -237,11 +247,13 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		defer cancel()
 		for _, cluster := range c.config.Clusters {
 			err = c.wait(ctx, cluster)
-			if os.IsTimeout(err) {
+			var waitErr *internal.WaitError
+			if errors.As(err, &waitErr) {
 				c.console.Error(
-					"Clusters aren't ready after waiting for %s",
-					wait,
+					"Cluster '%s' isn't ready: %v",
+					cluster.Name, err,
 				)
+				c.console.Print(waitErr.Report())
 				return exit.Error(1)
 			}
 			if err != nil {
@@ -253,12 +265,74 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 }
 
 func (c *CreateCommand) deploy(ctx context.Context, cluster *models.Cluster) error {
-	return c.applier.Apply(ctx, map[string]any{
+	applier, err := c.createApplier(cluster)
+	if err != nil {
+		return err
+	}
+	err = applier.Apply(ctx, map[string]any{
 		"Cluster": cluster,
 	})
+	if err != nil {
+		return err
+	}
+
+	// The enricher can't save the internal IP addresses of the nodes the first time because
+	// the namespace of the cluster doesn't exist yet, so we save them now that it does:
+	return c.ipam.Save(ctx, cluster)
+}
+
+// createApplier creates the applier for the given cluster. Each cluster has its own inventory, so
+// that objects that are no longer generated for one cluster can be pruned without affecting the
+// others.
+func (c *CreateCommand) createApplier(cluster *models.Cluster) (result *internal.Applier,
+	err error) {
+	listener, err := c.createListener(cluster)
+	if err != nil {
+		return
+	}
+	result, err = internal.NewApplier().
+		SetLogger(c.logger).
+		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(c.flags).
+		SetClient(c.client).
+		SetFS(templatesFS).
+		SetRoot("templates").
+		SetDir("objects").
+		SetInventory(fmt.Sprintf("cluster-%s", cluster.Name)).
+		AddLabel(labels.ZTPFW, "").
+		Build()
+	return
+}
+
+// createListener creates the listener that reports the events related to the given cluster.
+func (c *CreateCommand) createListener(cluster *models.Cluster) (result *internal.ApplierListener,
+	err error) {
+	result, err = internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		SetCluster(cluster.Name).
+		Build()
+	return
 }
 
 func (c *CreateCommand) wait(ctx context.Context, cluster *models.Cluster) error {
+	// Create the waiter, reporting the wait transitions as events of the cluster:
+	listener, err := c.createListener(cluster)
+	if err != nil {
+		return err
+	}
+	c.waiter, err = internal.NewWaiter().
+		SetLogger(c.logger).
+		SetFlags(c.flags).
+		SetClient(c.client).
+		SetListener(listener.WaiterFunc).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	// Run the wait tasks:
 	waitTasks := []func(context.Context, *models.Cluster) error{
 		c.waitHosts,
 		c.waitInstall,
@@ -278,55 +352,55 @@ func (c *CreateCommand) waitHosts(ctx context.Context, cluster *models.Cluster)
 		cluster.Name,
 	)
 
-	// First retrieve the list of hosts in the namespace of the cluster and construct a set with
-	// the names of the hosts that are not yet provisioned.
+	// First retrieve the list of hosts in the namespace of the cluster:
 	list := &unstructured.UnstructuredList{}
 	list.SetGroupVersionKind(internal.BareMetalHostListGVK)
 	err := c.client.List(ctx, list, clnt.InNamespace(cluster.Name))
 	if err != nil {
 		return err
 	}
-	pending := map[string]bool{}
-	for _, item := range list.Items {
-		pending[item.GetName()] = true
+	hosts := make([]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		hosts[i] = &list.Items[i]
 	}
 
-	// Now watch for changes in the hosts, remove them from the pending set when the status is
-	// `provisioned` and stop when the pending set is empty.
-	watch, err := c.client.Watch(ctx, list, clnt.InNamespace(cluster.Name))
-	if err != nil {
-		return err
-	}
-	defer watch.Stop()
-	for event := range watch.ResultChan() {
-		if event.Type == apiwatch.Bookmark {
-			continue
-		}
-		object, ok := event.Object.(*unstructured.Unstructured)
-		if !ok {
-			continue
-		}
-		var state string
-		err = c.jq.Query(
-			`try .status.provisioning.state`,
-			object.Object, &state,
-		)
-		if err != nil {
-			return err
-		}
-		if state == "provisioned" {
-			name := object.GetName()
-			c.console.Info(
-				"Host '%s' of cluster '%s' is provisioned",
-				name, cluster.Name,
-			)
-			delete(pending, name)
-			if len(pending) == 0 {
-				break
+	// Now wait till all the hosts are provisioned, reporting each of them only once:
+	provisioned := map[string]bool{}
+	return c.waiter.Wait(ctx, &internal.WaitStage{
+		Name:    fmt.Sprintf("hosts of cluster '%s' to be provisioned", cluster.Name),
+		Objects: hosts,
+		Check: func(ctx context.Context, current []*unstructured.Unstructured) (done bool,
+			err error) {
+			done = true
+			for _, host := range current {
+				if host == nil {
+					done = false
+					continue
+				}
+				var state string
+				err = c.jq.Query(
+					`try .status.provisioning.state`,
+					host.Object, &state,
+				)
+				if err != nil {
+					return
+				}
+				if state != "provisioned" {
+					done = false
+					continue
+				}
+				name := host.GetName()
+				if !provisioned[name] {
+					c.console.Info(
+						"Host '%s' of cluster '%s' is provisioned",
+						name, cluster.Name,
+					)
+					provisioned[name] = true
+				}
 			}
-		}
-	}
-	return nil
+			return
+		},
+	})
 }
 
 func (c *CreateCommand) waitInstall(ctx context.Context, cluster *models.Cluster) error {
@@ -335,91 +409,85 @@ func (c *CreateCommand) waitInstall(ctx context.Context, cluster *models.Cluster
 		cluster.Name,
 	)
 
-	// Watch the agent cluster install till the status is `ready`:
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(internal.AgentClusterInstallGVK)
-	watch, err := c.client.Watch(
-		ctx,
-		list,
-		clnt.InNamespace(cluster.Name),
-		clnt.MatchingFields{
-			"metadata.name": cluster.Name,
-		},
-	)
-	if err != nil {
-		return err
-	}
-	defer watch.Stop()
+	// Wait till the agent cluster install is completed, reporting the changes of state:
+	install := &unstructured.Unstructured{}
+	install.SetGroupVersionKind(internal.AgentClusterInstallGVK)
+	install.SetNamespace(cluster.Name)
+	install.SetName(cluster.Name)
 	previousState := ""
-	for event := range watch.ResultChan() {
-		if event.Type == apiwatch.Bookmark {
-			continue
-		}
-		object, ok := event.Object.(*unstructured.Unstructured)
-		if !ok {
-			continue
-		}
+	return c.waiter.Wait(ctx, &internal.WaitStage{
+		Name:    fmt.Sprintf("installation of cluster '%s' to be completed", cluster.Name),
+		Objects: []*unstructured.Unstructured{install},
+		Check: func(ctx context.Context, current []*unstructured.Unstructured) (done bool,
+			err error) {
+			object := current[0]
+			if object == nil {
+				return
+			}
 
-		// Check the status:
-		var currentState string
-		err = c.jq.Query(
-			`.status.debugInfo.state`,
-			object, &currentState,
-		)
-		if err != nil {
-			return err
-		}
-		if currentState != previousState {
-			c.console.Info(
-				"Cluster '%s' moved to state '%s'",
-				cluster.Name, currentState,
+			// Check the status:
+			var currentState string
+			err = c.jq.Query(
+				`.status.debugInfo.state`,
+				object, &currentState,
 			)
-		}
-		if currentState == "error" {
-			c.console.Error(
-				"Installation of cluster '%s' failed because it moved to "+
-					"the '%s' state",
-				cluster.Name, currentState,
-			)
-			return exit.Error(1)
-		}
-		previousState = currentState
+			if err != nil {
+				return
+			}
+			if currentState != previousState {
+				c.console.Info(
+					"Cluster '%s' moved to state '%s'",
+					cluster.Name, currentState,
+				)
+			}
+			if currentState == "error" {
+				c.console.Error(
+					"Installation of cluster '%s' failed because it moved to "+
+						"the '%s' state",
+					cluster.Name, currentState,
+				)
+				err = exit.Error(1)
+				return
+			}
+			previousState = currentState
 
-		// Check if the installation has completed:
-		var completed string
-		err = c.jq.Query(
-			`.status.conditions[]? | select(.type == "Completed") | .status`,
-			object, &completed,
-		)
-		if err != nil {
-			return err
-		}
-		if completed == "True" {
-			c.console.Info(
-				"Installation of cluster '%s' succeeded",
-				cluster.Name,
+			// Check if the installation has completed:
+			var completed string
+			err = c.jq.Query(
+				`.status.conditions[]? | select(.type == "Completed") | .status`,
+				object, &completed,
 			)
-			break
-		}
+			if err != nil {
+				return
+			}
+			if completed == "True" {
+				c.console.Info(
+					"Installation of cluster '%s' succeeded",
+					cluster.Name,
+				)
+				done = true
+				return
+			}
 
-		// Check if the installation has failed:
-		var failed string
-		err = c.jq.Query(
-			`.status.conditions[]? | select(.type == "Failed") | .status`,
-			object, &failed,
-		)
-		if err != nil {
-			return err
-		}
-		if failed == "True" {
-			c.console.Error(
-				"Installation of cluster '%s' failed",
-				cluster.Name,
+			// Check if the installation has failed:
+			var failed string
+			err = c.jq.Query(
+				`.status.conditions[]? | select(.type == "Failed") | .status`,
+				object, &failed,
 			)
-			return exit.Error(1)
-		}
-	}
-	return ctx.Err()
+			if err != nil {
+				return
+			}
+			if failed == "True" {
+				c.console.Error(
+					"Installation of cluster '%s' failed",
+					cluster.Name,
+				)
+				err = exit.Error(1)
+			}
+			return
+		},
+	})
 }
 
 func (c *CreateCommand) writeOutput(ctx context.Context, cluster *models.Cluster, output string) error {
@@ -455,6 +523,6 @@ func (c *CreateCommand) writeOutput(ctx context.Context, cluster *models.Cluster
 
 // Names of the command line flags:
 const (
-	outputFlagName = "output"
-	waitFlagName   = "wait"
+	outputDirFlagName = "output-dir"
+	waitFlagName      = "wait"
 )
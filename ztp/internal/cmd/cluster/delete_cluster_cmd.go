@@ -16,6 +16,7 @@ package cluster
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
@@ -42,17 +43,19 @@ func Delete() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddDryRunFlag(flags)
 	return result
 }
 
 // DeleteCommand contains the data and logic needed to run the `delete cluster` command.
 type DeleteCommand struct {
-	logger  logr.Logger
-	flags   *pflag.FlagSet
-	console *internal.Console
-	config  *models.Config
-	client  *internal.Client
-	applier *internal.Applier
+	logger   logr.Logger
+	flags    *pflag.FlagSet
+	console  *internal.Console
+	config   *models.Config
+	client   *internal.Client
+	listener *internal.ApplierListener
+	ipam     *internal.IPAM
 }
 
 // NewDeleteCommand creates a new runner that knows how to execute the `delete cluster` command.
@@ -101,11 +104,26 @@ func (c *DeleteCommand) Run(cmd *cobra.Command, argv []string) error {
 	}
 	defer c.client.Close()
 
+	// Create the listener for the events of the enricher:
+	c.listener, err = internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create applier listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(cmd.Flags()).
+		SetSteps(deleteEnricherSteps...).
+		SetListener(c.listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -123,30 +141,15 @@ func (c *DeleteCommand) Run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
-	// Create the applier:
-	listener, err := internal.NewApplierListener().
+	// Create the IPAM:
+	c.ipam, err = internal.NewIPAM().
 		SetLogger(c.logger).
-		SetConsole(c.console).
-		Build()
-	if err != nil {
-		c.console.Error(
-			"Failed to create applier listener: %v",
-			err,
-		)
-		return exit.Error(1)
-	}
-	c.applier, err = internal.NewApplier().
-		SetLogger(c.logger).
-		SetListener(listener.Func).
 		SetClient(c.client).
-		SetFS(templatesFS).
-		SetRoot("templates").
-		SetDir("objects").
-		AddLabel(labels.ZTPFW, "").
+		SetDryRun(internal.DryRunFlag(c.flags)).
 		Build()
 	if err != nil {
 		c.console.Error(
-			"Failed to create applier: %v",
+			"Failed to create IPAM: %v",
 			err,
 		)
 		return exit.Error(1)
@@ -168,13 +171,19 @@ func (c *DeleteCommand) Run(cmd *cobra.Command, argv []string) error {
 }
 
 func (c *DeleteCommand) delete(ctx context.Context, cluster *models.Cluster) error {
+	// Create the applier:
+	applier, err := c.createApplier(cluster)
+	if err != nil {
+		return err
+	}
+
 	// The cluster deployment can't be deleted directly because Hive will then delete the
 	// namespace, and with the namespace terminating it isn't possible to delete other objects
 	// that create things as part of the deletion process. In particular the process to delete
 	// bare metal hosts needs to create `preprovisioningimages` inside the namespace. To address
 	// that remove the cluster deployment from the list of objects to delete, and let Kubernetes
 	// delete it when the namespace is deleted.
-	objects, err := c.applier.Render(ctx, map[string]any{
+	objects, err := applier.Render(ctx, map[string]any{
 		"Cluster": cluster,
 	})
 	if err != nil {
@@ -187,5 +196,39 @@ func (c *DeleteCommand) delete(ctx context.Context, cluster *models.Cluster) err
 		}
 		deleteable = append(deleteable, object)
 	}
-	return c.applier.DeleteObjects(ctx, deleteable)
+	err = applier.DeleteObjects(ctx, deleteable)
+	if err != nil {
+		return err
+	}
+
+	// Release the internal IP addresses of the nodes, in case the namespace isn't deleted:
+	return c.ipam.Release(ctx, cluster)
+}
+
+// createApplier creates the applier for the given cluster. Each cluster has its own inventory, so
+// that objects that are no longer generated for one cluster can be pruned without affecting the
+// others.
+func (c *DeleteCommand) createApplier(cluster *models.Cluster) (result *internal.Applier,
+	err error) {
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		SetCluster(cluster.Name).
+		Build()
+	if err != nil {
+		return
+	}
+	result, err = internal.NewApplier().
+		SetLogger(c.logger).
+		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(c.flags).
+		SetClient(c.client).
+		SetFS(templatesFS).
+		SetRoot("templates").
+		SetDir("objects").
+		SetInventory(fmt.Sprintf("cluster-%s", cluster.Name)).
+		AddLabel(labels.ZTPFW, "").
+		Build()
+	return
 }
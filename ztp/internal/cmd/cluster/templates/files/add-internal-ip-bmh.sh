@@ -1,7 +1,13 @@
 #!/usr/bin/bash
 
 # This script will only be present in nodes that don't have an internal NIC. For those nodes we need
-# to add the internal IP to the external `br-ex` bridge used by OVS:
-internal_ip="{{ .InternalIP }}"
+# to add the internal IPs to the external `br-ex` bridge used by OVS:
+{{ range .InternalIPs -}}
+internal_ip="{{ . }}"
+{{ if .IsIPv4 -}}
 nmcli connection modify br-ex +ipv4.addresses "${internal_ip}" ipv4.method auto
+{{ else -}}
+nmcli connection modify br-ex +ipv6.addresses "${internal_ip}" ipv6.method auto
+{{ end -}}
 ip addr add "${internal_ip}" dev br-ex
+{{ end -}}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/config"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/exit"
+)
+
+// Schema creates and returns the `config schema` command.
+func Schema() *cobra.Command {
+	c := NewSchemaCommand()
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Prints the JSON schema of the configuration",
+		Long: "Prints the JSON schema that describes the configuration format. It can be " +
+			"used by editors and other tools to complete and validate configuration " +
+			"files. Note that some checks, like the syntax of secret references or the " +
+			"number of control plane nodes, can only be done with the 'validate config' " +
+			"command.",
+		Args: cobra.NoArgs,
+		RunE: c.run,
+	}
+}
+
+// SchemaCommand contains the data and logic needed to run the `config schema` command.
+type SchemaCommand struct {
+}
+
+// NewSchemaCommand creates a new runner that knows how to execute the `config schema` command.
+func NewSchemaCommand() *SchemaCommand {
+	return &SchemaCommand{}
+}
+
+// run runs the `config schema` command.
+func (c *SchemaCommand) run(cmd *cobra.Command, argv []string) error {
+	// Get the context:
+	ctx := cmd.Context()
+
+	// Get the dependencies from the context:
+	tool := internal.ToolFromContext(ctx)
+	console := internal.ConsoleFromContext(ctx)
+
+	// Generate the schema and write it directly to the output, without the prefixes that the
+	// console adds, so that it can be redirected to a file:
+	data, err := json.MarshalIndent(config.JSONSchema(), "", "  ")
+	if err != nil {
+		console.Error(
+			"Failed to generate schema: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+	_, err = fmt.Fprintf(tool.Out(), "%s\n", data)
+	return err
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/config"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/exit"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// Validate creates and returns the `validate config` command.
+func Validate() *cobra.Command {
+	c := NewValidateCommand()
+	result := &cobra.Command{
+		Use:   "config",
+		Short: "Validates the configuration",
+		Long: "Validates the configuration without creating anything. By default only " +
+			"the configuration itself is checked. Use the '--online' flag to also " +
+			"check that the hub cluster contains the objects that will be needed " +
+			"to create the clusters.",
+		Args: cobra.NoArgs,
+		RunE: c.run,
+	}
+	flags := result.Flags()
+	config.AddFlags(flags)
+	_ = flags.Bool(
+		onlineFlagName,
+		false,
+		"Enables or disables the checks that require access to the hub cluster, like "+
+			"the existence of the pull secret or the cluster image set.",
+	)
+	return result
+}
+
+// ValidateCommand contains the data and logic needed to run the `validate config` command.
+type ValidateCommand struct {
+	logger   logr.Logger
+	flags    *pflag.FlagSet
+	console  *internal.Console
+	config   *models.Config
+	problems []*config.Problem
+}
+
+// NewValidateCommand creates a new runner that knows how to execute the `validate config` command.
+func NewValidateCommand() *ValidateCommand {
+	return &ValidateCommand{}
+}
+
+// run runs the `validate config` command.
+func (c *ValidateCommand) run(cmd *cobra.Command, argv []string) error {
+	var err error
+
+	// Get the context:
+	ctx := cmd.Context()
+
+	// Get the dependencies from the context:
+	c.logger = internal.LoggerFromContext(ctx)
+	c.console = internal.ConsoleFromContext(ctx)
+
+	// Save the flags:
+	c.flags = cmd.Flags()
+	online, err := c.flags.GetBool(onlineFlagName)
+	if err != nil {
+		return err
+	}
+
+	// Load the configuration. If it doesn't conform to the schema there is no point in running
+	// the rest of the checks, so we report the problems and stop.
+	c.config, err = config.NewLoader().
+		SetLogger(c.logger).
+		SetFlags(c.flags).
+		Load()
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		c.problems = validationErr.Problems
+		return c.report()
+	}
+	if err != nil {
+		c.console.Error(
+			"Failed to load configuration: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
+	// Run the checks that don't need the hub:
+	checker, err := config.NewChecker().
+		SetLogger(c.logger).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create configuration checker: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+	c.problems = append(c.problems, checker.Check(c.config)...)
+
+	// Run the checks that need the hub:
+	if online {
+		client, err := internal.NewClient().
+			SetLogger(c.logger).
+			SetFlags(c.flags).
+			Build()
+		if err != nil {
+			c.console.Error(
+				"Failed to create API client: %v",
+				err,
+			)
+			return exit.Error(1)
+		}
+		defer client.Close()
+		hubChecker, err := internal.NewHubChecker().
+			SetLogger(c.logger).
+			SetClient(client).
+			Build()
+		if err != nil {
+			c.console.Error(
+				"Failed to create hub checker: %v",
+				err,
+			)
+			return exit.Error(1)
+		}
+		problems, err := hubChecker.Check(ctx, c.config)
+		if err != nil {
+			c.console.Error(
+				"Failed to check hub: %v",
+				err,
+			)
+			return exit.Error(1)
+		}
+		c.problems = append(c.problems, problems...)
+	}
+
+	return c.report()
+}
+
+func (c *ValidateCommand) report() error {
+	if len(c.problems) == 0 {
+		c.console.Info("Configuration is valid")
+		return nil
+	}
+	for _, problem := range c.problems {
+		c.console.Error("%s", problem)
+	}
+	if len(c.problems) == 1 {
+		c.console.Error("Configuration isn't valid, found 1 problem")
+	} else {
+		c.console.Error(
+			"Configuration isn't valid, found %d problems",
+			len(c.problems),
+		)
+	}
+	return exit.Error(1)
+}
+
+// Names of command line flags:
+const (
+	onlineFlagName = "online"
+)
@@ -25,12 +25,15 @@ import (
 // Cleanup creates and returns the `dev cleanup` command.
 func Cleanup() *cobra.Command {
 	c := NewCleanupCommand()
-	return &cobra.Command{
+	result := &cobra.Command{
 		Use:   "cleanup",
 		Short: "Cleans the development environment",
 		Args:  cobra.NoArgs,
 		RunE:  c.run,
 	}
+	flags := result.Flags()
+	internal.AddCRDDeleterFlags(flags)
+	return result
 }
 
 // CleanupCommand contains the data and logic needed to run the `dev cleanup` command.
@@ -84,6 +87,8 @@ func (c *CleanupCommand) run(cmd *cobra.Command, argv []string) (err error) {
 		SetLogger(logger).
 		SetClient(client).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(flags).
 		SetFS(templatesFS).
 		SetRoot("templates/setup").
 		SetDirs("crds", "objects").
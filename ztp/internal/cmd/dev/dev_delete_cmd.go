@@ -48,6 +48,7 @@ func Delete() *cobra.Command {
 			"Value '-' indicates that the file should be taken from the "+
 			"standard input stream.",
 	)
+	internal.AddCRDDeleterFlags(flags)
 
 	return result
 }
@@ -156,6 +157,8 @@ func (c *DeleteCommand) run(cmd *cobra.Command, argv []string) error {
 		SetLogger(logger).
 		SetClient(client).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(flags).
 		SetFS(os.DirFS(tmp)).
 		Build()
 	if err != nil {
@@ -85,6 +85,7 @@ func (c *SetupCommand) run(cmd *cobra.Command, argv []string) (err error) {
 		SetLogger(logger).
 		SetClient(client).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
 		SetFS(templatesFS).
 		SetRoot("templates/setup").
 		SetDirs("crds", "objects").
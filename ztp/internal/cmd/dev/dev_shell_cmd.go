@@ -127,10 +127,23 @@ func (c *ShellCommand) run(cmd *cobra.Command, argv []string) (err error) {
 		c.cluster.Name,
 	)
 	c.config.Clusters = []*models.Cluster{c.cluster}
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		SetCluster(c.cluster.Name).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
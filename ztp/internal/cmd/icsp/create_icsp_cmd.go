@@ -27,6 +27,7 @@ import (
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v3"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	clnt "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/exit"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/jq"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
 )
 
 // Create creates and returns the `create icsp` command.
@@ -49,6 +51,7 @@ func Create() *cobra.Command {
 	}
 	flags := result.Flags()
 	config.AddFlags(flags)
+	internal.AddDryRunFlag(flags)
 	return result
 }
 
@@ -130,11 +133,25 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -256,12 +273,18 @@ func (t *CreateTask) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if trusted {
+	switch {
+	case trusted:
 		t.console.Warn(
 			"Registry '%s' is already trusted in cluster '%s'",
 			t.cluster.Registry.URL, t.cluster.Name,
 		)
-	} else {
+	case internal.DryRunFlag(t.parent.flags):
+		t.console.Info(
+			"Not adding trusted registry '%s' to cluster '%s' because of dry run",
+			t.cluster.Registry.URL, t.cluster.Name,
+		)
+	default:
 		err = registryTool.AddTrusted(ctx, t.cluster.Registry.URL, t.cluster.Registry.CA)
 		if err != nil {
 			return err
@@ -293,6 +316,9 @@ func (t *CreateTask) createCatalogICSP(ctx context.Context, catalog *unstructure
 	if err != nil {
 		return err
 	}
+	if internal.DryRunFlag(t.parent.flags) {
+		return t.showCatalogICSP(ctx, icsp, catalog)
+	}
 	err = t.client.Create(ctx, icsp)
 	if apierrors.IsAlreadyExists(err) {
 		t.console.Warn(
@@ -311,6 +337,42 @@ func (t *CreateTask) createCatalogICSP(ctx context.Context, catalog *unstructure
 	return nil
 }
 
+// showCatalogICSP is used in dry run mode instead of creating the ICSP. It writes to the console the
+// differences that creating it would introduce.
+func (t *CreateTask) showCatalogICSP(ctx context.Context, icsp,
+	catalog *unstructured.Unstructured) error {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(icsp.GroupVersionKind())
+	err := t.client.Get(ctx, clnt.ObjectKeyFromObject(icsp), current)
+	if err == nil {
+		t.console.Warn(
+			"ICSP '%s' for catalog '%s' of cluster '%s' already exists",
+			icsp, catalog, t.cluster.Name,
+		)
+		return nil
+	}
+	if !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(icsp.Object)
+	if err != nil {
+		return err
+	}
+	t.console.Info(
+		"Differences for ICSP '%s' for catalog '%s' of cluster '%s':",
+		icsp, catalog, t.cluster.Name,
+	)
+	t.console.Print(text.Diff(
+		"/dev/null",
+		fmt.Sprintf("merged/%s/%s", icsp.GetKind(), icsp.GetName()),
+		"", buffer.String(),
+	))
+	return nil
+}
+
 func (t *CreateTask) generateCatalogICSP(ctx context.Context, catalog *unstructured.Unstructured,
 	registry string) (result *unstructured.Unstructured, err error) {
 	t.logger.V(1).Info(
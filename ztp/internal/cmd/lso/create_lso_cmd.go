@@ -54,6 +54,7 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	return result
 }
 
@@ -137,11 +138,25 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -248,10 +263,17 @@ func (t *CreateTask) run(ctx context.Context) error {
 		return err
 	}
 
-	// Wipe the disks:
-	err = t.wipeClusterDisks(ctx)
-	if err != nil {
-		return err
+	// Wipe the disks, unless this is a dry run:
+	if internal.DryRunFlag(t.flags) {
+		t.console.Info(
+			"Not wiping disks of cluster '%s' because of dry run",
+			t.cluster.Name,
+		)
+	} else {
+		err = t.wipeClusterDisks(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Deploy the operator:
@@ -388,6 +410,7 @@ func (t *CreateTask) deployLSO(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -395,9 +418,13 @@ func (t *CreateTask) deployLSO(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("lso").
 		SetClient(t.client).
 		SetFS(templatesFS).
-		SetRoot("templates/objects").
+		SetRoot("templates").
+		SetDir("objects").
 		Build()
 	if err != nil {
 		return err
@@ -425,6 +452,11 @@ func (t *CreateTask) deployLSO(ctx context.Context) error {
 		return err
 	}
 
+	// In dry run mode the objects haven't been created, so there is nothing to wait for:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
 	// Find the volume:
 	var volume *unstructured.Unstructured
 	for _, object := range objects {
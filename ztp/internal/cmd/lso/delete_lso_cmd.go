@@ -44,6 +44,8 @@ func Delete() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddDryRunFlag(flags)
+	internal.AddCRDDeleterFlags(flags)
 	_ = flags.Bool(
 		crdsFlagName,
 		false,
@@ -122,11 +124,25 @@ func (c *DeleteCommand) run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -197,6 +213,7 @@ func (t *DeleteTask) deleteLSO(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -204,6 +221,9 @@ func (t *DeleteTask) deleteLSO(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("lso").
 		SetClient(t.client).
 		SetFS(templatesFS).
 		SetRoot("templates").
@@ -219,6 +239,11 @@ func (t *DeleteTask) deleteLSO(ctx context.Context) error {
 		return err
 	}
 
+	// In dry run mode nothing has been deleted, so there is nothing else to do:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
 	// Delete the annotation in the namespace that indicates that the disks have been wiped:
 	namespace := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -241,7 +266,10 @@ func (t *DeleteTask) deleteLSO(ctx context.Context) error {
 	}
 	if crds {
 		t.console.Warn("CRDs in group 'local.storage.openshift.io' will be deleted")
-		err = t.deleteCRDGroup(ctx, "local.storage.openshift.io")
+		err = internal.DeleteCRDGroup(
+			ctx, t.logger, t.console, t.flags, t.client, t.cluster.Name,
+			"local.storage.openshift.io",
+		)
 		if err != nil {
 			return err
 		}
@@ -250,31 +278,6 @@ func (t *DeleteTask) deleteLSO(ctx context.Context) error {
 	return nil
 }
 
-func (t *DeleteTask) deleteCRDGroup(ctx context.Context, group string) error {
-	n, err := t.client.DeleteCRDGroup(ctx, group)
-	if err != nil {
-		return err
-	}
-	switch {
-	case n == 0:
-		t.console.Info(
-			"There are CRDs to delete in group '%s' in cluster '%s'",
-			group, t.cluster.Name,
-		)
-	case n == 1:
-		t.console.Info(
-			"Deleted one CRD in group '%s' in cluster '%s'",
-			group, t.cluster.Name,
-		)
-	default:
-		t.console.Info(
-			"Deleted %d CRDs in group '%s' in cluster '%s",
-			n, group, t.cluster.Name,
-		)
-	}
-	return nil
-}
-
 // Names of command line flags:
 const (
 	crdsFlagName = "crds"
@@ -54,6 +54,7 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	return result
 }
 
@@ -148,11 +149,25 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -188,6 +203,10 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 					"cluster '%s': %v",
 				cluster.Name, err,
 			)
+			var waitErr *internal.WaitError
+			if errors.As(err, &waitErr) {
+				c.console.Print(waitErr.Report())
+			}
 			return exit.Error(1)
 		}
 	}
@@ -245,10 +264,17 @@ func (t *CreateTask) run(ctx context.Context) error {
 		return err
 	}
 
-	// Wipe the disks:
-	err = t.wipeClusterDisks(ctx)
-	if err != nil {
-		return err
+	// Wipe the disks, unless this is a dry run:
+	if internal.DryRunFlag(t.flags) {
+		t.console.Info(
+			"Not wiping disks of cluster '%s' because of dry run",
+			t.cluster.Name,
+		)
+	} else {
+		err = t.wipeClusterDisks(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Deploy the operator:
@@ -385,6 +411,7 @@ func (t *CreateTask) deployLVMO(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -392,15 +419,21 @@ func (t *CreateTask) deployLVMO(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("lvmo").
+		SetWait(true).
 		SetClient(t.client).
 		SetFS(templatesFS).
-		SetRoot("templates/objects").
+		SetRoot("templates").
+		SetDir("objects").
 		Build()
 	if err != nil {
 		return err
 	}
 
-	// Create the objects:
+	// Create the objects and wait till they are healthy, in particular till the LVM cluster is
+	// ready:
 	objects, err := applier.Render(ctx, map[string]any{
 		"Version": t.version,
 	})
@@ -412,6 +445,11 @@ func (t *CreateTask) deployLVMO(ctx context.Context) error {
 		return err
 	}
 
+	// In dry run mode the objects haven't been created, so there is nothing to wait for:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
 	// Find the LVM cluster:
 	var cluster *unstructured.Unstructured
 	for _, object := range objects {
@@ -424,12 +462,6 @@ func (t *CreateTask) deployLVMO(ctx context.Context) error {
 		return fmt.Errorf("failed to find created LVM cluster")
 	}
 
-	// Wait till the volume is available:
-	err = t.waitLVMCluster(ctx, cluster)
-	if err != nil {
-		return err
-	}
-
 	// Wait till the storage class is created and then make it the default:
 	var deviceClass string
 	err = t.jq.Query(`.spec.storage.deviceClasses[0].name`, cluster, &deviceClass)
@@ -463,48 +495,6 @@ func (t *CreateTask) deployLVMO(ctx context.Context) error {
 	return nil
 }
 
-func (t *CreateTask) waitLVMCluster(ctx context.Context, cluster *unstructured.Unstructured) error {
-	t.console.Info(
-		"Waiting for LVM cluster '%s' of cluster '%s' to be ready",
-		cluster, t.cluster.Name,
-	)
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(internal.LVMClusterGVK)
-	watch, err := t.client.Watch(
-		ctx, list,
-		clnt.InNamespace(cluster.GetNamespace()),
-		clnt.MatchingFields{
-			"metadata.name": cluster.GetName(),
-		},
-	)
-	if err != nil {
-		return err
-	}
-	defer watch.Stop()
-	for event := range watch.ResultChan() {
-		object, ok := event.Object.(*unstructured.Unstructured)
-		if !ok {
-			continue
-		}
-		var ready string
-		err = t.jq.Query(
-			`.status.ready`,
-			object.Object, &ready,
-		)
-		if err != nil {
-			return err
-		}
-		if ready == "true" {
-			t.console.Info(
-				"LVM cluster '%s' of cluster '%s' is now ready",
-				cluster, t.cluster.Name,
-			)
-			break
-		}
-	}
-	return nil
-}
-
 func (t *CreateTask) waitStorageClass(ctx context.Context, object *storagev1.StorageClass) error {
 	list := &storagev1.StorageClassList{}
 	watch, err := t.client.Watch(
@@ -45,6 +45,7 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	_ = flags.DurationP(
 		waitFlagName,
 		"w",
@@ -120,11 +121,25 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -160,6 +175,12 @@ func (c *CreateCommand) Run(cmd *cobra.Command, argv []string) error {
 		}
 	}
 
+	// In dry run mode the load balancers haven't been created, so there is nothing to wait
+	// for:
+	if internal.DryRunFlag(c.flags) {
+		return nil
+	}
+
 	// Wait for the API endpoints of the clusters to be reachable:
 	wait, err := c.flags.GetDuration(waitFlagName)
 	if err != nil {
@@ -256,6 +277,7 @@ func (t *CreateTask) Run(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -263,6 +285,9 @@ func (t *CreateTask) Run(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("metallb").
 		SetClient(t.client).
 		SetFS(templatesFS).
 		SetRoot("templates").
@@ -42,6 +42,8 @@ func Delete() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddDryRunFlag(flags)
+	internal.AddCRDDeleterFlags(flags)
 	_ = flags.Bool(
 		crdsFlagName,
 		false,
@@ -134,11 +136,25 @@ func (c *DeleteCommand) Run(cmd *cobra.Command, argv []string) error {
 	}
 	defer c.client.Close()
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(cmd.Flags()).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -230,6 +246,7 @@ func (t *DeleteTask) Run(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -237,6 +254,9 @@ func (t *DeleteTask) Run(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("metallb").
 		SetClient(t.client).
 		SetFS(templatesFS).
 		SetRoot("templates").
@@ -254,18 +274,29 @@ func (t *DeleteTask) Run(ctx context.Context) error {
 		return err
 	}
 
+	// In dry run mode nothing has been deleted, so there is nothing else to do:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
 	// Deleting the CRDs:
 	crds, err := t.flags.GetBool(crdsFlagName)
 	if err != nil {
 		return err
 	}
 	if crds {
-		t.console.Warn("CRDs in groups 'metallb.io' and 'mnstate.io' will be deleted")
-		err = t.deleteCRDGroup(ctx, "metallb.io")
+		t.console.Warn("CRDs in groups 'metallb.io' and 'nmstate.io' will be deleted")
+		err = internal.DeleteCRDGroup(
+			ctx, t.logger, t.console, t.flags, t.client, t.cluster.Name,
+			"metallb.io",
+		)
 		if err != nil {
 			return err
 		}
-		err = t.deleteCRDGroup(ctx, "nmstate.io")
+		err = internal.DeleteCRDGroup(
+			ctx, t.logger, t.console, t.flags, t.client, t.cluster.Name,
+			"nmstate.io",
+		)
 		if err != nil {
 			return err
 		}
@@ -274,31 +305,6 @@ func (t *DeleteTask) Run(ctx context.Context) error {
 	return nil
 }
 
-func (t *DeleteTask) deleteCRDGroup(ctx context.Context, group string) error {
-	n, err := t.client.DeleteCRDGroup(ctx, group)
-	if err != nil {
-		return err
-	}
-	switch {
-	case n == 0:
-		t.console.Info(
-			"There are CRDs to delete in group '%s' in cluster '%s'",
-			group, t.cluster.Name,
-		)
-	case n == 1:
-		t.console.Info(
-			"Deleted one CRD in group '%s' in cluster '%s'",
-			group, t.cluster.Name,
-		)
-	default:
-		t.console.Info(
-			"Deleted %d CRDs in group '%s' in cluster '%s",
-			n, group, t.cluster.Name,
-		)
-	}
-	return nil
-}
-
 // Names of command line flags:
 const (
 	crdsFlagName = "crds"
@@ -50,6 +50,7 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	return result
 }
 
@@ -143,11 +144,25 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -248,6 +263,7 @@ func (t *CreateTask) deployODF(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -255,6 +271,9 @@ func (t *CreateTask) deployODF(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("odf").
 		SetClient(t.client).
 		SetFS(templatesFS).
 		SetRoot("templates").
@@ -264,10 +283,17 @@ func (t *CreateTask) deployODF(ctx context.Context) error {
 		return err
 	}
 
-	// Label the nodes:
-	err = t.labelNodes(ctx)
-	if err != nil {
-		return err
+	// Label the nodes, unless this is a dry run:
+	if internal.DryRunFlag(t.flags) {
+		t.console.Info(
+			"Not labeling nodes of cluster '%s' because of dry run",
+			t.cluster.Name,
+		)
+	} else {
+		err = t.labelNodes(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Calculate the variables:
@@ -291,6 +317,11 @@ func (t *CreateTask) deployODF(ctx context.Context) error {
 		return err
 	}
 
+	// In dry run mode the objects haven't been created, so there is nothing to wait for:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
 	// Find the storage cluster:
 	var storageCluster *unstructured.Unstructured
 	for _, object := range objects {
@@ -19,6 +19,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -54,6 +55,7 @@ func Create() *cobra.Command {
 	flags := result.Flags()
 	config.AddFlags(flags)
 	internal.AddEnricherFlags(flags)
+	internal.AddApplierFlags(flags)
 	return result
 }
 
@@ -137,11 +139,25 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 		return exit.Error(1)
 	}
 
+	// Create the listener for the events of the enricher:
+	listener, err := internal.NewApplierListener().
+		SetLogger(c.logger).
+		SetConsole(c.console).
+		Build()
+	if err != nil {
+		c.console.Error(
+			"Failed to create listener: %v",
+			err,
+		)
+		return exit.Error(1)
+	}
+
 	// Enrich the configuration:
 	enricher, err := internal.NewEnricher().
 		SetLogger(c.logger).
 		SetClient(c.client).
 		SetFlags(c.flags).
+		SetListener(listener.EnricherFunc).
 		Build()
 	if err != nil {
 		c.console.Error(
@@ -175,6 +191,10 @@ func (c *CreateCommand) run(cmd *cobra.Command, argv []string) error {
 				"Failed to create registry for cluster '%s': %v",
 				cluster.Name, err,
 			)
+			var waitErr *internal.WaitError
+			if errors.As(err, &waitErr) {
+				c.console.Print(waitErr.Report())
+			}
 			return exit.Error(1)
 		}
 	}
@@ -244,6 +264,7 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 	listener, err := internal.NewApplierListener().
 		SetLogger(t.logger).
 		SetConsole(t.console).
+		SetCluster(t.cluster.Name).
 		Build()
 	if err != nil {
 		return err
@@ -251,6 +272,10 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 	applier, err := internal.NewApplier().
 		SetLogger(t.logger).
 		SetListener(listener.Func).
+		AddWaiterListener(listener.WaiterFunc).
+		SetFlags(t.flags).
+		SetInventory("registry").
+		SetWait(true).
 		SetClient(t.client).
 		SetFS(templatesFS).
 		SetRoot("templates/quay").
@@ -260,7 +285,8 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 		return err
 	}
 
-	// Create the objects:
+	// Create the objects and wait till they are healthy, in particular till the registry is
+	// available:
 	objects, err := applier.Render(ctx, nil)
 	if err != nil {
 		return err
@@ -270,7 +296,12 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 		return err
 	}
 
-	// Wait for the registry to be available:
+	// In dry run mode the objects haven't been created, so there is nothing to wait for:
+	if internal.DryRunFlag(t.flags) {
+		return nil
+	}
+
+	// Find the registry:
 	var registry *unstructured.Unstructured
 	for _, object := range objects {
 		if object.GroupVersionKind() == internal.QuayRegistryGVK {
@@ -284,15 +315,6 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 			t.cluster.Name,
 		)
 	}
-	t.console.Info(
-		"Waiting for registry '%s' for cluster '%s' to be available",
-		registry, t.cluster.Name,
-	)
-	err = t.waitRegistry(ctx, registry)
-	if err != nil {
-		return err
-	}
-
 	// Check if the secret containing the registry user and password is available:
 	var registryUser, registryPass string
 	secretObject := &corev1.Secret{}
@@ -443,49 +465,6 @@ func (t *CreateTask) deployRegistry(ctx context.Context) error {
 	return nil
 }
 
-func (t *CreateTask) waitRegistry(ctx context.Context, registry *unstructured.Unstructured) error {
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(internal.QuayRegistryListGVK)
-	namespace := registry.GetNamespace()
-	name := registry.GetName()
-	watch, err := t.client.Watch(
-		ctx, list,
-		clnt.InNamespace(namespace),
-		clnt.MatchingFields{
-			"metadata.name": name,
-		},
-	)
-	if err != nil {
-		return err
-	}
-	defer watch.Stop()
-	for event := range watch.ResultChan() {
-		object, ok := event.Object.(*unstructured.Unstructured)
-		if !ok {
-			continue
-		}
-		var available string
-		err = t.jq.Query(
-			`.status.conditions[]? | select(.type == "Available") | .status`,
-			object.Object, &available,
-		)
-		if err != nil {
-			return err
-		}
-		if available == "True" {
-			t.console.Info(
-				"Registry '%s' of cluster '%s' is now available",
-				registry, t.cluster.Name,
-			)
-			return nil
-		}
-	}
-	return fmt.Errorf(
-		"timed out while waiting for registry '%s/%s' of cluster '%s' to be ready",
-		namespace, name, t.cluster.Name,
-	)
-}
-
 func (t *CreateTask) initializeRegistry(ctx context.Context,
 	registry *unstructured.Unstructured) (user, pass string, err error) {
 	// Get the API host name from the routeObject:
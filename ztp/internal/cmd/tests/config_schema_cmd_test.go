@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/cmd"
+)
+
+var _ = Describe("Config schema command", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("Prints the JSON schema", func() {
+		// Run the command:
+		outBuffer := &bytes.Buffer{}
+		errBuffer := &bytes.Buffer{}
+		tool, err := internal.NewTool().
+			AddArgs("ztp", "config", "schema").
+			AddCommand(cmd.Config).
+			SetIn(&bytes.Buffer{}).
+			SetOut(outBuffer).
+			SetErr(errBuffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = tool.Run(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Check that the output is the schema, without any prefix:
+		var schema map[string]any
+		err = json.Unmarshal(outBuffer.Bytes(), &schema)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(schema).To(HaveKey("$schema"))
+		Expect(schema).To(HaveKey("$defs"))
+		Expect(errBuffer.String()).To(BeEmpty())
+	})
+})
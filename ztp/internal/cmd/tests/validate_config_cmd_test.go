@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/cmd"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/exit"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Validate config command", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// run is a helper function that runs the command with the given configuration and returns
+	// the error and the text written to the standard output and error.
+	run := func(config string) (err error, out, errs string) {
+		outBuffer := &bytes.Buffer{}
+		errBuffer := &bytes.Buffer{}
+		tool, err := internal.NewTool().
+			AddArgs("ztp", "validate", "config", "--config", config).
+			AddCommand(cmd.Validate).
+			SetIn(&bytes.Buffer{}).
+			SetOut(outBuffer).
+			SetErr(errBuffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = tool.Run(ctx)
+		out = outBuffer.String()
+		errs = errBuffer.String()
+		return
+	}
+
+	It("Accepts valid configuration", func() {
+		err, out, errs := run(text.Dedent(`
+			apiVersion: ztpfw/v1
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+		`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(ContainSubstring("Configuration is valid"))
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Reports schema problems", func() {
+		err, _, errs := run(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_usr: "user0"
+		`))
+		Expect(err).To(Equal(exit.Error(1)))
+		Expect(errs).To(ContainSubstring("'edgeclusters[0].my.master0.bmc_usr'"))
+		Expect(errs).To(ContainSubstring("found 1 problem"))
+	})
+
+	It("Reports semantic problems", func() {
+		err, _, errs := run(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+			    master1:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+		`))
+		Expect(err).To(Equal(exit.Error(1)))
+		Expect(errs).To(ContainSubstring("'my': cluster should have 1 or 3 control plane nodes"))
+		Expect(errs).To(ContainSubstring("'my.master1.mac_ext_dhcp': MAC address"))
+		Expect(errs).To(ContainSubstring("'my.master0.bmc_user': BMC user is mandatory"))
+		Expect(errs).To(ContainSubstring("found 8 problems"))
+	})
+})
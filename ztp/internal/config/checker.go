@@ -0,0 +1,340 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// CheckerBuilder contains the data and logic needed to create an object that checks the semantics
+// of a configuration that has already been loaded. Don't create instances of this type directly,
+// use the NewChecker function instead.
+type CheckerBuilder struct {
+	logger logr.Logger
+}
+
+// Checker knows how to check the semantics of a configuration, for example that MAC addresses
+// aren't repeated, or that the number of control plane nodes is supported. These are the checks
+// that can't be expressed in the schema and that don't need access to the hub cluster. Don't create
+// instances of this type directly, use the NewChecker function instead.
+type Checker struct {
+	logger logr.Logger
+}
+
+// NewChecker creates a builder that can then be used to create a configuration checker.
+func NewChecker() *CheckerBuilder {
+	return &CheckerBuilder{}
+}
+
+// SetLogger sets the logger that the checker will use to write to the log. This is mandatory.
+func (b *CheckerBuilder) SetLogger(value logr.Logger) *CheckerBuilder {
+	b.logger = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new checker.
+func (b *CheckerBuilder) Build() (result *Checker, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+
+	// Create and populate the object:
+	result = &Checker{
+		logger: b.logger,
+	}
+	return
+}
+
+// Check checks the given configuration and returns the list of problems found. The result will be
+// empty if there are no problems.
+func (c *Checker) Check(config *models.Config) []*Problem {
+	task := &checkerTask{
+		logger: c.logger,
+		macs:   map[string]string{},
+	}
+	for _, cluster := range config.Clusters {
+		task.checkCluster(cluster)
+	}
+	return task.problems
+}
+
+// checkerTask contains the state of one execution of the checker.
+type checkerTask struct {
+	logger   logr.Logger
+	macs     map[string]string
+	problems []*Problem
+}
+
+func (t *checkerTask) checkCluster(cluster *models.Cluster) {
+	t.checkControlPlaneCount(cluster)
+	t.checkStorageDisks(cluster)
+	for _, node := range cluster.Nodes {
+		t.checkNode(cluster, node)
+	}
+}
+
+func (t *checkerTask) checkControlPlaneCount(cluster *models.Cluster) {
+	count := 0
+	for _, node := range cluster.Nodes {
+		if node.Kind == models.NodeKindControlPlane {
+			count++
+		}
+	}
+	if count != 1 && count != 3 {
+		t.report(
+			cluster.Name,
+			"cluster should have 1 or 3 control plane nodes, but it has %d",
+			count,
+		)
+	}
+}
+
+func (t *checkerTask) checkStorageDisks(cluster *models.Cluster) {
+	// The `create odf` command assumes that all the nodes have the same number of storage disks,
+	// and it will fail if that isn't true, so we check it in advance:
+	nodes := cluster.Nodes
+	if len(nodes) == 0 {
+		return
+	}
+	disks0 := len(nodes[0].StorageDisks)
+	for i := 1; i < len(nodes); i++ {
+		disksI := len(nodes[i].StorageDisks)
+		if disks0 != disksI {
+			t.report(
+				t.join(cluster.Name, nodes[i].Name, "storage_disk"),
+				"all nodes should have the same number of storage disks, but "+
+					"node '%s' has %d and node '%s' has %d",
+				nodes[0].Name, disks0,
+				nodes[i].Name, disksI,
+			)
+		}
+	}
+}
+
+func (t *checkerTask) checkNode(cluster *models.Cluster, node *models.Node) {
+	path := t.join(cluster.Name, node.Name)
+
+	// Kind:
+	if node.Kind == "" {
+		t.report(
+			path,
+			"role isn't set and name doesn't match '%s' or '%s', so it isn't "+
+				"possible to decide if it is a control plane or a worker node",
+			controlNodeRE, workerNodeRE,
+		)
+	}
+
+	// Hostname:
+	t.checkHostname(cluster, node)
+
+	// BMC:
+	if node.BMC.URL == "" {
+		t.report(t.join(path, "bmc_url"), "BMC URL is mandatory")
+	}
+	if node.BMC.User == nil {
+		t.report(t.join(path, "bmc_user"), "BMC user is mandatory")
+	}
+	if node.BMC.Pass == nil {
+		t.report(t.join(path, "bmc_pass"), "BMC password is mandatory")
+	}
+
+	// Bonds and VLANs:
+	t.checkExternalBond(path, node)
+	t.checkExternalVLAN(path, node)
+
+	// Static external addressing:
+	t.checkExternalStatic(path, node)
+
+	// MAC addresses:
+	if node.ExternalNIC != nil {
+		t.checkMAC(t.join(path, "mac_ext_dhcp"), node.ExternalNIC.MAC)
+	}
+	if node.ExternalBond != nil {
+		for i, member := range node.ExternalBond.Members {
+			t.checkMAC(
+				t.join(path, fmt.Sprintf("ext_bond.members[%d].mac", i)),
+				member.MAC,
+			)
+		}
+	}
+	if node.InternalNIC != nil {
+		t.checkMAC(t.join(path, "mac_int_static"), node.InternalNIC.MAC)
+	}
+}
+
+func (t *checkerTask) checkHostname(cluster *models.Cluster, node *models.Node) {
+	// Explicit host names may be fully qualified, so they only need to be valid subdomains. The
+	// generated ones are also used as labels, so they need to be valid labels, and that means that
+	// the combination of the cluster and node names can't be longer than 63 characters.
+	if node.Hostname != "" {
+		errs := validation.IsDNS1123Subdomain(node.Hostname)
+		if len(errs) > 0 {
+			t.report(
+				t.join(cluster.Name, node.Name, "hostname"),
+				"host name '%s' isn't valid: %s",
+				node.Hostname, strings.Join(errs, ", "),
+			)
+		}
+		return
+	}
+	hostname := node.DefaultHostname(cluster.Name)
+	if hostname == "" {
+		return
+	}
+	errs := validation.IsDNS1123Label(hostname)
+	if len(errs) > 0 {
+		t.report(
+			t.join(cluster.Name, node.Name),
+			"generated host name '%s' isn't valid, use a shorter cluster or node name "+
+				"containing only lower case letters, digits and dashes, or set the "+
+				"'hostname' field explicitly: %s",
+			hostname, strings.Join(errs, ", "),
+		)
+	}
+}
+
+func (t *checkerTask) checkExternalBond(path string, node *models.Node) {
+	bond := node.ExternalBond
+	if bond == nil {
+		return
+	}
+	if node.ExternalNIC != nil {
+		t.report(
+			t.join(path, "ext_bond"),
+			"bond can't be used together with 'nic_ext_dhcp', the NICs should be "+
+				"listed as members of the bond instead",
+		)
+	}
+	if len(bond.Members) == 0 {
+		t.report(
+			t.join(path, "ext_bond", "members"),
+			"bond '%s' should have at least one member",
+			bond.Name,
+		)
+	}
+}
+
+func (t *checkerTask) checkExternalVLAN(path string, node *models.Node) {
+	vlan := node.ExternalVLAN
+	if vlan == nil {
+		return
+	}
+	if node.ExternalBase() == "" {
+		t.report(
+			t.join(path, "ext_vlan"),
+			"VLAN needs an external NIC or bond to be built on top of",
+		)
+	}
+	if vlan.ID < 1 || vlan.ID > 4094 {
+		t.report(
+			t.join(path, "ext_vlan", "id"),
+			"VLAN identifier should be between 1 and 4094, but it is %d",
+			vlan.ID,
+		)
+	}
+}
+
+func (t *checkerTask) checkExternalStatic(path string, node *models.Node) {
+	// The gateway and the DNS servers only make sense when the address is also static, otherwise
+	// they would be silently ignored in favour of what the DHCP server provides:
+	if !node.ExternalStatic {
+		if node.ExternalGateway != nil {
+			t.report(
+				t.join(path, "ext_gateway"),
+				"gateway can only be set when 'ext_ip' is also set",
+			)
+		}
+		if len(node.ExternalDNS) > 0 {
+			t.report(
+				t.join(path, "ext_dns"),
+				"DNS servers can only be set when 'ext_ip' is also set",
+			)
+		}
+		return
+	}
+
+	// A static address needs a NIC to put it on and a gateway to reach the rest of the world:
+	if node.ExternalInterface() == "" {
+		t.report(
+			t.join(path, "nic_ext_dhcp"),
+			"external NIC or bond is mandatory when 'ext_ip' is set",
+		)
+	}
+	if node.ExternalGateway == nil {
+		t.report(
+			t.join(path, "ext_gateway"),
+			"gateway is mandatory when 'ext_ip' is set",
+		)
+		return
+	}
+	address := node.ExternalIP
+	network := &net.IPNet{
+		IP:   address.Address,
+		Mask: net.CIDRMask(address.Prefix, models.HostPrefix(address.Address)),
+	}
+	if !network.Contains(node.ExternalGateway) {
+		t.report(
+			t.join(path, "ext_gateway"),
+			"gateway '%s' isn't inside the network of external address '%s'",
+			node.ExternalGateway, address,
+		)
+	}
+}
+
+func (t *checkerTask) checkMAC(path, value string) {
+	if value == "" {
+		return
+	}
+	mac, err := net.ParseMAC(value)
+	if err != nil {
+		// This should have been detected when loading the configuration.
+		t.report(path, "value '%s' isn't a valid MAC address", value)
+		return
+	}
+	key := mac.String()
+	previous, ok := t.macs[key]
+	if ok {
+		t.report(path, "MAC address '%s' is already used by '%s'", value, previous)
+		return
+	}
+	t.macs[key] = path
+}
+
+func (t *checkerTask) join(names ...string) string {
+	return strings.Join(names, ".")
+}
+
+func (t *checkerTask) report(path string, format string, args ...any) {
+	problem := &Problem{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	t.logger.V(1).Info(
+		"Found configuration problem",
+		"path", problem.Path,
+		"message", problem.Message,
+	)
+	t.problems = append(t.problems, problem)
+}
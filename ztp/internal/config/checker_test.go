@@ -0,0 +1,279 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		logger  logr.Logger
+		checker *Checker
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		checker, err = NewChecker().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// load is a helper function that loads the given configuration text.
+	load := func(text string) *models.Config {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		return config
+	}
+
+	It("Can't be created without a logger", func() {
+		checker, err := NewChecker().Build()
+		Expect(err).To(MatchError("logger is mandatory"))
+		Expect(checker).To(BeNil())
+	})
+
+	It("Accepts valid configuration", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			      storage_disk:
+			      - /dev/vdb
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("Detects duplicated MAC addresses", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			- your:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "AA:BB:CC:EE:B0:10"
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Path).To(Equal("your.master0.mac_ext_dhcp"))
+		Expect(problems[0].Message).To(ContainSubstring("my.master0.mac_ext_dhcp"))
+	})
+
+	It("Detects missing BMC credentials", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Path).To(Equal("my.master0.bmc_user"))
+		Expect(problems[1].Path).To(Equal("my.master0.bmc_pass"))
+	})
+
+	It("Detects different number of storage disks", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			      storage_disk:
+			      - /dev/vdb
+			    master1:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+			      storage_disk:
+			      - /dev/vdb
+			      - /dev/vdc
+			    master2:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/2"
+			      bmc_user: "user2"
+			      bmc_pass: "pass2"
+			      storage_disk:
+			      - /dev/vdb
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Path).To(Equal("my.master1.storage_disk"))
+		Expect(problems[0].Message).To(ContainSubstring("node 'master1' has 2"))
+	})
+
+	It("Detects node names that don't match the expected pattern", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			    node1:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Path).To(Equal("my.node1"))
+		Expect(problems[0].Message).To(ContainSubstring("^master\\d+$"))
+	})
+
+	It("Detects node names that generate invalid host names", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			    Node_A:
+			      role: worker
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+			    node-b-with-a-name-that-is-way-too-long-to-be-used-in-a-host-name:
+			      role: worker
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/2"
+			      bmc_user: "user2"
+			      bmc_pass: "pass2"
+			    node-c:
+			      role: worker
+			      hostname: node-c.example.com
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/3"
+			      bmc_user: "user3"
+			      bmc_pass: "pass3"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Path).To(Equal("my.Node_A"))
+		Expect(problems[0].Message).To(ContainSubstring("'ztpfw-my-Node_A'"))
+		Expect(problems[1].Path).To(Equal(
+			"my.node-b-with-a-name-that-is-way-too-long-to-be-used-in-a-host-name",
+		))
+		Expect(problems[1].Message).To(ContainSubstring("63"))
+	})
+
+	It("Detects invalid number of control plane nodes", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			    master1:
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Path).To(Equal("my"))
+		Expect(problems[0].Message).To(ContainSubstring("1 or 3"))
+		Expect(problems[0].Message).To(ContainSubstring("it has 2"))
+	})
+
+	It("Detects incomplete static external addressing", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      ext_ip: 192.168.150.100/24
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+			    master1:
+			      nic_ext_dhcp: enp1s0
+			      ext_ip: 192.168.150.101/24
+			      ext_gateway: 192.168.151.1
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/1"
+			      bmc_user: "user1"
+			      bmc_pass: "pass1"
+			    master2:
+			      nic_ext_dhcp: enp1s0
+			      ext_dns:
+			      - 192.168.150.2
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/2"
+			      bmc_user: "user2"
+			      bmc_pass: "pass2"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0].Path).To(Equal("my.master0.ext_gateway"))
+		Expect(problems[0].Message).To(ContainSubstring("mandatory"))
+		Expect(problems[1].Path).To(Equal("my.master1.ext_gateway"))
+		Expect(problems[1].Message).To(ContainSubstring("isn't inside the network"))
+		Expect(problems[2].Path).To(Equal("my.master2.ext_dns"))
+	})
+
+	It("Detects invalid external bonds and VLANs", func() {
+		config := load(text.Dedent(`
+			edgeclusters:
+			- my:
+			    master0:
+			      nic_ext_dhcp: enp1s0
+			      mac_ext_dhcp: "aa:bb:cc:ee:b0:10"
+			      ext_bond:
+			        name: bond0
+			        members:
+			        - nic: enp2s0
+			          mac: "aa:bb:cc:ee:b0:10"
+			      ext_vlan:
+			        id: 5000
+			      bmc_url: "redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0"
+			      bmc_user: "user0"
+			      bmc_pass: "pass0"
+		`))
+		problems := checker.Check(config)
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0].Path).To(Equal("my.master0.ext_bond"))
+		Expect(problems[1].Path).To(Equal("my.master0.ext_vlan.id"))
+		Expect(problems[2].Path).To(Equal("my.master0.ext_bond.members[0].mac"))
+		Expect(problems[2].Message).To(ContainSubstring("my.master0.mac_ext_dhcp"))
+	})
+})
@@ -26,9 +26,17 @@ func AddFlags(set *pflag.FlagSet) {
 			"configuration text will be loaded from that file. Otherwise the "+
 			"value should be the YAML text itself.",
 	)
+	_ = set.StringArray(
+		configOverlayFlagName,
+		[]string{},
+		"Configuration that will be merged on top of the one given with the '--config' "+
+			"flag. It accepts the same kind of values. Can be used multiple times, and "+
+			"the overlays are merged in the order they are given.",
+	)
 }
 
 // Names of the flags:
 const (
-	configFlagName = "config"
+	configFlagName        = "config"
+	configOverlayFlagName = "config-overlay"
 )
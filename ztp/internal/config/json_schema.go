@@ -0,0 +1,199 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// JSONSchemaID is the identifier of the JSON schema generated by the JSONSchema function.
+const JSONSchemaID = "https://github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/config.json"
+
+// JSONSchema generates a JSON schema that describes the configuration format. It is generated from
+// the same types that the loader uses to validate the configuration, so it is always up to date.
+// The result is a tree of maps and slices that can be directly converted to JSON. It is intended
+// for editors and other tools that can use it to offer completion and validation, but note that it
+// can't express some of the checks that the loader does, for example the syntax of secret
+// references.
+func JSONSchema() map[string]any {
+	g := &jsonSchemaGenerator{
+		defs: map[string]any{},
+	}
+	result := g.generateStruct(reflect.TypeOf(fileData{}))
+	result["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	result["$id"] = JSONSchemaID
+	result["title"] = "Zero touch provisioning configuration"
+	result["$defs"] = g.defs
+	properties := result["properties"].(map[string]any)
+	properties["apiVersion"].(map[string]any)["enum"] = slices.Clone(apiVersions)
+	return result
+}
+
+// jsonSchemaGenerator contains the state needed while generating the JSON schema.
+type jsonSchemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *jsonSchemaGenerator) generateValue(typ reflect.Type, format, enum string) map[string]any {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		return g.generateRef(typ)
+	case reflect.Map:
+		return map[string]any{
+			"type": "object",
+			"additionalProperties": g.generateNullable(
+				g.generateValue(typ.Elem(), format, enum),
+			),
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type": "array",
+			"items": g.generateNullable(
+				g.generateValue(typ.Elem(), format, enum),
+			),
+		}
+	case reflect.String:
+		result := map[string]any{
+			"type": "string",
+		}
+		g.addFormat(result, format)
+		if enum != "" {
+			result["enum"] = strings.Split(enum, ",")
+		}
+		return result
+	case reflect.Bool:
+		return map[string]any{
+			"type": "boolean",
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{
+			"type": "integer",
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+// generateRef adds the definition of the given struct type to the `$defs` section of the schema, if
+// it isn't already there, and returns a reference to it. The name of the definition is the name of
+// the type without the `Data` suffix.
+func (g *jsonSchemaGenerator) generateRef(typ reflect.Type) map[string]any {
+	name := strings.TrimSuffix(typ.Name(), "Data")
+	_, ok := g.defs[name]
+	if !ok {
+		// Put a placeholder first, in case the type references itself:
+		g.defs[name] = nil
+		g.defs[name] = g.generateStruct(typ)
+	}
+	return map[string]any{
+		"$ref": "#/$defs/" + name,
+	}
+}
+
+func (g *jsonSchemaGenerator) generateStruct(typ reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	var additional any = false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, options := fieldName(field)
+		if slices.Contains(options, "inline") {
+			additional = g.generateNullable(g.generateValue(field.Type.Elem(), "", ""))
+			description := field.Tag.Get("description")
+			if description != "" {
+				additional.(map[string]any)["description"] = description
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		property := g.generateNullable(g.generateValue(
+			field.Type, field.Tag.Get("format"), field.Tag.Get("enum"),
+		))
+		description := field.Tag.Get("description")
+		if description != "" {
+			property["description"] = description
+		}
+		properties[name] = property
+		if field.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+	}
+	result := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": additional,
+	}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	return result
+}
+
+// generateNullable modifies the schema of a value so that it also accepts null, as the validator
+// does. This is needed because overlays use null values to remove fields.
+func (g *jsonSchemaGenerator) generateNullable(schema map[string]any) map[string]any {
+	typ, ok := schema["type"]
+	if !ok {
+		if _, ok := schema["$ref"]; ok {
+			schema = map[string]any{
+				"anyOf": []any{
+					schema,
+					map[string]any{"type": "null"},
+				},
+			}
+		}
+		return schema
+	}
+	schema["type"] = []any{typ, "null"}
+	enum, ok := schema["enum"].([]string)
+	if ok {
+		values := make([]any, len(enum)+1)
+		for i, value := range enum {
+			values[i] = value
+		}
+		schema["enum"] = values
+	}
+	return schema
+}
+
+// addFormat adds to the schema the keywords that correspond to the formats supported by the
+// validator. Only the formats that have an equivalent in JSON schema are translated, for the rest
+// a regular expression that approximately matches the valid values is used.
+func (g *jsonSchemaGenerator) addFormat(schema map[string]any, format string) {
+	switch format {
+	case "mac":
+		schema["pattern"] = `^[0-9A-Fa-f]{2}([:-][0-9A-Fa-f]{2}){5}$`
+	case "path":
+		schema["pattern"] = `^/`
+	case "ip":
+		schema["anyOf"] = []any{
+			map[string]any{"format": "ipv4"},
+			map[string]any{"format": "ipv6"},
+		}
+	case "ipprefix", "cidr":
+		schema["pattern"] = `^[0-9A-Fa-f:.]+/[0-9]{1,3}$`
+	case "hostname":
+		schema["format"] = "hostname"
+		schema["pattern"] = hostnameRE.String()
+	}
+}
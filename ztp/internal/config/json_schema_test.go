@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON schema", func() {
+	It("Can be converted to JSON", func() {
+		_, err := json.Marshal(JSONSchema())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Contains all the fields of the nodes", func() {
+		schema := JSONSchema()
+		defs := schema["$defs"].(map[string]any)
+		node := defs["node"].(map[string]any)
+		properties := node["properties"].(map[string]any)
+		typ := reflect.TypeOf(nodeData{})
+		for i := 0; i < typ.NumField(); i++ {
+			name, _ := fieldName(typ.Field(i))
+			Expect(properties).To(HaveKey(name))
+		}
+		Expect(node["additionalProperties"]).To(BeFalse())
+	})
+
+	It("Translates formats, enums and required fields", func() {
+		schema := JSONSchema()
+		defs := schema["$defs"].(map[string]any)
+		node := defs["node"].(map[string]any)
+		properties := node["properties"].(map[string]any)
+		Expect(properties["root_disk"]).To(HaveKeyWithValue("pattern", "^/"))
+		Expect(properties["role"]).To(HaveKeyWithValue("enum", ConsistOf(
+			"control-plane", "master", "worker", BeNil(),
+		)))
+		bond := defs["bond"].(map[string]any)
+		Expect(bond["required"]).To(ConsistOf("name", "members"))
+	})
+
+	It("Accepts nodes as additional properties of clusters", func() {
+		schema := JSONSchema()
+		defs := schema["$defs"].(map[string]any)
+		cluster := defs["cluster"].(map[string]any)
+		Expect(cluster["properties"]).To(HaveKey("config"))
+		Expect(cluster["properties"]).To(HaveKey("contrib"))
+		Expect(cluster["additionalProperties"]).To(HaveKeyWithValue("anyOf", ContainElement(
+			HaveKeyWithValue("$ref", "#/$defs/node"),
+		)))
+	})
+
+	It("Restricts the version to the supported ones", func() {
+		schema := JSONSchema()
+		properties := schema["properties"].(map[string]any)
+		Expect(properties["apiVersion"]).To(HaveKeyWithValue("enum", ContainElement(APIVersionV1)))
+	})
+})
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,39 +28,20 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 
-	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/jq"
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
 )
 
 // Loader contains the data and logic needed to load a configuration object. Don't create instances
 // of this type directly, use the NewLoader function instead.
 type Loader struct {
-	logger logr.Logger
-	flags  *pflag.FlagSet
-	source any
-	jq     *jq.Tool
-}
-
-// configData is used internally to parse the data of a cluster.
-type configData struct {
-	TPM *bool `json:"tpm"`
-}
-
-// nodeData is used internally to parse the data of a node.
-type nodeData struct {
-	BMCPass      *string  `json:"bmc_pass"`
-	BMCURL       *string  `json:"bmc_url"`
-	BMCUser      *string  `json:"bmc_user"`
-	IgnoreIfaces *string  `json:"ignore_ifaces"`
-	MACExtDHCP   *string  `json:"mac_ext_dhcp"`
-	MACIntStatic *string  `json:"mac_int_static"`
-	NICExtDHCP   *string  `json:"nic_ext_dhcp"`
-	NICIntStatic *string  `json:"nic_int_static"`
-	RootDisk     *string  `json:"root_disk"`
-	StorageDisk  []string `json:"storage_disk"`
+	logger   logr.Logger
+	flags    *pflag.FlagSet
+	source   any
+	overlays []any
 }
 
 // NewLoader creates a builder that can then be used to create a new configuration object.
@@ -85,12 +67,25 @@ func (l *Loader) SetLogger(value logr.Logger) *Loader {
 //
 // - A io.Reader providing the configuration text.
 //
+// The configuration can contain an `include` field with a list of files that will be loaded first
+// and then merged with the rest of the configuration. Relative names are relative to the directory
+// of the file that contains the `include` field, or to the current working directory if the
+// configuration isn't a file. See the mergeNodes function for the merge rules.
+//
 // This is mandatory.
 func (l *Loader) SetSource(value any) *Loader {
 	l.source = value
 	return l
 }
 
+// AddOverlay adds a configuration that will be merged on top of the source. The value can be any
+// of the things accepted by the SetSource method. Overlays are applied in the order they are added,
+// and they can also contain `include` fields. This is optional.
+func (l *Loader) AddOverlay(value any) *Loader {
+	l.overlays = append(l.overlays, value)
+	return l
+}
+
 // SetFlags sets the command line flags that that indicate how to load the configuration. This is
 // optional.
 func (b *Loader) SetFlags(flags *pflag.FlagSet) *Loader {
@@ -101,47 +96,39 @@ func (b *Loader) SetFlags(flags *pflag.FlagSet) *Loader {
 			b.SetSource(value)
 		}
 	}
+	if flags.Changed(configOverlayFlagName) {
+		values, err := flags.GetStringArray(configOverlayFlagName)
+		if err == nil {
+			for _, value := range values {
+				b.AddOverlay(value)
+			}
+		}
+	}
 	return b
 }
 
-// Load uses the data stored in the loader to create and populate a configuration object.
-func (l *Loader) Load() (result *models.Config, err error) {
-	// Check the parameters:
-	if l.logger.GetSink() == nil {
-		err = errors.New("logger is mandatory")
-		return
-	}
-	if l.source == nil {
-		if l.flags != nil && !l.flags.Changed(configFlagName) {
-			err = fmt.Errorf("flag '--%s' is mandatory", configFlagName)
-		} else {
-			err = fmt.Errorf("source is mandatory")
-		}
-		return
-	}
-	switch l.source.(type) {
-	case string:
-	case []byte:
-	case io.Reader:
-	default:
-		err = fmt.Errorf(
-			"source isn't valid, should be a string, an array of bytes "+
-				"or a reader, but it is of type %T",
-			l.source,
-		)
-		return
+// Validate checks that the configuration conforms to the schema, without creating the
+// configuration object. If there are problems the returned error will be a *ValidationError
+// containing all of them, each with its path and line number.
+func (l *Loader) Validate() error {
+	err := l.checkParameters()
+	if err != nil {
+		return err
 	}
+	_, err = l.loadSource()
+	return err
+}
 
-	// Create the JQ tool:
-	l.jq, err = jq.NewTool().
-		SetLogger(l.logger).
-		Build()
+// Load uses the data stored in the loader to create and populate a configuration object. The
+// configuration is validated first, see the Validate method for details.
+func (l *Loader) Load() (result *models.Config, err error) {
+	// Check the parameters:
+	err = l.checkParameters()
 	if err != nil {
-		err = fmt.Errorf("failed to create jq tool: %w", err)
 		return
 	}
 
-	// Load the source:
+	// Load and validate the source:
 	data, err := l.loadSource()
 	if err != nil {
 		return
@@ -161,30 +148,143 @@ func (l *Loader) Load() (result *models.Config, err error) {
 	return
 }
 
-func (l *Loader) loadSource() (result map[string]any, err error) {
-	switch typed := l.source.(type) {
+func (l *Loader) checkParameters() error {
+	if l.logger.GetSink() == nil {
+		return errors.New("logger is mandatory")
+	}
+	if l.source == nil {
+		if l.flags != nil && !l.flags.Changed(configFlagName) {
+			return fmt.Errorf("flag '--%s' is mandatory", configFlagName)
+		}
+		return fmt.Errorf("source is mandatory")
+	}
+	sources := append([]any{l.source}, l.overlays...)
+	for _, source := range sources {
+		switch source.(type) {
+		case string:
+		case []byte:
+		case io.Reader:
+		default:
+			return fmt.Errorf(
+				"source isn't valid, should be a string, an array of bytes "+
+					"or a reader, but it is of type %T",
+				source,
+			)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadSource() (result *fileData, err error) {
+	// Read the source and the overlays, merging them into a single document. We remember the
+	// file that each YAML node comes from so that problems can be reported with the right
+	// location.
+	task := &loaderTask{
+		logger:  l.logger,
+		origins: map[*yaml.Node]string{},
+	}
+	root, file, err := task.readSource(l.source)
+	if err != nil {
+		return
+	}
+	for _, overlay := range l.overlays {
+		var overlayRoot *yaml.Node
+		overlayRoot, _, err = task.readSource(overlay)
+		if err != nil {
+			return
+		}
+		root = mergeNodes("", root, overlayRoot)
+	}
+
+	// Check the document against the schema:
+	data := &fileData{}
+	if root != nil {
+		validationErr := validateDocument(file, task.origins, root)
+		if validationErr != nil {
+			err = validationErr
+			return
+		}
+
+		// Decode the data:
+		err = root.Decode(data)
+		if err != nil {
+			var prefix string
+			if file != "" {
+				prefix = fmt.Sprintf("failed to decode file '%s'", file)
+			} else {
+				prefix = "failed to decode YAML"
+			}
+			typeErr, ok := err.(*yaml.TypeError)
+			if ok {
+				err = fmt.Errorf("%s, %s", prefix, typeErr.Errors[0])
+			} else {
+				err = fmt.Errorf("%s: %w", prefix, err)
+			}
+			return
+		}
+	}
+	if data.APIVersion == "" {
+		l.logger.V(1).Info(
+			"Configuration doesn't specify version, will assume the first one",
+			"version", APIVersionV1,
+		)
+		data.APIVersion = APIVersionV1
+	}
+
+	// Apply the defaults:
+	applyDefaults(data)
+
+	result = data
+	return
+}
+
+// loaderTask contains the state of one execution of the loader, in particular the files that are
+// being read, so that include loops can be detected.
+type loaderTask struct {
+	logger  logr.Logger
+	origins map[*yaml.Node]string
+	stack   []string
+}
+
+// readSource reads the given source and returns the root node of the document, after expanding the
+// includes. It also returns the name of the file, which will be empty if the source isn't a file.
+func (t *loaderTask) readSource(source any) (root *yaml.Node, file string, err error) {
+	switch typed := source.(type) {
 	case string:
-		result, err = l.loadFromString(typed)
+		ext := filepath.Ext(typed)
+		switch strings.ToLower(ext) {
+		case ".yaml", ".yml":
+			file = typed
+			root, err = t.readFile(typed)
+		default:
+			root, err = t.readReader(strings.NewReader(typed), "")
+		}
 	case []byte:
-		result, err = l.loadFromBytes(typed)
+		root, err = t.readReader(bytes.NewBuffer(typed), "")
 	case io.Reader:
-		result, err = l.loadFromReader(typed, "")
+		root, err = t.readReader(typed, "")
 	}
 	return
 }
 
-func (l *Loader) loadFromString(source string) (result map[string]any, err error) {
-	ext := filepath.Ext(source)
-	switch strings.ToLower(ext) {
-	case ".yaml", ".yml":
-		result, err = l.loadFromFile(source)
-	default:
-		result, err = l.loadFromBytes([]byte(source))
+func (t *loaderTask) readFile(file string) (result *yaml.Node, err error) {
+	// Check that we aren't already reading this file, as that would be an infinite loop:
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return
 	}
-	return
-}
+	if slices.Contains(t.stack, abs) {
+		err = fmt.Errorf(
+			"file '%s' includes itself via %s",
+			file, strings.Join(append(t.stack, abs), " -> "),
+		)
+		return
+	}
+	t.stack = append(t.stack, abs)
+	defer func() {
+		t.stack = t.stack[0 : len(t.stack)-1]
+	}()
 
-func (l *Loader) loadFromFile(file string) (result map[string]any, err error) {
 	reader, err := os.Open(file)
 	if errors.Is(err, os.ErrNotExist) {
 		err = fmt.Errorf("file '%s' doesn't exist", file)
@@ -197,53 +297,143 @@ func (l *Loader) loadFromFile(file string) (result map[string]any, err error) {
 	if err != nil {
 		return
 	}
-	result, err = l.loadFromReader(reader, file)
-	return
-}
-
-func (l *Loader) loadFromBytes(data []byte) (result map[string]any, err error) {
-	result, err = l.loadFromReader(bytes.NewBuffer(data), "")
+	defer reader.Close()
+	result, err = t.readReader(reader, file)
 	return
 }
 
-func (l *Loader) loadFromReader(reader io.Reader, file string) (result map[string]any, err error) {
+func (t *loaderTask) readReader(reader io.Reader, file string) (result *yaml.Node, err error) {
+	// Parse the YAML text preserving the positions, so that we can report them in case of
+	// errors:
+	var doc yaml.Node
 	decoder := yaml.NewDecoder(reader)
-	err = decoder.Decode(&result)
+	err = decoder.Decode(&doc)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
 	if err != nil {
-		var prefix string
 		if file != "" {
-			prefix = fmt.Sprintf("failed to parse file '%s'", file)
-		} else {
-			prefix = "failed to parse YAML"
-		}
-		typeErr, ok := err.(*yaml.TypeError)
-		if ok {
-			err = fmt.Errorf("%s, %s", prefix, typeErr.Errors[0])
+			err = fmt.Errorf("failed to parse file '%s': %w", file, err)
 		} else {
-			err = fmt.Errorf("%s: %w", prefix, err)
+			err = fmt.Errorf("failed to parse YAML: %w", err)
 		}
+		return
 	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+
+	// Remember where the nodes come from:
+	if file != "" {
+		t.setOrigin(root, file)
+	}
+
+	// Load the included files. They are the base, and the content of this file is merged on
+	// top of them.
+	result, err = t.expandIncludes(root, file)
 	return
 }
 
-func (l *Loader) loadProperties(content any, config *models.Config) error {
-	return l.jq.Query(`.config`, content, &config.Properties)
+func (t *loaderTask) setOrigin(node *yaml.Node, file string) {
+	if node == nil {
+		return
+	}
+	_, seen := t.origins[node]
+	if seen {
+		return
+	}
+	t.origins[node] = file
+	for _, child := range node.Content {
+		t.setOrigin(child, file)
+	}
 }
 
-func (l *Loader) loadClusters(content any, config *models.Config) error {
-	var data []map[string]any
-	err := l.jq.Query(`.edgeclusters`, content, &data)
-	if err != nil {
-		return err
+func (t *loaderTask) expandIncludes(root *yaml.Node, file string) (result *yaml.Node, err error) {
+	result = root
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+	var includes *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "include" {
+			includes = root.Content[i+1]
+			root.Content = slices.Delete(root.Content, i, i+2)
+			break
+		}
 	}
-	for _, item := range data {
+	if includes == nil {
+		return
+	}
+	if includes.Kind != yaml.SequenceNode {
+		err = &ValidationError{
+			Problems: []*Problem{{
+				File:    file,
+				Path:    "include",
+				Line:    includes.Line,
+				Column:  includes.Column,
+				Message: "expected a list of file names",
+			}},
+		}
+		return
+	}
+	dir := "."
+	if file != "" {
+		dir = filepath.Dir(file)
+	}
+	var base *yaml.Node
+	for _, include := range includes.Content {
+		if include.Kind != yaml.ScalarNode {
+			err = &ValidationError{
+				Problems: []*Problem{{
+					File:    file,
+					Path:    "include",
+					Line:    include.Line,
+					Column:  include.Column,
+					Message: "expected a file name",
+				}},
+			}
+			return
+		}
+		name := include.Value
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		t.logger.V(1).Info(
+			"Including configuration file",
+			"file", file,
+			"include", name,
+		)
+		var included *yaml.Node
+		included, err = t.readFile(name)
+		if err != nil {
+			return
+		}
+		base = mergeNodes("", base, included)
+	}
+	result = mergeNodes("", base, root)
+	return
+}
+
+func (l *Loader) loadProperties(data *fileData, config *models.Config) error {
+	config.Properties = maps.Clone(data.Config)
+	if config.Properties == nil {
+		config.Properties = map[string]string{}
+	}
+	return nil
+}
+
+func (l *Loader) loadClusters(data *fileData, config *models.Config) error {
+	for _, item := range data.EdgeClusters {
 		for name, value := range item {
 			cluster := &models.Cluster{
 				Name: name,
 			}
-			err = l.loadCluster(value, cluster)
-			if err != nil {
-				return err
+			if value != nil {
+				err := l.loadCluster(value, cluster)
+				if err != nil {
+					return err
+				}
 			}
 			config.Clusters = append(config.Clusters, cluster)
 		}
@@ -254,30 +444,25 @@ func (l *Loader) loadClusters(content any, config *models.Config) error {
 	return nil
 }
 
-func (l *Loader) loadCluster(content any, cluster *models.Cluster) error {
-	var data map[string]any
-	err := l.jq.Query(`.`, content, &data)
-	if err != nil {
-		return err
+func (l *Loader) loadCluster(data *clusterData, cluster *models.Cluster) error {
+	if data.Config != nil {
+		err := l.loadClusterConfig(data.Config, cluster)
+		if err != nil {
+			return err
+		}
 	}
-	for name, value := range data {
-		switch {
-		case name == "contrib":
-		case name == "config":
-			err = l.loadClusterConfig(value, cluster)
-			if err != nil {
-				return err
-			}
-		default:
-			node := &models.Node{
-				Name: name,
-			}
-			err = l.loadNode(value, node)
-			if err != nil {
-				return err
-			}
-			cluster.Nodes = append(cluster.Nodes, node)
+	for name, value := range data.Nodes {
+		node := &models.Node{
+			Name: name,
 		}
+		if value == nil {
+			value = &nodeData{}
+		}
+		err := l.loadNode(value, node)
+		if err != nil {
+			return err
+		}
+		cluster.Nodes = append(cluster.Nodes, node)
 	}
 	sort.Slice(cluster.Nodes, func(i, j int) bool {
 		return strings.Compare(cluster.Nodes[i].Name, cluster.Nodes[j].Name) < 0
@@ -285,11 +470,10 @@ func (l *Loader) loadCluster(content any, cluster *models.Cluster) error {
 	return nil
 }
 
-func (l *Loader) loadClusterConfig(content any, cluster *models.Cluster) error {
-	var data configData
-	err := l.jq.Query(".", content, &data)
-	if err != nil {
-		return err
+func (l *Loader) loadClusterConfig(data *configData, cluster *models.Cluster) error {
+	// Architecture:
+	if data.Architecture != nil {
+		cluster.Architecture = models.Architecture(*data.Architecture)
 	}
 
 	// TPM:
@@ -297,22 +481,98 @@ func (l *Loader) loadClusterConfig(content any, cluster *models.Cluster) error {
 		cluster.TPM = *data.TPM
 	}
 
+	// Networks:
+	for _, item := range data.ClusterNetwork {
+		cidr, err := l.parseCIDR(item.CIDR)
+		if err != nil {
+			return err
+		}
+		network := &models.ClusterNetwork{
+			CIDR: cidr,
+		}
+		if item.HostPrefix != nil {
+			network.HostPrefix = *item.HostPrefix
+		}
+		cluster.ClusterNetworks = append(cluster.ClusterNetworks, network)
+	}
+	for _, item := range data.MachineNetwork {
+		cidr, err := l.parseCIDR(item.CIDR)
+		if err != nil {
+			return err
+		}
+		cluster.MachineNetworks = append(cluster.MachineNetworks, &models.MachineNetwork{
+			CIDR: cidr,
+		})
+	}
+	for _, item := range data.ServiceNetwork {
+		cidr, err := l.parseCIDR(item.CIDR)
+		if err != nil {
+			return err
+		}
+		cluster.ServiceNetworks = append(cluster.ServiceNetworks, &models.ServiceNetwork{
+			CIDR: cidr,
+		})
+	}
+
+	// Virtual IP addresses:
+	if data.APIVIP != nil {
+		cluster.API.InternalIP = net.ParseIP(*data.APIVIP)
+	}
+	if data.IngressVIP != nil {
+		cluster.Ingress.InternalIP = net.ParseIP(*data.IngressVIP)
+	}
+
+	// External IP addresses:
+	if data.APIExtIP != nil {
+		cluster.API.ExternalIP = net.ParseIP(*data.APIExtIP)
+	}
+	if data.IngressExtIP != nil {
+		cluster.Ingress.ExternalIP = net.ParseIP(*data.IngressExtIP)
+	}
+
+	// Pull secret:
+	if data.PullSecret != nil {
+		pullSecret, err := models.ParseSecret(*data.PullSecret)
+		if err != nil {
+			return err
+		}
+		cluster.PullSecretRef = pullSecret
+	}
+
 	return nil
 }
 
-func (l *Loader) loadNode(content any, node *models.Node) error {
-	var data nodeData
-	err := l.jq.Query(".", content, &data)
-	if err != nil {
-		return err
+func (l *Loader) parseCIDR(value *string) (result *net.IPNet, err error) {
+	if value == nil {
+		err = errors.New("CIDR is mandatory")
+		return
 	}
+	_, result, err = net.ParseCIDR(*value)
+	return
+}
 
-	// Kind:
-	switch {
-	case controlNodeRE.MatchString(node.Name):
-		node.Kind = models.NodeKindControlPlane
-	case workerNodeRE.MatchString(node.Name):
-		node.Kind = models.NodeKindWorker
+func (l *Loader) loadNode(data *nodeData, node *models.Node) error {
+	// Kind. The explicit role takes precedence, and when it isn't given we fall back to guessing
+	// it from the name of the node, which is what older configurations relied on:
+	if data.Role != nil {
+		switch *data.Role {
+		case "control-plane", "master":
+			node.Kind = models.NodeKindControlPlane
+		case "worker":
+			node.Kind = models.NodeKindWorker
+		}
+	} else {
+		switch {
+		case controlNodeRE.MatchString(node.Name):
+			node.Kind = models.NodeKindControlPlane
+		case workerNodeRE.MatchString(node.Name):
+			node.Kind = models.NodeKindWorker
+		}
+	}
+
+	// Hostname:
+	if data.Hostname != nil {
+		node.Hostname = *data.Hostname
 	}
 
 	// BMC:
@@ -320,10 +580,18 @@ func (l *Loader) loadNode(content any, node *models.Node) error {
 		node.BMC.URL = *data.BMCURL
 	}
 	if data.BMCUser != nil {
-		node.BMC.User = *data.BMCUser
+		user, err := models.ParseSecret(*data.BMCUser)
+		if err != nil {
+			return err
+		}
+		node.BMC.User = user
 	}
 	if data.BMCPass != nil {
-		node.BMC.Pass = *data.BMCPass
+		pass, err := models.ParseSecret(*data.BMCPass)
+		if err != nil {
+			return err
+		}
+		node.BMC.Pass = pass
 	}
 	if data.RootDisk != nil {
 		node.RootDisk = *data.RootDisk
@@ -351,6 +619,41 @@ func (l *Loader) loadNode(content any, node *models.Node) error {
 	if node.ExternalNIC != nil && data.MACExtDHCP != nil {
 		node.ExternalNIC.MAC = *data.MACExtDHCP
 	}
+	if data.ExtBond != nil {
+		node.ExternalBond = l.loadBond(data.ExtBond)
+	}
+	if data.ExtVLAN != nil {
+		node.ExternalVLAN = &models.VLAN{}
+		if data.ExtVLAN.ID != nil {
+			node.ExternalVLAN.ID = *data.ExtVLAN.ID
+		}
+		if data.ExtVLAN.Name != nil {
+			node.ExternalVLAN.Name = *data.ExtVLAN.Name
+		} else {
+			node.ExternalVLAN.Name = fmt.Sprintf(
+				"%s.%d",
+				node.ExternalBase(), node.ExternalVLAN.ID,
+			)
+		}
+	}
+	if data.ExtIP != nil {
+		address, network, err := net.ParseCIDR(*data.ExtIP)
+		if err != nil {
+			return err
+		}
+		prefix, _ := network.Mask.Size()
+		node.ExternalIP = &models.IP{
+			Address: address,
+			Prefix:  prefix,
+		}
+		node.ExternalStatic = true
+	}
+	if data.ExtGateway != nil {
+		node.ExternalGateway = net.ParseIP(*data.ExtGateway)
+	}
+	for _, text := range data.ExtDNS {
+		node.ExternalDNS = append(node.ExternalDNS, net.ParseIP(text))
+	}
 
 	// Ignored NICs:
 	if data.IgnoreIfaces != nil {
@@ -365,6 +668,35 @@ func (l *Loader) loadNode(content any, node *models.Node) error {
 	return nil
 }
 
+func (l *Loader) loadBond(data *bondData) *models.Bond {
+	bond := &models.Bond{}
+	if data.Name != nil {
+		bond.Name = *data.Name
+	}
+	if data.Mode != nil {
+		bond.Mode = *data.Mode
+	} else {
+		bond.Mode = "802.3ad"
+	}
+	if len(data.Options) > 0 {
+		bond.Options = map[string]string{}
+		for name, value := range data.Options {
+			bond.Options[name] = fmt.Sprint(value)
+		}
+	}
+	for _, member := range data.Members {
+		nic := &models.NIC{}
+		if member.NIC != nil {
+			nic.Name = *member.NIC
+		}
+		if member.MAC != nil {
+			nic.MAC = *member.MAC
+		}
+		bond.Members = append(bond.Members, nic)
+	}
+	return bond
+}
+
 var (
 	controlNodeRE = regexp.MustCompile(`^master\d+$`)
 	workerNodeRE  = regexp.MustCompile(`^worker\d+$`)
@@ -15,6 +15,7 @@ License.
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 
@@ -89,8 +90,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
 		Expect(node.ExternalNIC.MAC).To(Equal("63:ed:8b:f1:15:4c"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/d5405874-a05e-44bd-a6e1-f7105d6ed932"))
-		Expect(node.BMC.User).To(Equal("user0"))
-		Expect(node.BMC.Pass).To(Equal("pass0"))
+		Expect(node.BMC.User.Value()).To(Equal("user0"))
+		Expect(node.BMC.Pass.Value()).To(Equal("pass0"))
 		Expect(node.RootDisk).To(Equal("/dev/vda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/vdb"))
 	})
@@ -170,8 +171,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
 		Expect(node.ExternalNIC.MAC).To(Equal("df:2e:74:9e:2a:87"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/0487a8a0-bf51-460e-8ecd-729349f95125"))
-		Expect(node.BMC.User).To(Equal("user0"))
-		Expect(node.BMC.Pass).To(Equal("pass0"))
+		Expect(node.BMC.User.Value()).To(Equal("user0"))
+		Expect(node.BMC.Pass.Value()).To(Equal("pass0"))
 		Expect(node.RootDisk).To(Equal("/dev/vda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/vdb"))
 
@@ -182,8 +183,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
 		Expect(node.ExternalNIC.MAC).To(Equal("dd:c5:f6:18:8f:ac"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/e3563c0a-59ca-4af6-90e5-04b655921e08"))
-		Expect(node.BMC.User).To(Equal("user1"))
-		Expect(node.BMC.Pass).To(Equal("pass1"))
+		Expect(node.BMC.User.Value()).To(Equal("user1"))
+		Expect(node.BMC.Pass.Value()).To(Equal("pass1"))
 		Expect(node.RootDisk).To(Equal("/dev/vda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/vdb"))
 
@@ -194,8 +195,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
 		Expect(node.ExternalNIC.MAC).To(Equal("83:8e:3f:38:bc:87"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/48f72d2a-aea8-4cc4-9c1e-b92d35ad35ad"))
-		Expect(node.BMC.User).To(Equal("user2"))
-		Expect(node.BMC.Pass).To(Equal("pass2"))
+		Expect(node.BMC.User.Value()).To(Equal("user2"))
+		Expect(node.BMC.Pass.Value()).To(Equal("pass2"))
 		Expect(node.RootDisk).To(Equal("/dev/vda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/vdb"))
 
@@ -206,8 +207,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
 		Expect(node.ExternalNIC.MAC).To(Equal("e6:19:4d:a8:82:58"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia+http://192.168.122.1:8000/redfish/v1/Systems/b12af704-a17f-4e4f-8b1a-d4771ebc5bd4"))
-		Expect(node.BMC.User).To(Equal("user3"))
-		Expect(node.BMC.Pass).To(Equal("pass3"))
+		Expect(node.BMC.User.Value()).To(Equal("user3"))
+		Expect(node.BMC.Pass.Value()).To(Equal("pass3"))
 		Expect(node.RootDisk).To(Equal("/dev/vda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/vdb"))
 	})
@@ -302,8 +303,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.InternalNIC.Name).To(Equal("enp0s4"))
 		Expect(node.InternalNIC.MAC).To(Equal("8d:5c:ec:5c:db:20"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia://192.168.123.1:8000/redfish/v1/Systems/3cfeb445-248d-46d3-bc86-15969ebd5245"))
-		Expect(node.BMC.User).To(Equal("admin"))
-		Expect(node.BMC.Pass).To(Equal("password"))
+		Expect(node.BMC.User.Value()).To(Equal("admin"))
+		Expect(node.BMC.Pass.Value()).To(Equal("password"))
 		Expect(node.RootDisk).To(Equal("/dev/sda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/sdb", "/dev/sdc", "/dev/sdd"))
 
@@ -316,8 +317,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.InternalNIC.Name).To(Equal("enp0s4"))
 		Expect(node.InternalNIC.MAC).To(Equal("f9:21:50:8f:9d:68"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia://192.168.123.1:8000/redfish/v1/Systems/5aa364f7-151c-4de9-8902-5958299e97d3"))
-		Expect(node.BMC.User).To(Equal("admin"))
-		Expect(node.BMC.Pass).To(Equal("password"))
+		Expect(node.BMC.User.Value()).To(Equal("admin"))
+		Expect(node.BMC.Pass.Value()).To(Equal("password"))
 		Expect(node.RootDisk).To(Equal("/dev/sda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/sdb", "/dev/sdc", "/dev/sdd"))
 
@@ -330,8 +331,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.InternalNIC.Name).To(Equal("enp0s4"))
 		Expect(node.InternalNIC.MAC).To(Equal("e7:d3:33:60:e8:d0"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia://192.168.123.1:8000/redfish/v1/Systems/c2183827-1f60-473e-a5ee-5f587619a0b0"))
-		Expect(node.BMC.User).To(Equal("admin"))
-		Expect(node.BMC.Pass).To(Equal("password"))
+		Expect(node.BMC.User.Value()).To(Equal("admin"))
+		Expect(node.BMC.Pass.Value()).To(Equal("password"))
 		Expect(node.RootDisk).To(Equal("/dev/sda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/sdb", "/dev/sdc", "/dev/sdd"))
 
@@ -344,8 +345,8 @@ var _ = Describe("Loader", func() {
 		Expect(node.InternalNIC.Name).To(Equal("enp0s4"))
 		Expect(node.InternalNIC.MAC).To(Equal("f5:92:66:41:f1:38"))
 		Expect(node.BMC.URL).To(Equal("redfish-virtualmedia://192.168.123.1:8000/redfish/v1/Systems/9e0a017a-00d1-495f-a4c1-4edd5f5ec78f"))
-		Expect(node.BMC.User).To(Equal("admin"))
-		Expect(node.BMC.Pass).To(Equal("password"))
+		Expect(node.BMC.User.Value()).To(Equal("admin"))
+		Expect(node.BMC.Pass.Value()).To(Equal("password"))
 		Expect(node.RootDisk).To(Equal("/dev/sda"))
 		Expect(node.StorageDisks).To(ConsistOf("/dev/sdb", "/dev/sdc", "/dev/sdd"))
 	})
@@ -428,4 +429,696 @@ var _ = Describe("Loader", func() {
 		Expect(config.Clusters).To(HaveLen(1))
 		Expect(config.Clusters[0].Name).To(Equal("my"))
 	})
+
+	It("Accepts explicit version", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				apiVersion: ztpfw/v1
+				edgeclusters:
+				- my: {}
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveLen(1))
+	})
+
+	It("Rejects unsupported version", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				apiVersion: ztpfw/v2
+				edgeclusters:
+				- my: {}
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(1))
+		problem := validationErr.Problems[0]
+		Expect(problem.Path).To(Equal("apiVersion"))
+		Expect(problem.Line).To(Equal(2))
+		Expect(problem.Message).To(ContainSubstring("ztpfw/v2"))
+		Expect(problem.Message).To(ContainSubstring("ztpfw/v1"))
+	})
+
+	It("Rejects unknown field with location and suggestion", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				      bmc_usr: "user0"
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(1))
+		problem := validationErr.Problems[0]
+		Expect(problem.Path).To(Equal("edgeclusters[0].my.master0.bmc_usr"))
+		Expect(problem.Line).To(Equal(6))
+		Expect(problem.Message).To(ContainSubstring("did you mean 'bmc_user'"))
+	})
+
+	It("Reports all problems at once", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      tpm: "yes"
+				    master0:
+				      mac_ext_dhcp: "aa:ss:dd:ee:b0:10"
+				      root_disk: vda
+				      junk: 123
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(4))
+		Expect(validationErr.Problems[0].Path).To(Equal("edgeclusters[0].my.config.tpm"))
+		Expect(validationErr.Problems[0].Line).To(Equal(5))
+		Expect(validationErr.Problems[0].Message).To(ContainSubstring("expected a boolean"))
+		Expect(validationErr.Problems[1].Path).To(Equal("edgeclusters[0].my.master0.mac_ext_dhcp"))
+		Expect(validationErr.Problems[1].Line).To(Equal(7))
+		Expect(validationErr.Problems[1].Message).To(ContainSubstring("MAC address"))
+		Expect(validationErr.Problems[2].Path).To(Equal("edgeclusters[0].my.master0.root_disk"))
+		Expect(validationErr.Problems[2].Line).To(Equal(8))
+		Expect(validationErr.Problems[3].Path).To(Equal("edgeclusters[0].my.master0.junk"))
+		Expect(validationErr.Problems[3].Line).To(Equal(9))
+		Expect(err.Error()).To(ContainSubstring("found 4 problems"))
+	})
+
+	It("Validates without loading", func() {
+		err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      mac_ext_dhcp: "junk"
+			`)).
+			Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("5:21 'edgeclusters[0].my.master0.mac_ext_dhcp'"))
+	})
+	It("Takes node role and hostname from explicit fields", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    node-a:
+				      role: control-plane
+				      hostname: host-a.example.com
+				    node-b:
+				      role: master
+				    master2:
+				      role: worker
+				    worker3: {}
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		Expect(cluster.Nodes).To(HaveLen(4))
+		master2 := cluster.LookupNode("master2")
+		Expect(master2.Kind).To(Equal(models.NodeKindWorker))
+		Expect(master2.Hostname).To(BeEmpty())
+		nodeA := cluster.LookupNode("node-a")
+		Expect(nodeA.Kind).To(Equal(models.NodeKindControlPlane))
+		Expect(nodeA.Hostname).To(Equal("host-a.example.com"))
+		nodeB := cluster.LookupNode("node-b")
+		Expect(nodeB.Kind).To(Equal(models.NodeKindControlPlane))
+		worker3 := cluster.LookupNode("worker3")
+		Expect(worker3.Kind).To(Equal(models.NodeKindWorker))
+	})
+
+	It("Rejects invalid node role and hostname", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    node-a:
+				      role: boss
+				      hostname: Host_A
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(2))
+		Expect(validationErr.Problems[0].Path).To(Equal("edgeclusters[0].my.node-a.role"))
+		Expect(validationErr.Problems[0].Message).To(ContainSubstring("'control-plane'"))
+		Expect(validationErr.Problems[1].Path).To(Equal("edgeclusters[0].my.node-a.hostname"))
+		Expect(validationErr.Problems[1].Message).To(ContainSubstring("host name"))
+	})
+
+	It("Loads the architecture of the cluster", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      architecture: aarch64
+				- your: {}
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveLen(2))
+		Expect(config.Clusters[0].Architecture).To(Equal(models.ArchitectureAARCH64))
+		Expect(config.Clusters[1].Architecture).To(BeEmpty())
+	})
+
+	It("Rejects unsupported architecture", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      architecture: arm64
+			`)).
+			Load()
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(1))
+		Expect(validationErr.Problems[0].Path).To(Equal("edgeclusters[0].my.config.architecture"))
+		Expect(validationErr.Problems[0].Message).To(ContainSubstring("'aarch64'"))
+	})
+
+	It("Loads cluster networks and virtual IP addresses", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      cluster_network:
+				      - cidr: 10.132.0.0/14
+				        host_prefix: 24
+				      machine_network:
+				      - cidr: 192.168.8.0/24
+				      service_network:
+				      - cidr: 172.31.0.0/16
+				      api_vip: 192.168.8.243
+				      ingress_vip: 192.168.8.242
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		Expect(cluster.ClusterNetworks).To(HaveLen(1))
+		Expect(cluster.ClusterNetworks[0].CIDR.String()).To(Equal("10.132.0.0/14"))
+		Expect(cluster.ClusterNetworks[0].HostPrefix).To(Equal(24))
+		Expect(cluster.MachineNetworks).To(HaveLen(1))
+		Expect(cluster.MachineNetworks[0].CIDR.String()).To(Equal("192.168.8.0/24"))
+		Expect(cluster.ServiceNetworks).To(HaveLen(1))
+		Expect(cluster.ServiceNetworks[0].CIDR.String()).To(Equal("172.31.0.0/16"))
+		Expect(cluster.API.InternalIP.String()).To(Equal("192.168.8.243"))
+		Expect(cluster.Ingress.InternalIP.String()).To(Equal("192.168.8.242"))
+	})
+
+	It("Loads external IP addresses", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      api_ext_ip: 192.168.150.100
+				      ingress_ext_ip: 192.168.150.101
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		Expect(cluster.API.ExternalIP.String()).To(Equal("192.168.150.100"))
+		Expect(cluster.Ingress.ExternalIP.String()).To(Equal("192.168.150.101"))
+	})
+
+	It("Rejects invalid networks", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      machine_network:
+				      - cidr: 192.168.8.0/33
+				      service_network:
+				      - host_prefix: 23
+				      api_vip: junk
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(4))
+		Expect(validationErr.Problems[0].Message).To(ContainSubstring("isn't a valid CIDR"))
+		Expect(validationErr.Problems[1].Message).To(Equal("unknown field 'host_prefix'"))
+		Expect(validationErr.Problems[2].Message).To(Equal("field 'cidr' is mandatory"))
+		Expect(validationErr.Problems[3].Message).To(ContainSubstring("isn't a valid IP"))
+	})
+
+	It("Loads static external addressing", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				      ext_ip: 192.168.150.100/24
+				      ext_gateway: 192.168.150.1
+				      ext_dns:
+				      - 192.168.150.2
+				      - 192.168.150.3
+				    master1:
+				      nic_ext_dhcp: enp1s0
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		master0 := cluster.LookupNode("master0")
+		Expect(master0.ExternalStatic).To(BeTrue())
+		Expect(master0.ExternalIP.String()).To(Equal("192.168.150.100/24"))
+		Expect(master0.ExternalGateway.String()).To(Equal("192.168.150.1"))
+		Expect(master0.ExternalDNS).To(HaveLen(2))
+		Expect(master0.ExternalDNS[0].String()).To(Equal("192.168.150.2"))
+		Expect(master0.ExternalDNS[1].String()).To(Equal("192.168.150.3"))
+		master1 := cluster.LookupNode("master1")
+		Expect(master1.ExternalStatic).To(BeFalse())
+		Expect(master1.ExternalIP).To(BeNil())
+	})
+
+	It("Rejects invalid static external addressing", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      ext_ip: 192.168.150.100
+				      ext_gateway: junk
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(2))
+		Expect(validationErr.Problems[0].Path).To(Equal("edgeclusters[0].my.master0.ext_ip"))
+		Expect(validationErr.Problems[0].Message).To(ContainSubstring("prefix length"))
+		Expect(validationErr.Problems[1].Path).To(Equal("edgeclusters[0].my.master0.ext_gateway"))
+		Expect(validationErr.Problems[1].Message).To(ContainSubstring("isn't a valid IP"))
+	})
+
+	It("Loads external bond and VLAN", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      ext_bond:
+				        name: bond0
+				        options:
+				          miimon: 100
+				        members:
+				        - nic: enp1s0
+				          mac: aa:bb:cc:ee:b0:10
+				        - nic: enp2s0
+				          mac: aa:bb:cc:ee:b0:11
+				      ext_vlan:
+				        id: 100
+				    master1:
+				      nic_ext_dhcp: enp1s0
+				      mac_ext_dhcp: aa:bb:cc:ee:b1:10
+				      ext_vlan:
+				        id: 200
+				        name: external
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		master0 := cluster.LookupNode("master0")
+		Expect(master0.ExternalNIC).To(BeNil())
+		Expect(master0.ExternalBond).ToNot(BeNil())
+		Expect(master0.ExternalBond.Name).To(Equal("bond0"))
+		Expect(master0.ExternalBond.Mode).To(Equal("802.3ad"))
+		Expect(master0.ExternalBond.Options).To(Equal(map[string]string{
+			"miimon": "100",
+		}))
+		Expect(master0.ExternalBond.Members).To(HaveLen(2))
+		Expect(master0.ExternalBond.Members[0].Name).To(Equal("enp1s0"))
+		Expect(master0.ExternalBond.Members[0].MAC).To(Equal("aa:bb:cc:ee:b0:10"))
+		Expect(master0.ExternalBond.Members[1].Name).To(Equal("enp2s0"))
+		Expect(master0.ExternalBond.Members[1].MAC).To(Equal("aa:bb:cc:ee:b0:11"))
+		Expect(master0.ExternalVLAN).ToNot(BeNil())
+		Expect(master0.ExternalVLAN.ID).To(Equal(100))
+		Expect(master0.ExternalVLAN.Name).To(Equal("bond0.100"))
+		Expect(master0.ExternalInterface()).To(Equal("bond0.100"))
+		Expect(master0.ExternalMAC()).To(Equal("aa:bb:cc:ee:b0:10"))
+		master1 := cluster.LookupNode("master1")
+		Expect(master1.ExternalBond).To(BeNil())
+		Expect(master1.ExternalVLAN.ID).To(Equal(200))
+		Expect(master1.ExternalVLAN.Name).To(Equal("external"))
+		Expect(master1.ExternalInterface()).To(Equal("external"))
+	})
+
+	It("Rejects invalid external bond", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      ext_bond:
+				        mode: lacp
+				        members:
+				        - nic: enp1s0
+				      ext_vlan:
+				        id: junk
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(4))
+		Expect(validationErr.Problems[0].Message).To(Equal("field 'name' is mandatory"))
+		Expect(validationErr.Problems[1].Path).To(Equal("edgeclusters[0].my.master0.ext_bond.mode"))
+		Expect(validationErr.Problems[2].Message).To(Equal("field 'mac' is mandatory"))
+		Expect(validationErr.Problems[3].Path).To(Equal("edgeclusters[0].my.master0.ext_vlan.id"))
+	})
+
+	It("Loads secret references without resolving them", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    config:
+				      pull_secret: file:/etc/ztp/pull-secret.json
+				    master0:
+				      bmc_user: env:BMC_USER
+				      bmc_pass: secret:my-ns/my-bmc#password
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := config.Clusters[0]
+		Expect(cluster.PullSecretRef).ToNot(BeNil())
+		Expect(cluster.PullSecretRef.Source).To(Equal(models.SecretSourceFile))
+		Expect(cluster.PullSecretRef.File).To(Equal("/etc/ztp/pull-secret.json"))
+		Expect(cluster.PullSecretRef.Resolved()).To(BeFalse())
+		node := cluster.LookupNode("master0")
+		Expect(node.BMC.User.Source).To(Equal(models.SecretSourceEnv))
+		Expect(node.BMC.User.Env).To(Equal("BMC_USER"))
+		Expect(node.BMC.User.Resolved()).To(BeFalse())
+		Expect(node.BMC.Pass.Source).To(Equal(models.SecretSourceKube))
+		Expect(node.BMC.Pass.Namespace).To(Equal("my-ns"))
+		Expect(node.BMC.Pass.Name).To(Equal("my-bmc"))
+		Expect(node.BMC.Pass.Key).To(Equal("password"))
+		Expect(node.BMC.Pass.Resolved()).To(BeFalse())
+	})
+
+	It("Rejects invalid secret references", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      bmc_user: "env:"
+				      bmc_pass: secret:my-bmc
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(2))
+		Expect(validationErr.Problems[0].Path).To(Equal("edgeclusters[0].my.master0.bmc_user"))
+		Expect(validationErr.Problems[1].Path).To(Equal("edgeclusters[0].my.master0.bmc_pass"))
+		Expect(validationErr.Problems[1].Message).To(ContainSubstring("secret:namespace/name#key"))
+	})
+
+	It("Loads included files relative to the including file", func() {
+		tmp, _ := TmpFS(
+			"site.yaml",
+			text.Dedent(`
+				include:
+				- common/base.yaml
+				edgeclusters:
+				- my:
+				    master0:
+				      mac_ext_dhcp: "aa:bb:cc:dd:ee:01"
+			`),
+			"common/base.yaml",
+			text.Dedent(`
+				config:
+				  OC_OCP_VERSION: '4.11.20'
+				edgeclusters:
+				- my:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				      mac_ext_dhcp: "aa:bb:cc:dd:ee:00"
+			`),
+		)
+		defer func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(filepath.Join(tmp, "site.yaml")).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue("OC_OCP_VERSION", "4.11.20"))
+		Expect(config.Clusters).To(HaveLen(1))
+		Expect(config.Clusters[0].Nodes).To(HaveLen(1))
+		node := config.Clusters[0].Nodes[0]
+		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
+		Expect(node.ExternalNIC.MAC).To(Equal("aa:bb:cc:dd:ee:01"))
+	})
+
+	It("Rejects include loops", func() {
+		tmp, _ := TmpFS(
+			"a.yaml", "include: [b.yaml]",
+			"b.yaml", "include: [a.yaml]",
+		)
+		defer func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(filepath.Join(tmp, "a.yaml")).
+			Load()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("includes itself"))
+	})
+
+	It("Reports the file of the problem when it is in an included file", func() {
+		tmp, _ := TmpFS(
+			"site.yaml",
+			text.Dedent(`
+				include:
+				- base.yaml
+			`),
+			"base.yaml",
+			text.Dedent(`
+				edgeclusters:
+				- my:
+				    master0:
+				      mac_ext_dhcp: junk
+			`),
+		)
+		defer func() {
+			err := os.RemoveAll(tmp)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(filepath.Join(tmp, "site.yaml")).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(1))
+		problem := validationErr.Problems[0]
+		Expect(problem.File).To(Equal(filepath.Join(tmp, "base.yaml")))
+		Expect(problem.Line).To(Equal(5))
+	})
+
+	It("Applies defaults to nodes and clusters", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				defaults:
+				  config:
+				    tpm: true
+				  nodes:
+				    bmc_user: "env:BMC_USER"
+				    root_disk: /dev/vda
+				    ignore_ifaces: eno1 eno2
+				edgeclusters:
+				- my:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				    master1:
+				      root_disk: /dev/sda
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveLen(1))
+		cluster := config.Clusters[0]
+		Expect(cluster.TPM).To(BeTrue())
+		Expect(cluster.Nodes).To(HaveLen(2))
+		for _, node := range cluster.Nodes {
+			Expect(node.BMC.User).ToNot(BeNil())
+			Expect(node.BMC.User.Env).To(Equal("BMC_USER"))
+			Expect(node.IgnoredNICs).To(ConsistOf("eno1", "eno2"))
+		}
+		Expect(cluster.LookupNode("master0").RootDisk).To(Equal("/dev/vda"))
+		Expect(cluster.LookupNode("master1").RootDisk).To(Equal("/dev/sda"))
+	})
+
+	It("Doesn't share default values between nodes", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				defaults:
+				  config:
+				    cluster_network:
+				    - cidr: 10.128.0.0/14
+				  nodes:
+				    storage_disk:
+				    - /dev/vdb
+				edgeclusters:
+				- first:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				- second:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveLen(2))
+		first := config.Clusters[0]
+		second := config.Clusters[1]
+		Expect(first.ClusterNetworks).To(HaveLen(1))
+		Expect(second.ClusterNetworks).To(HaveLen(1))
+		Expect(first.ClusterNetworks[0]).ToNot(BeIdenticalTo(second.ClusterNetworks[0]))
+		first.Nodes[0].StorageDisks[0] = "/dev/sdb"
+		Expect(second.Nodes[0].StorageDisks).To(ConsistOf("/dev/vdb"))
+	})
+
+	It("Rejects defaults for fields that identify nodes and clusters", func() {
+		_, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				defaults:
+				  config:
+				    api_vip: 192.168.7.242
+				  nodes:
+				    hostname: my-host
+				    mac_ext_dhcp: aa:ss:dd:ee:b0:10
+				edgeclusters:
+				- my:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+			`)).
+			Load()
+		Expect(err).To(HaveOccurred())
+		var validationErr *ValidationError
+		Expect(errors.As(err, &validationErr)).To(BeTrue())
+		Expect(validationErr.Problems).To(HaveLen(3))
+		problem := validationErr.Problems[0]
+		Expect(problem.Path).To(Equal("defaults.config.api_vip"))
+		Expect(problem.Line).To(Equal(4))
+		Expect(problem.Column).To(Equal(5))
+		Expect(problem.Message).To(Equal(
+			"field 'api_vip' can't have a default value, it must be set explicitly",
+		))
+		problem = validationErr.Problems[1]
+		Expect(problem.Path).To(Equal("defaults.nodes.hostname"))
+		Expect(problem.Line).To(Equal(6))
+		problem = validationErr.Problems[2]
+		Expect(problem.Path).To(Equal("defaults.nodes.mac_ext_dhcp"))
+		Expect(problem.Line).To(Equal(7))
+		Expect(problem.Message).To(Equal(
+			"field 'mac_ext_dhcp' can't have a default value, it must be set explicitly",
+		))
+	})
+
+	It("Merges overlays on top of the source", func() {
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetSource(text.Dedent(`
+				config:
+				  OC_OCP_VERSION: '4.11.20'
+				  OC_ACM_VERSION: '2.6'
+				edgeclusters:
+				- first:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+				      root_disk: /dev/vda
+				      storage_disk:
+				      - /dev/vdb
+				      - /dev/vdc
+				- second:
+				    master0:
+				      nic_ext_dhcp: enp1s0
+			`)).
+			AddOverlay(text.Dedent(`
+				config:
+				  OC_OCP_VERSION: '4.12.1'
+				  OC_ACM_VERSION: null
+				edgeclusters:
+				- first:
+				    master0:
+				      root_disk: /dev/sda
+				      storage_disk:
+				      - /dev/sdb
+				- third:
+				    master0:
+				      nic_ext_dhcp: enp2s0
+			`)).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(Equal(map[string]string{
+			"OC_OCP_VERSION": "4.12.1",
+		}))
+		Expect(config.Clusters).To(HaveLen(3))
+		Expect(config.Clusters[0].Name).To(Equal("first"))
+		Expect(config.Clusters[1].Name).To(Equal("second"))
+		Expect(config.Clusters[2].Name).To(Equal("third"))
+		node := config.Clusters[0].Nodes[0]
+		Expect(node.ExternalNIC.Name).To(Equal("enp1s0"))
+		Expect(node.RootDisk).To(Equal("/dev/sda"))
+		Expect(node.StorageDisks).To(Equal([]string{"/dev/sdb"}))
+	})
+
+	It("Loads overlays from --config-overlay", func() {
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		AddFlags(flags)
+		err := flags.Parse([]string{
+			"--config", "edgeclusters: [{my: {master0: {root_disk: /dev/vda}}}]",
+			"--config-overlay", "edgeclusters: [{my: {master0: {root_disk: /dev/vdb}}}]",
+			"--config-overlay", "edgeclusters: [{my: {master0: {root_disk: /dev/vdc}}}]",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		config, err := NewLoader().
+			SetLogger(logger).
+			SetFlags(flags).
+			Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveLen(1))
+		Expect(config.Clusters[0].Nodes[0].RootDisk).To(Equal("/dev/vdc"))
+	})
 })
@@ -0,0 +1,235 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeNodes merges the overlay YAML node on top of the base node and returns the result. The
+// rules are the following:
+//
+// - If one of the nodes is nil the result is the other one.
+//
+// - Mappings are merged key by key, recursively. Keys that are only in the base or only in the
+// overlay are preserved. An explicit `null` in the overlay removes the key from the result.
+//
+// - The items of the `edgeclusters` list are matched by cluster name. Matching clusters are merged
+// recursively, and clusters that are only in the overlay are added at the end.
+//
+// - Any other list, scalar or combination of different kinds of nodes is replaced by the value in
+// the overlay.
+//
+// The base node is modified and reused, so it shouldn't be used after calling this function. The
+// path is the location of the nodes inside the document, using the same syntax that the validator
+// uses, and it is used to decide what lists need special treatment.
+func mergeNodes(path string, base, overlay *yaml.Node) *yaml.Node {
+	base = mergeResolve(base)
+	overlay = mergeResolve(overlay)
+	if base == nil {
+		return overlay
+	}
+	if overlay == nil {
+		return base
+	}
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		return mergeMappings(path, base, overlay)
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode &&
+		path == "edgeclusters":
+		return mergeClusters(path, base, overlay)
+	default:
+		return overlay
+	}
+}
+
+func mergeMappings(path string, base, overlay *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key := overlay.Content[i]
+		value := overlay.Content[i+1]
+		index := mergeFindKey(base, key.Value)
+		if mergeIsNull(value) {
+			if index >= 0 {
+				base.Content = append(base.Content[0:index], base.Content[index+2:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			base.Content = append(base.Content, key, value)
+			continue
+		}
+		base.Content[index+1] = mergeNodes(
+			mergeJoin(path, key.Value),
+			base.Content[index+1],
+			value,
+		)
+	}
+	return base
+}
+
+func mergeClusters(path string, base, overlay *yaml.Node) *yaml.Node {
+	for _, item := range overlay.Content {
+		item = mergeResolve(item)
+		name := mergeClusterName(item)
+		index := -1
+		if name != "" {
+			for i, candidate := range base.Content {
+				if mergeClusterName(mergeResolve(candidate)) == name {
+					index = i
+					break
+				}
+			}
+		}
+		if index < 0 {
+			base.Content = append(base.Content, item)
+			continue
+		}
+		base.Content[index] = mergeNodes(path, base.Content[index], item)
+	}
+	return base
+}
+
+// mergeClusterName returns the name of the cluster contained in the given item of the
+// `edgeclusters` list, or an empty string if the item doesn't have the expected structure.
+func mergeClusterName(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return ""
+	}
+	return node.Content[0].Value
+}
+
+func mergeFindKey(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mergeIsNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// mergeResolve follows aliases, so that the merge sees the actual content.
+func mergeResolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func mergeJoin(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// applyDefaults copies the values of the `defaults` section to the clusters and nodes that don't
+// specify them explicitly.
+func applyDefaults(data *fileData) {
+	defaults := data.Defaults
+	if defaults == nil {
+		return
+	}
+	for _, item := range data.EdgeClusters {
+		for _, cluster := range item {
+			if cluster == nil {
+				continue
+			}
+			if defaults.Config != nil {
+				if cluster.Config == nil {
+					cluster.Config = &configData{}
+				}
+				applyDefaultFields(cluster.Config, defaults.Config)
+			}
+			if defaults.Nodes != nil {
+				for name, node := range cluster.Nodes {
+					if node == nil {
+						node = &nodeData{}
+						cluster.Nodes[name] = node
+					}
+					applyDefaultFields(node, defaults.Nodes)
+				}
+			}
+		}
+	}
+}
+
+// applyDefaultFields copies the fields of the defaults struct to the fields with the same name of
+// the target struct that are nil. Both parameters must be pointers to structs, the fields of the
+// defaults struct must also exist in the target struct, with the same type, and all of them must be
+// pointers, slices or maps. The values are cloned, so that targets don't share them.
+func applyDefaultFields(target, defaults any) {
+	targetValue := reflect.ValueOf(target).Elem()
+	defaultsValue := reflect.ValueOf(defaults).Elem()
+	defaultsType := defaultsValue.Type()
+	for i := 0; i < defaultsValue.NumField(); i++ {
+		field := targetValue.FieldByName(defaultsType.Field(i).Name)
+		if field.IsNil() {
+			field.Set(cloneValue(defaultsValue.Field(i)))
+		}
+	}
+}
+
+// cloneValue returns a deep copy of the given value.
+func cloneValue(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.New(value.Type().Elem())
+		result.Elem().Set(cloneValue(value.Elem()))
+		return result
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(cloneValue(value.Index(i)))
+		}
+		return result
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iterator := value.MapRange()
+		for iterator.Next() {
+			result.SetMapIndex(iterator.Key(), cloneValue(iterator.Value()))
+		}
+		return result
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.New(value.Type()).Elem()
+		result.Set(cloneValue(value.Elem()))
+		return result
+	case reflect.Struct:
+		result := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			result.Field(i).Set(cloneValue(value.Field(i)))
+		}
+		return result
+	default:
+		return value
+	}
+}
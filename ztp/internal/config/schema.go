@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// This file contains the types that describe the format of the configuration file. The loader
+// checks the YAML document against these types before decoding it, so the tags are the single
+// source of truth for the names of the fields that are accepted.
+//
+// Besides the usual `json` and `yaml` tags fields can have a `format` tag that indicates that the
+// value must have a specific format. The supported formats are `mac` for MAC addresses, `path`
+// for absolute file system paths, `hostname` for DNS host names, `ip` for IP addresses, `ipprefix`
+// for IP addresses with a prefix length like `192.168.7.10/24` and `cidr` for network ranges like
+// `192.168.7.0/24`. The `secret` format is for security sensitive values that can also be given as
+// references like `env:VAR`, `file:/path` or `secret:ns/name#key`, see models.Secret for details.
+// Fields can also have an `enum` tag containing the comma
+// separated list of values that are accepted, and a `required:"true"` tag to indicate that they
+// can't be omitted. The `description` tag is a short human readable explanation of the field that
+// is included in the JSON schema generated by the JSONSchema function.
+
+// APIVersionV1 is the first version of the configuration format. Files that don't contain the
+// `apiVersion` field are assumed to use this version.
+const APIVersionV1 = "ztpfw/v1"
+
+// apiVersions contains the versions of the configuration format supported by the loader.
+var apiVersions = []string{
+	APIVersionV1,
+}
+
+// fileData is used internally to parse the complete configuration file.
+type fileData struct {
+	APIVersion   string                    `json:"apiVersion,omitempty" yaml:"apiVersion" description:"Version of the configuration format."`
+	Include      []string                  `json:"include,omitempty" yaml:"include" description:"Files that are loaded first and then merged with the rest of this file. Relative names are relative to the directory of this file."`
+	Config       map[string]string         `json:"config,omitempty" yaml:"config" description:"Global properties, like the OpenShift and ACM versions."`
+	Defaults     *defaultsData             `json:"defaults,omitempty" yaml:"defaults" description:"Values used by clusters and nodes that don't specify them explicitly."`
+	EdgeClusters []map[string]*clusterData `json:"edgeclusters,omitempty" yaml:"edgeclusters" description:"List of edge clusters. Each item contains one field, the name of the cluster."`
+}
+
+// defaultsData is used internally to parse the values that are used when the clusters or the nodes
+// don't specify them explicitly.
+type defaultsData struct {
+	Config *defaultsConfigData `json:"config,omitempty" yaml:"config" description:"Default cluster configuration."`
+	Nodes  *defaultsNodeData   `json:"nodes,omitempty" yaml:"nodes" description:"Default node configuration."`
+}
+
+// defaultsConfigData is used internally to parse the default configuration of the clusters. It
+// contains only the fields of configData that can be shared by multiple clusters, not the addresses
+// that identify each cluster. The names and types of the fields must be the same than in
+// configData.
+type defaultsConfigData struct {
+	Architecture   *string               `json:"architecture,omitempty" yaml:"architecture" enum:"x86_64,aarch64" description:"CPU architecture of the nodes."`
+	TPM            *bool                 `json:"tpm,omitempty" yaml:"tpm" description:"Enables disk encryption using the TPM."`
+	ClusterNetwork []*clusterNetworkData `json:"cluster_network,omitempty" yaml:"cluster_network" description:"Networks used for pod IP addresses."`
+	MachineNetwork []*networkData        `json:"machine_network,omitempty" yaml:"machine_network" description:"Networks used for node IP addresses."`
+	ServiceNetwork []*networkData        `json:"service_network,omitempty" yaml:"service_network" description:"Networks used for service IP addresses."`
+	PullSecret     *string               `json:"pull_secret,omitempty" yaml:"pull_secret" format:"secret" description:"Pull secret, or reference to it."`
+}
+
+// defaultsNodeData is used internally to parse the default configuration of the nodes. It contains
+// only the fields of nodeData that can be shared by multiple nodes, not the roles, names or
+// addresses that identify each node. The names and types of the fields must be the same than in
+// nodeData.
+type defaultsNodeData struct {
+	BMCPass      *string   `json:"bmc_pass,omitempty" yaml:"bmc_pass" format:"secret" description:"BMC password, or reference to it."`
+	BMCUser      *string   `json:"bmc_user,omitempty" yaml:"bmc_user" format:"secret" description:"BMC user name, or reference to it."`
+	ExtDNS       []string  `json:"ext_dns,omitempty" yaml:"ext_dns" format:"ip" description:"DNS servers used with the static external address."`
+	ExtGateway   *string   `json:"ext_gateway,omitempty" yaml:"ext_gateway" format:"ip" description:"Gateway used with the static external address."`
+	ExtVLAN      *vlanData `json:"ext_vlan,omitempty" yaml:"ext_vlan" description:"VLAN used as external interface, on top of the NIC or bond."`
+	IgnoreIfaces *string   `json:"ignore_ifaces,omitempty" yaml:"ignore_ifaces" description:"Space separated list of NICs that will be ignored."`
+	NICExtDHCP   *string   `json:"nic_ext_dhcp,omitempty" yaml:"nic_ext_dhcp" description:"Name of the external NIC."`
+	NICIntStatic *string   `json:"nic_int_static,omitempty" yaml:"nic_int_static" description:"Name of the internal NIC."`
+	RootDisk     *string   `json:"root_disk,omitempty" yaml:"root_disk" format:"path" description:"Disk where the operating system will be installed."`
+	StorageDisk  []string  `json:"storage_disk,omitempty" yaml:"storage_disk" format:"path" description:"Disks used for storage."`
+}
+
+// clusterData is used internally to parse the data of a cluster. Note that the nodes aren't inside
+// a specific field, they are the rest of the fields of the cluster.
+type clusterData struct {
+	Config  *configData          `json:"config,omitempty" yaml:"config" description:"Cluster configuration."`
+	Contrib map[string]any       `json:"contrib,omitempty" yaml:"contrib" description:"Configuration of additional components."`
+	Nodes   map[string]*nodeData `json:"-" yaml:",inline" description:"Nodes of the cluster. The name of the field is the name of the node."`
+}
+
+// configData is used internally to parse the data of a cluster.
+type configData struct {
+	Architecture   *string               `json:"architecture,omitempty" yaml:"architecture" enum:"x86_64,aarch64" description:"CPU architecture of the nodes. When omitted the value of the OC_OCP_ARCH property is used, and if that isn't set either then x86_64."`
+	TPM            *bool                 `json:"tpm,omitempty" yaml:"tpm" description:"Enables disk encryption using the TPM."`
+	ClusterNetwork []*clusterNetworkData `json:"cluster_network,omitempty" yaml:"cluster_network" description:"Networks used for pod IP addresses."`
+	MachineNetwork []*networkData        `json:"machine_network,omitempty" yaml:"machine_network" description:"Networks used for node IP addresses."`
+	ServiceNetwork []*networkData        `json:"service_network,omitempty" yaml:"service_network" description:"Networks used for service IP addresses."`
+	APIVIP         *string               `json:"api_vip,omitempty" yaml:"api_vip" format:"ip" description:"Virtual IP address of the API server."`
+	IngressVIP     *string               `json:"ingress_vip,omitempty" yaml:"ingress_vip" format:"ip" description:"Virtual IP address of the ingress router."`
+	APIExtIP       *string               `json:"api_ext_ip,omitempty" yaml:"api_ext_ip" format:"ip" description:"External IP address of the API server. When omitted it is obtained resolving the 'api' name of the cluster."`
+	IngressExtIP   *string               `json:"ingress_ext_ip,omitempty" yaml:"ingress_ext_ip" format:"ip" description:"External IP address of the ingress router. When omitted it is obtained resolving the 'apps' name of the cluster."`
+	PullSecret     *string               `json:"pull_secret,omitempty" yaml:"pull_secret" format:"secret" description:"Pull secret, or reference to it."`
+}
+
+// clusterNetworkData is used internally to parse the data of a cluster network.
+type clusterNetworkData struct {
+	CIDR       *string `json:"cidr" yaml:"cidr" format:"cidr" required:"true" description:"Network range."`
+	HostPrefix *int    `json:"host_prefix,omitempty" yaml:"host_prefix" description:"Prefix length of the range assigned to each node."`
+}
+
+// networkData is used internally to parse the data of a machine or service network.
+type networkData struct {
+	CIDR *string `json:"cidr" yaml:"cidr" format:"cidr" required:"true" description:"Network range."`
+}
+
+// nodeData is used internally to parse the data of a node.
+type nodeData struct {
+	BMCPass      *string   `json:"bmc_pass,omitempty" yaml:"bmc_pass" format:"secret" description:"BMC password, or reference to it."`
+	BMCURL       *string   `json:"bmc_url,omitempty" yaml:"bmc_url" description:"BMC URL."`
+	BMCUser      *string   `json:"bmc_user,omitempty" yaml:"bmc_user" format:"secret" description:"BMC user name, or reference to it."`
+	ExtBond      *bondData `json:"ext_bond,omitempty" yaml:"ext_bond" description:"Bond used as external interface."`
+	ExtDNS       []string  `json:"ext_dns,omitempty" yaml:"ext_dns" format:"ip" description:"DNS servers used with the static external address."`
+	ExtGateway   *string   `json:"ext_gateway,omitempty" yaml:"ext_gateway" format:"ip" description:"Gateway used with the static external address."`
+	ExtIP        *string   `json:"ext_ip,omitempty" yaml:"ext_ip" format:"ipprefix" description:"Static external address, with prefix length."`
+	ExtVLAN      *vlanData `json:"ext_vlan,omitempty" yaml:"ext_vlan" description:"VLAN used as external interface, on top of the NIC or bond."`
+	IgnoreIfaces *string   `json:"ignore_ifaces,omitempty" yaml:"ignore_ifaces" description:"Space separated list of NICs that will be ignored."`
+	MACExtDHCP   *string   `json:"mac_ext_dhcp,omitempty" yaml:"mac_ext_dhcp" format:"mac" description:"MAC address of the external NIC."`
+	MACIntStatic *string   `json:"mac_int_static,omitempty" yaml:"mac_int_static" format:"mac" description:"MAC address of the internal NIC."`
+	NICExtDHCP   *string   `json:"nic_ext_dhcp,omitempty" yaml:"nic_ext_dhcp" description:"Name of the external NIC."`
+	NICIntStatic *string   `json:"nic_int_static,omitempty" yaml:"nic_int_static" description:"Name of the internal NIC."`
+	Role         *string   `json:"role,omitempty" yaml:"role" enum:"control-plane,master,worker" description:"Role of the node. When omitted it is calculated from the name of the node."`
+	Hostname     *string   `json:"hostname,omitempty" yaml:"hostname" format:"hostname" description:"Host name of the node. When omitted the name of the node is used."`
+	RootDisk     *string   `json:"root_disk,omitempty" yaml:"root_disk" format:"path" description:"Disk where the operating system will be installed."`
+	StorageDisk  []string  `json:"storage_disk,omitempty" yaml:"storage_disk" format:"path" description:"Disks used for storage."`
+}
+
+// bondData is used internally to parse the data of a bond interface.
+type bondData struct {
+	Name    *string           `json:"name" yaml:"name" required:"true" description:"Name of the bond interface."`
+	Mode    *string           `json:"mode,omitempty" yaml:"mode" enum:"balance-rr,active-backup,balance-xor,broadcast,802.3ad,balance-tlb,balance-alb" description:"Bonding mode."`
+	Options map[string]any    `json:"options,omitempty" yaml:"options" description:"Bonding options."`
+	Members []*bondMemberData `json:"members" yaml:"members" required:"true" description:"NICs that are members of the bond."`
+}
+
+// bondMemberData is used internally to parse the data of a NIC that is a member of a bond.
+type bondMemberData struct {
+	NIC *string `json:"nic" yaml:"nic" required:"true" description:"Name of the NIC."`
+	MAC *string `json:"mac" yaml:"mac" format:"mac" required:"true" description:"MAC address of the NIC."`
+}
+
+// vlanData is used internally to parse the data of a VLAN sub-interface.
+type vlanData struct {
+	ID   *int    `json:"id" yaml:"id" required:"true" description:"VLAN identifier."`
+	Name *string `json:"name,omitempty" yaml:"name" description:"Name of the VLAN interface. Defaults to the base interface name followed by a dot and the identifier."`
+}
+
+// fieldName returns the name of the given schema field and the options of its `yaml` tag, like
+// `inline`.
+func fieldName(field reflect.StructField) (name string, options []string) {
+	tag, ok := field.Tag.Lookup("yaml")
+	if !ok {
+		name = strings.ToLower(field.Name)
+		return
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	options = parts[1:]
+	return
+}
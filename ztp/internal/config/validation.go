@@ -0,0 +1,462 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// Problem describes a problem found while validating the configuration.
+type Problem struct {
+	// File is the name of the file that contains the problem. It will be empty if the
+	// configuration wasn't loaded from a file.
+	File string
+
+	// Path is the location of the problematic value inside the document, for example
+	// `edgeclusters[0].my-cluster.master0.mac_ext_dhcp`.
+	Path string
+
+	// Line and Column are the position of the problematic value inside the document. Both
+	// start with one. They will be zero for problems that are detected after loading the
+	// configuration, as the position is no longer known at that point.
+	Line   int
+	Column int
+
+	// Message is the human readable description of the problem.
+	Message string
+}
+
+// String generates a human readable representation of the problem, including the location.
+func (p *Problem) String() string {
+	var location []string
+	if p.File != "" {
+		location = append(location, p.File)
+	}
+	if p.Line > 0 {
+		location = append(location, fmt.Sprintf("%d:%d", p.Line, p.Column))
+	}
+	buffer := &strings.Builder{}
+	buffer.WriteString(strings.Join(location, ":"))
+	if p.Path != "" {
+		if buffer.Len() > 0 {
+			buffer.WriteString(" ")
+		}
+		fmt.Fprintf(buffer, "'%s'", p.Path)
+	}
+	if buffer.Len() > 0 {
+		buffer.WriteString(": ")
+	}
+	buffer.WriteString(p.Message)
+	return buffer.String()
+}
+
+// ValidationError is the error returned by the loader when the configuration doesn't conform to
+// the schema. It contains all the problems that were found, not just the first one.
+type ValidationError struct {
+	Problems []*Problem
+}
+
+// Error is the implementation of the error interface.
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("configuration isn't valid: %s", e.Problems[0])
+	}
+	buffer := &strings.Builder{}
+	fmt.Fprintf(
+		buffer,
+		"configuration isn't valid, found %d problems:",
+		len(e.Problems),
+	)
+	for _, problem := range e.Problems {
+		fmt.Fprintf(buffer, "\n  %s", problem)
+	}
+	return buffer.String()
+}
+
+// validator checks a YAML document against the types of the schema. Don't create instances of this
+// type directly, use the validateDocument function instead.
+type validator struct {
+	file     string
+	origins  map[*yaml.Node]string
+	problems []*Problem
+}
+
+// validateDocument checks that the given YAML document conforms to the schema. Returns a
+// ValidationError containing all the problems found, or nil if there are no problems. The origins
+// map contains the files where the nodes come from, when the document is the result of merging
+// several files. Nodes that aren't in that map are assumed to come from the given file.
+func validateDocument(file string, origins map[*yaml.Node]string,
+	doc *yaml.Node) *ValidationError {
+	v := &validator{
+		file:    file,
+		origins: origins,
+	}
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = root.Content[0]
+	}
+	v.checkVersion(root)
+	v.checkValue("", root, reflect.TypeOf(fileData{}), "", "")
+	if len(v.problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].File != v.problems[j].File {
+			return v.problems[i].File < v.problems[j].File
+		}
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+		return v.problems[i].Column < v.problems[j].Column
+	})
+	return &ValidationError{
+		Problems: v.problems,
+	}
+}
+
+func (v *validator) checkVersion(root *yaml.Node) {
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		value := root.Content[i+1]
+		if key.Value != "apiVersion" || value.Kind != yaml.ScalarNode {
+			continue
+		}
+		if !slices.Contains(apiVersions, value.Value) {
+			v.report(
+				"apiVersion", value,
+				"version '%s' isn't supported, it should be %s",
+				value.Value, logging.Any(apiVersions),
+			)
+		}
+	}
+}
+
+func (v *validator) checkValue(path string, node *yaml.Node, typ reflect.Type, format,
+	enum string) {
+	// Follow aliases and pointers:
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	// Null values are acceptable for all the types, they will result in the zero value:
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		v.checkStruct(path, node, typ)
+	case reflect.Map:
+		v.checkMap(path, node, typ, format, enum)
+	case reflect.Slice:
+		v.checkSlice(path, node, typ, format, enum)
+	case reflect.Interface:
+		// Any value is acceptable.
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.report(path, node, "expected a string, but found %s", v.describe(node))
+			return
+		}
+		v.checkFormat(path, node, format)
+		v.checkEnum(path, node, enum)
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.report(path, node, "expected a boolean, but found %s", v.describe(node))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.report(path, node, "expected an integer, but found %s", v.describe(node))
+		}
+	}
+}
+
+func (v *validator) checkStruct(path string, node *yaml.Node, typ reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		v.report(path, node, "expected an object, but found %s", v.describe(node))
+		return
+	}
+	fields := map[string]reflect.StructField{}
+	var inline *reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, options := fieldName(field)
+		if slices.Contains(options, "inline") {
+			inline = &field
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	present := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		name := key.Value
+		present[name] = true
+		field, ok := fields[name]
+		switch {
+		case ok:
+			v.checkValue(
+				v.join(path, name), value, field.Type,
+				field.Tag.Get("format"), field.Tag.Get("enum"),
+			)
+		case inline != nil:
+			v.checkValue(v.join(path, name), value, inline.Type.Elem(), "", "")
+		case v.hasField(validatorDefaultsTypes[typ], name):
+			v.report(
+				v.join(path, name), key,
+				"field '%s' can't have a default value, it must be set explicitly",
+				name,
+			)
+		default:
+			suggestion := v.suggest(name, maps.Keys(fields))
+			if suggestion != "" {
+				v.report(
+					v.join(path, name), key,
+					"unknown field '%s', did you mean '%s'?",
+					name, suggestion,
+				)
+			} else {
+				v.report(v.join(path, name), key, "unknown field '%s'", name)
+			}
+		}
+	}
+	names := maps.Keys(fields)
+	sort.Strings(names)
+	for _, name := range names {
+		field := fields[name]
+		if field.Tag.Get("required") == "true" && !present[name] {
+			v.report(path, node, "field '%s' is mandatory", name)
+		}
+	}
+}
+
+func (v *validator) checkMap(path string, node *yaml.Node, typ reflect.Type, format,
+	enum string) {
+	if node.Kind != yaml.MappingNode {
+		v.report(path, node, "expected an object, but found %s", v.describe(node))
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		v.checkValue(v.join(path, key.Value), value, typ.Elem(), format, enum)
+	}
+}
+
+func (v *validator) checkSlice(path string, node *yaml.Node, typ reflect.Type, format,
+	enum string) {
+	if node.Kind != yaml.SequenceNode {
+		v.report(path, node, "expected a list, but found %s", v.describe(node))
+		return
+	}
+	for i, item := range node.Content {
+		v.checkValue(fmt.Sprintf("%s[%d]", path, i), item, typ.Elem(), format, enum)
+	}
+}
+
+func (v *validator) checkFormat(path string, node *yaml.Node, format string) {
+	value := node.Value
+	switch format {
+	case "mac":
+		_, err := net.ParseMAC(value)
+		if err != nil {
+			v.report(path, node, "value '%s' isn't a valid MAC address", value)
+		}
+	case "path":
+		if !strings.HasPrefix(value, "/") {
+			v.report(path, node, "value '%s' isn't an absolute path", value)
+		}
+	case "ip":
+		if net.ParseIP(value) == nil {
+			v.report(path, node, "value '%s' isn't a valid IP address", value)
+		}
+	case "ipprefix":
+		address, _, err := net.ParseCIDR(value)
+		if err != nil || address == nil {
+			v.report(
+				path, node,
+				"value '%s' isn't a valid IP address with prefix length, like "+
+					"'192.168.122.10/24'",
+				value,
+			)
+		}
+	case "cidr":
+		_, _, err := net.ParseCIDR(value)
+		if err != nil {
+			v.report(path, node, "value '%s' isn't a valid CIDR", value)
+		}
+	case "secret":
+		_, err := models.ParseSecret(value)
+		if err != nil {
+			v.report(path, node, "%v", err)
+		}
+	case "hostname":
+		if !hostnameRE.MatchString(value) || len(value) > 253 {
+			v.report(path, node, "value '%s' isn't a valid host name", value)
+		}
+	}
+}
+
+func (v *validator) checkEnum(path string, node *yaml.Node, enum string) {
+	if enum == "" {
+		return
+	}
+	values := strings.Split(enum, ",")
+	if !slices.Contains(values, node.Value) {
+		v.report(
+			path, node,
+			"value '%s' isn't valid, it should be %s",
+			node.Value, logging.Any(values),
+		)
+	}
+}
+
+func (v *validator) describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!bool":
+			return fmt.Sprintf("boolean '%s'", node.Value)
+		case "!!int", "!!float":
+			return fmt.Sprintf("number '%s'", node.Value)
+		default:
+			return fmt.Sprintf("string '%s'", node.Value)
+		}
+	default:
+		return "an unknown value"
+	}
+}
+
+// hasField checks if the given struct type has a field with the given schema name. Returns false
+// if the type is nil.
+func (v *validator) hasField(typ reflect.Type, name string) bool {
+	if typ == nil {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		current, _ := fieldName(typ.Field(i))
+		if current == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// suggest returns the name from the given list that is closest to the given name, or an empty
+// string if none of them is close enough.
+func (v *validator) suggest(name string, names []string) string {
+	sort.Strings(names)
+	best := ""
+	distance := 3
+	for _, candidate := range names {
+		current := v.distance(name, candidate)
+		if current < distance {
+			best = candidate
+			distance = current
+		}
+	}
+	return best
+}
+
+// distance calculates the Levenshtein distance between two strings.
+func (v *validator) distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j] + 1
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func (v *validator) report(path string, node *yaml.Node, format string, args ...any) {
+	file, ok := v.origins[node]
+	if !ok {
+		file = v.file
+	}
+	v.problems = append(v.problems, &Problem{
+		File:    file,
+		Path:    path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// hostnameRE is the regular expression used to check host names. It accepts lower case labels
+// separated by dots, as required by RFC 1123 and by the Kubernetes object names where the host
+// names are used.
+var hostnameRE = regexp.MustCompile(
+	`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?(\.[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?)*$`,
+)
+
+// validatorDefaultsTypes contains the types used for the `defaults` section, and the corresponding
+// complete types. This is used to explain that fields that are in the complete type but not in the
+// defaults type can't have default values.
+var validatorDefaultsTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(defaultsConfigData{}): reflect.TypeOf(configData{}),
+	reflect.TypeOf(defaultsNodeData{}):   reflect.TypeOf(nodeData{}),
+}
@@ -15,11 +15,13 @@ License.
 package internal
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
@@ -34,6 +36,7 @@ type ConsoleBuilder struct {
 	logger logr.Logger
 	color  bool
 	mute   bool
+	output ConsoleOutput
 	out    io.Writer
 	err    io.Writer
 }
@@ -44,6 +47,7 @@ type Console struct {
 	logger   logr.Logger
 	lock     *sync.Mutex
 	mute     bool
+	output   ConsoleOutput
 	prefixes consolePrefixes
 	out      io.Writer
 	err      io.Writer
@@ -52,7 +56,8 @@ type Console struct {
 // NewConsole creates a builder that can then be used to configure and create a console.
 func NewConsole() *ConsoleBuilder {
 	return &ConsoleBuilder{
-		color: true,
+		color:  true,
+		output: ConsoleOutputText,
 	}
 }
 
@@ -82,6 +87,14 @@ func (b *ConsoleBuilder) SetMute(value bool) *ConsoleBuilder {
 	return b
 }
 
+// SetOutput sets the format of the output. The default is ConsoleOutputText, which writes human
+// friendly messages. With ConsoleOutputJSONL all the messages and events are written to the
+// standard output stream as JSON objects, one per line, so that other tools can consume them.
+func (b *ConsoleBuilder) SetOutput(value ConsoleOutput) *ConsoleBuilder {
+	b.output = value
+	return b
+}
+
 // SetOut sets the standard output stream. This is mandatory, but will be ignored if the console is
 // muted.
 func (b *ConsoleBuilder) SetOut(value io.Writer) *ConsoleBuilder {
@@ -111,6 +124,12 @@ func (b *ConsoleBuilder) SetFlags(flags *pflag.FlagSet) *ConsoleBuilder {
 			b.SetMute(value)
 		}
 	}
+	if flags.Changed(consoleOutputFlag) {
+		value, err := flags.GetString(consoleOutputFlag)
+		if err == nil {
+			b.SetOutput(ConsoleOutput(value))
+		}
+	}
 	return b
 }
 
@@ -129,6 +148,15 @@ func (b *ConsoleBuilder) Build() (result *Console, err error) {
 		err = errors.New("standard error stream is mandatory")
 		return
 	}
+	switch b.output {
+	case ConsoleOutputText, ConsoleOutputJSONL:
+	default:
+		err = fmt.Errorf(
+			"output format '%s' isn't valid, it should be '%s' or '%s'",
+			b.output, ConsoleOutputText, ConsoleOutputJSONL,
+		)
+		return
+	}
 
 	// Check if the ouptput is a terminal:
 	terminal := b.isTerminal(b.out) && b.isTerminal(b.err)
@@ -144,6 +172,7 @@ func (b *ConsoleBuilder) Build() (result *Console, err error) {
 		logger:   b.logger,
 		lock:     &sync.Mutex{},
 		mute:     b.mute,
+		output:   b.output,
 		prefixes: prefixes,
 		out:      b.out,
 		err:      b.err,
@@ -164,7 +193,9 @@ func (c *Console) Info(format string, args ...any) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	text := fmt.Sprintf(format, c.replaceArgs(args)...)
-	if !c.mute {
+	if c.output == ConsoleOutputJSONL {
+		c.writeMessage(consoleInfoLevel, text)
+	} else if !c.mute {
 		fmt.Fprintf(c.out, "%s%s\n", c.prefixes.info, text)
 	}
 	c.logger.Info("Console info", "text", text)
@@ -175,7 +206,9 @@ func (c *Console) Warn(format string, args ...any) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	text := fmt.Sprintf(format, c.replaceArgs(args)...)
-	if !c.mute {
+	if c.output == ConsoleOutputJSONL {
+		c.writeMessage(consoleWarnLevel, text)
+	} else if !c.mute {
 		fmt.Fprintf(c.out, "%s%s\n", c.prefixes.warn, text)
 	}
 	c.logger.Info("Console warn", "text", text)
@@ -186,12 +219,78 @@ func (c *Console) Error(format string, args ...any) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	text := fmt.Sprintf(format, c.replaceArgs(args)...)
-	if !c.mute {
+	if c.output == ConsoleOutputJSONL {
+		c.writeMessage(consoleErrorLevel, text)
+	} else if !c.mute {
 		fmt.Fprintf(c.err, "%s%s\n", c.prefixes.error, text)
 	}
 	c.logger.Info("Console error", "text", text)
 }
 
+// Print writes the given text to the console as is, without any prefix. This is intended for
+// multi-line output, like the differences between objects.
+func (c *Console) Print(text string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.output == ConsoleOutputJSONL {
+		c.writeMessage(consoleInfoLevel, text)
+	} else if !c.mute {
+		fmt.Fprint(c.out, text)
+	}
+	c.logger.Info("Console print", "text", text)
+}
+
+// Output returns the format of the output of the console.
+func (c *Console) Output() ConsoleOutput {
+	return c.output
+}
+
+// Event writes the given event to the console. Events are only written when the output format is
+// ConsoleOutputJSONL, otherwise they are only written to the log. If the time of the event isn't
+// set it will be set to the current time.
+func (c *Console) Event(event *ConsoleEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if c.output == ConsoleOutputJSONL {
+		c.writeJSON(event)
+	}
+	c.logger.V(2).Info(
+		"Console event",
+		"source", event.Source,
+		"type", event.Type,
+		"cluster", event.Cluster,
+	)
+}
+
+func (c *Console) writeMessage(level string, text string) {
+	c.writeJSON(&ConsoleEvent{
+		Time:    time.Now().UTC(),
+		Source:  ConsoleSourceConsole,
+		Type:    ConsoleMessageEvent,
+		Level:   level,
+		Message: text,
+	})
+}
+
+func (c *Console) writeJSON(event *ConsoleEvent) {
+	if c.mute {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		c.logger.Error(err, "Failed to serialize console event")
+		return
+	}
+	data = append(data, '\n')
+	_, err = c.out.Write(data)
+	if err != nil {
+		c.logger.Error(err, "Failed to write console event")
+	}
+}
+
 func (c *Console) replaceArgs(args []any) []any {
 	result := make([]any, len(args))
 	for i, arg := range args {
@@ -215,6 +314,59 @@ func (c *Console) replaceArg(arg any) any {
 	}
 }
 
+// ConsoleOutput is the format of the output of the console.
+type ConsoleOutput string
+
+const (
+	// ConsoleOutputText writes human friendly messages.
+	ConsoleOutputText ConsoleOutput = "text"
+
+	// ConsoleOutputJSONL writes messages and events as JSON objects, one per line.
+	ConsoleOutputJSONL ConsoleOutput = "jsonl"
+)
+
+// ConsoleEvent is the representation of a progress event written by the console when the output
+// format is ConsoleOutputJSONL.
+type ConsoleEvent struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	Type       string    `json:"type"`
+	Level      string    `json:"level,omitempty"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Group      string    `json:"group,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	Kind       string    `json:"kind,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Stage      string    `json:"stage,omitempty"`
+	Step       string    `json:"step,omitempty"`
+	Objects    []string  `json:"objects,omitempty"`
+	Changes    []string  `json:"changes,omitempty"`
+	Finalizers []string  `json:"finalizers,omitempty"`
+	Elapsed    string    `json:"elapsed,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Sources of console events:
+const (
+	ConsoleSourceConsole  = "console"
+	ConsoleSourceApplier  = "applier"
+	ConsoleSourceWaiter   = "waiter"
+	ConsoleSourceEnricher = "enricher"
+)
+
+// ConsoleMessageEvent is the type of the events that contain the messages written with the Info,
+// Warn, Error and Print methods.
+const ConsoleMessageEvent = "Message"
+
+// Levels of the messages:
+const (
+	consoleInfoLevel  = "info"
+	consoleWarnLevel  = "warn"
+	consoleErrorLevel = "error"
+)
+
 // consolePrefixes stores the prefixes used for messages.
 type consolePrefixes struct {
 	info  string
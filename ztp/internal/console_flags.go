@@ -15,6 +15,8 @@ License.
 package internal
 
 import (
+	"fmt"
+
 	"github.com/spf13/pflag"
 )
 
@@ -31,10 +33,20 @@ func AddConsoleFlags(set *pflag.FlagSet) {
 		true,
 		"Enables or disables writing to the console.",
 	)
+	_ = set.String(
+		consoleOutputFlag,
+		string(ConsoleOutputText),
+		fmt.Sprintf(
+			"Format of the output. Use '%s' for human friendly messages or '%s' to write "+
+				"messages and progress events as JSON objects, one per line.",
+			ConsoleOutputText, ConsoleOutputJSONL,
+		),
+	)
 }
 
 // Names of the flags:
 const (
-	consoleColorFlag = "color"
-	consoleMuteFlag  = "mute"
+	consoleColorFlag  = "color"
+	consoleMuteFlag   = "mute"
+	consoleOutputFlag = "output"
 )
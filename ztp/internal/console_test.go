@@ -79,6 +79,20 @@ var _ = Describe("Console", func() {
 			Expect(msg).To(ContainSubstring("error"))
 			Expect(msg).To(ContainSubstring("mandatory"))
 		})
+
+		It("Can't be created with an unknown output format", func() {
+			console, err := NewConsole().
+				SetLogger(logger).
+				SetOut(io.Discard).
+				SetErr(io.Discard).
+				SetOutput("xml").
+				Build()
+			Expect(console).To(BeNil())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(
+				"output format 'xml' isn't valid, it should be 'text' or 'jsonl'",
+			))
+		})
 	})
 
 	Describe("Usage", func() {
@@ -95,6 +109,68 @@ var _ = Describe("Console", func() {
 			Expect(buffer.String()).To(MatchRegexp(`(?m:^I: Hello!\n$)`))
 		})
 
+		It("Writes messages and events as JSON lines", func() {
+			buffer := &bytes.Buffer{}
+			multi := io.MultiWriter(buffer, GinkgoWriter)
+			console, err := NewConsole().
+				SetLogger(logger).
+				SetOut(multi).
+				SetErr(io.Discard).
+				SetOutput(ConsoleOutputJSONL).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			console.Error("Hello!")
+			console.Event(&ConsoleEvent{
+				Source: ConsoleSourceApplier,
+				Type:   "ObjectCreated",
+				Kind:   "Namespace",
+				Name:   "my-ns",
+			})
+			decoder := json.NewDecoder(buffer)
+			var msg, event ConsoleEvent
+			Expect(decoder.Decode(&msg)).To(Succeed())
+			Expect(msg.Time).ToNot(BeZero())
+			Expect(msg.Source).To(Equal(ConsoleSourceConsole))
+			Expect(msg.Type).To(Equal(ConsoleMessageEvent))
+			Expect(msg.Level).To(Equal("error"))
+			Expect(msg.Message).To(Equal("Hello!"))
+			Expect(decoder.Decode(&event)).To(Succeed())
+			Expect(event.Time).ToNot(BeZero())
+			Expect(event.Source).To(Equal(ConsoleSourceApplier))
+			Expect(event.Type).To(Equal("ObjectCreated"))
+			Expect(event.Kind).To(Equal("Namespace"))
+			Expect(event.Name).To(Equal("my-ns"))
+			Expect(decoder.More()).To(BeFalse())
+		})
+
+		It("Doesn't write events in text mode", func() {
+			buffer := &bytes.Buffer{}
+			console, err := NewConsole().
+				SetLogger(logger).
+				SetOut(buffer).
+				SetErr(buffer).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			console.Event(&ConsoleEvent{
+				Source: ConsoleSourceApplier,
+				Type:   "ObjectCreated",
+			})
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		It("Writes text without prefix to the output", func() {
+			buffer := &bytes.Buffer{}
+			multi := io.MultiWriter(buffer, GinkgoWriter)
+			console, err := NewConsole().
+				SetLogger(logger).
+				SetOut(multi).
+				SetErr(io.Discard).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			console.Print("-x: 1\n+x: 2\n")
+			Expect(buffer.String()).To(Equal("-x: 1\n+x: 2\n"))
+		})
+
 		It("Writes info messages to the log", func() {
 			// Create a logger that writes to a buffer, so that we can inspect the
 			// messages:
@@ -0,0 +1,443 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+)
+
+// CRDDeleterBuilder contains the data and logic needed to create an object that knows how to
+// safely delete custom resource definitions. Don't create instances of this type directly, use the
+// NewCRDDeleter function instead.
+type CRDDeleterBuilder struct {
+	logger    logr.Logger
+	client    clnt.WithWatch
+	listeners []func(*ApplierEvent)
+	timeout   time.Duration
+	force     bool
+}
+
+// CRDDeleter knows how to safely delete custom resource definitions. Before deleting a definition
+// it deletes the objects of that kind and waits till they disappear, so that their finalizers have
+// a chance to run while the controllers that process them still work. Don't create instances of
+// this type directly, use the NewCRDDeleter function instead.
+type CRDDeleter struct {
+	logger    logr.Logger
+	client    clnt.WithWatch
+	listeners []func(*ApplierEvent)
+	timeout   time.Duration
+	force     bool
+}
+
+// NewCRDDeleter creates a builder that can then be used to create an object that knows how to
+// safely delete custom resource definitions.
+func NewCRDDeleter() *CRDDeleterBuilder {
+	return &CRDDeleterBuilder{
+		timeout: crdDeleterDefaultTimeout,
+	}
+}
+
+// SetLogger sets the logger that the deleter will use to write to the log. This is mandatory.
+func (b *CRDDeleterBuilder) SetLogger(value logr.Logger) *CRDDeleterBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the deleter will use. This is mandatory.
+func (b *CRDDeleterBuilder) SetClient(value clnt.WithWatch) *CRDDeleterBuilder {
+	b.client = value
+	return b
+}
+
+// SetListener sets a function that will be called to notify of the progress of the deletion. The
+// events are the same used by the applier, so the applier listener can be used. This is optional.
+func (b *CRDDeleterBuilder) SetListener(value func(*ApplierEvent)) *CRDDeleterBuilder {
+	b.listeners = []func(*ApplierEvent){value}
+	return b
+}
+
+// AddListener adds a function that will be called to notify of the progress of the deletion. This
+// is optional.
+func (b *CRDDeleterBuilder) AddListener(value func(*ApplierEvent)) *CRDDeleterBuilder {
+	b.listeners = append(b.listeners, value)
+	return b
+}
+
+// SetTimeout sets the maximum time to wait for the objects of a custom resource definition to
+// disappear. The default is five minutes.
+func (b *CRDDeleterBuilder) SetTimeout(value time.Duration) *CRDDeleterBuilder {
+	b.timeout = value
+	return b
+}
+
+// SetForce enables or disables the removal of the finalizers of the objects that don't disappear
+// before the timeout expires. Note that this means that the controllers will not have a chance to
+// clean up the resources associated to those objects, so it should only be used when those
+// controllers no longer work. The default is false.
+func (b *CRDDeleterBuilder) SetForce(value bool) *CRDDeleterBuilder {
+	b.force = value
+	return b
+}
+
+// SetFlags sets the command line flags that indicate how to configure the deleter. This is
+// optional.
+func (b *CRDDeleterBuilder) SetFlags(flags *pflag.FlagSet) *CRDDeleterBuilder {
+	if flags.Changed(crdDeleterTimeoutFlagName) {
+		value, err := flags.GetDuration(crdDeleterTimeoutFlagName)
+		if err == nil {
+			b.timeout = value
+		}
+	}
+	if flags.Changed(crdDeleterForceFlagName) {
+		value, err := flags.GetBool(crdDeleterForceFlagName)
+		if err == nil {
+			b.force = value
+		}
+	}
+	return b
+}
+
+// Build uses the data stored in the builder to create a new deleter.
+func (b *CRDDeleterBuilder) Build() (result *CRDDeleter, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.client == nil {
+		err = errors.New("client is mandatory")
+		return
+	}
+	if b.timeout <= 0 {
+		err = fmt.Errorf("timeout should be positive, but it is %s", b.timeout)
+		return
+	}
+
+	// Create and populate the object:
+	result = &CRDDeleter{
+		logger:    b.logger,
+		client:    b.client,
+		listeners: slices.Clone(b.listeners),
+		timeout:   b.timeout,
+		force:     b.force,
+	}
+	return
+}
+
+// DeleteGroup deletes all the custom resource definitions of the given group. Returns the number of
+// definitions deleted.
+func (d *CRDDeleter) DeleteGroup(ctx context.Context, group string) (n int, err error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(CustomResourceDefinitionListGVK)
+	err = d.client.List(ctx, list)
+	if err != nil {
+		return
+	}
+	var crds []*unstructured.Unstructured
+	for i := range list.Items {
+		crd := &list.Items[i]
+		value, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		if value == group {
+			crds = append(crds, crd)
+		}
+	}
+	d.logger.V(1).Info(
+		"CRDs to delete",
+		"group", group,
+		"count", len(crds),
+	)
+	err = d.Delete(ctx, crds...)
+	if err != nil {
+		return
+	}
+	n = len(crds)
+	return
+}
+
+// Delete deletes the given custom resource definitions, after deleting the objects of the kinds
+// that they define and waiting for them to disappear.
+func (d *CRDDeleter) Delete(ctx context.Context, crds ...*unstructured.Unstructured) error {
+	for _, crd := range crds {
+		err := d.deleteCRD(ctx, crd)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CRDDeleter) deleteCRD(ctx context.Context, crd *unstructured.Unstructured) error {
+	// Delete the objects:
+	gvk, err := d.storageGVK(crd)
+	if err != nil {
+		return err
+	}
+	err = d.drainObjects(ctx, crd, gvk)
+	if err != nil {
+		return err
+	}
+
+	// Delete the definition:
+	err = d.client.Delete(ctx, crd)
+	if apierrors.IsNotFound(err) {
+		d.fireInfo(ApplierObjectNotExist, crd)
+		return nil
+	}
+	if err != nil {
+		d.fireError(ApplierDeleteError, crd, err)
+		return err
+	}
+	d.fireInfo(ApplierObjectDeleted, crd)
+	return nil
+}
+
+// storageGVK returns the group, version and kind that should be used to list the objects defined
+// by the given custom resource definition.
+func (d *CRDDeleter) storageGVK(crd *unstructured.Unstructured) (result schema.GroupVersionKind,
+	err error) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	var version string
+	for _, item := range versions {
+		item, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := item["name"].(string)
+		storage, _ := item["storage"].(bool)
+		if version == "" || storage {
+			version = name
+		}
+	}
+	if group == "" || kind == "" || version == "" {
+		err = fmt.Errorf(
+			"failed to find group, version and kind of CRD '%s'",
+			crd.GetName(),
+		)
+		return
+	}
+	result = schema.GroupVersionKind{
+		Group:   group,
+		Version: version,
+		Kind:    kind,
+	}
+	return
+}
+
+// drainObjects deletes all the objects of the given kind and waits till they disappear. If they
+// don't disappear before the timeout and force is enabled it removes their finalizers.
+func (d *CRDDeleter) drainObjects(ctx context.Context, crd *unstructured.Unstructured,
+	gvk schema.GroupVersionKind) error {
+	// Delete the objects that exist now:
+	objects, err := d.listObjects(ctx, gvk)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+	for _, object := range objects {
+		if object.GetDeletionTimestamp() != nil {
+			continue
+		}
+		err = d.client.Delete(ctx, object)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			d.fireError(ApplierDeleteError, object, err)
+			return err
+		}
+		d.fireInfo(ApplierObjectDeleted, object)
+	}
+
+	// Wait for the objects to disappear:
+	d.fireInfo(ApplierWaitingDrain, crd)
+	objects, err = d.waitObjects(ctx, gvk)
+	if err != nil || len(objects) == 0 {
+		return err
+	}
+
+	// If we are here the timeout expired and there are objects that didn't disappear. If
+	// forcing isn't enabled we report them, including the finalizers that block them.
+	if !d.force {
+		names := make([]string, len(objects))
+		var finalizers []string
+		for i, object := range objects {
+			names[i] = d.objectName(object)
+			for _, finalizer := range object.GetFinalizers() {
+				if !slices.Contains(finalizers, finalizer) {
+					finalizers = append(finalizers, finalizer)
+				}
+			}
+		}
+		sort.Strings(finalizers)
+		return fmt.Errorf(
+			"timed out while waiting for objects of kind '%s' to be deleted, "+
+				"objects %s are blocked by finalizers %s, use the '--%s' flag to "+
+				"remove them",
+			gvk.Kind, logging.All(names), logging.All(finalizers),
+			crdDeleterForceFlagName,
+		)
+	}
+
+	// Remove the finalizers and wait again:
+	for _, object := range objects {
+		finalizers := object.GetFinalizers()
+		patch := clnt.RawPatch(types.MergePatchType, []byte(`{
+			"metadata": {
+				"finalizers": null
+			}
+		}`))
+		err = d.client.Patch(ctx, object, patch)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			d.fireError(ApplierDeleteError, object, err)
+			return err
+		}
+		d.fireEvent(&ApplierEvent{
+			Type:       ApplierFinalizersRemoved,
+			Object:     object,
+			Finalizers: finalizers,
+		})
+	}
+	objects, err = d.waitObjects(ctx, gvk)
+	if err != nil {
+		return err
+	}
+	if len(objects) > 0 {
+		names := make([]string, len(objects))
+		for i, object := range objects {
+			names[i] = d.objectName(object)
+		}
+		return fmt.Errorf(
+			"objects %s of kind '%s' still exist after removing their finalizers",
+			logging.All(names), gvk.Kind,
+		)
+	}
+	return nil
+}
+
+// waitObjects waits till there are no objects of the given kind or till the timeout expires.
+// Returns the objects that still exist.
+func (d *CRDDeleter) waitObjects(ctx context.Context,
+	gvk schema.GroupVersionKind) (result []*unstructured.Unstructured, err error) {
+	waitCtx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	settings := backoff.NewExponentialBackOff()
+	settings.InitialInterval = crdDeleterInitialInterval
+	settings.MaxInterval = crdDeleterMaxInterval
+	settings.MaxElapsedTime = 0
+	operation := func() error {
+		var err error
+		result, err = d.listObjects(ctx, gvk)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if len(result) > 0 {
+			return errCRDDeleterPending
+		}
+		return nil
+	}
+	err = backoff.Retry(operation, backoff.WithContext(settings, waitCtx))
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		err = nil
+	}
+	return
+}
+
+// listObjects returns all the objects of the given kind in all the namespaces. If the kind
+// doesn't exist it returns an empty list.
+func (d *CRDDeleter) listObjects(ctx context.Context,
+	gvk schema.GroupVersionKind) (result []*unstructured.Unstructured, err error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(listGVK(gvk))
+	err = d.client.List(ctx, list)
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	result = make([]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		result[i] = &list.Items[i]
+	}
+	return
+}
+
+func (d *CRDDeleter) objectName(object *unstructured.Unstructured) string {
+	if object.GetNamespace() == "" {
+		return object.GetName()
+	}
+	return fmt.Sprintf("%s/%s", object.GetNamespace(), object.GetName())
+}
+
+func (d *CRDDeleter) fireInfo(typ ApplierEventType, object *unstructured.Unstructured) {
+	d.fireEvent(&ApplierEvent{
+		Type:   typ,
+		Object: object,
+	})
+}
+
+func (d *CRDDeleter) fireError(typ ApplierEventType, object *unstructured.Unstructured,
+	err error) {
+	d.fireEvent(&ApplierEvent{
+		Type:   typ,
+		Object: object,
+		Error:  err,
+	})
+}
+
+func (d *CRDDeleter) fireEvent(event *ApplierEvent) {
+	for _, listener := range d.listeners {
+		listener(event)
+	}
+}
+
+// errCRDDeleterPending is used to tell the backoff loop that there are still objects pending and
+// that it should try again.
+var errCRDDeleterPending = errors.New("there are objects pending deletion")
+
+// crdDeleterDefaultTimeout is the default maximum time to wait for the objects of a custom resource
+// definition to disappear.
+const crdDeleterDefaultTimeout = 5 * time.Minute
+
+// Intervals used to check again if the objects have disappeared:
+const (
+	crdDeleterInitialInterval = time.Second
+	crdDeleterMaxInterval     = 15 * time.Second
+)
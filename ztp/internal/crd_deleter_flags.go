@@ -0,0 +1,43 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// AddCRDDeleterFlags adds the flags that control how custom resource definitions are deleted to the
+// given flag set.
+func AddCRDDeleterFlags(set *pflag.FlagSet) {
+	_ = set.Duration(
+		crdDeleterTimeoutFlagName,
+		crdDeleterDefaultTimeout,
+		"Maximum time to wait for the objects of a custom resource definition to be "+
+			"deleted before deleting the definition.",
+	)
+	_ = set.Bool(
+		crdDeleterForceFlagName,
+		false,
+		"Remove the finalizers of the objects that aren't deleted before the timeout "+
+			"expires. This means that the controllers will not have a chance to "+
+			"clean up, so it should only be used when they no longer work.",
+	)
+}
+
+// Names of the flags:
+const (
+	crdDeleterTimeoutFlagName = "finalizers-timeout"
+	crdDeleterForceFlagName   = "force-finalizers"
+)
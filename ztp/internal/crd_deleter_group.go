@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeleteCRDGroup deletes the custom resource definitions of the given group from the given cluster,
+// reporting the progress in the console. This is intended for the commands that delete operators,
+// so that they all send the same events, including the name of the cluster.
+func DeleteCRDGroup(ctx context.Context, logger logr.Logger, console *Console,
+	flags *pflag.FlagSet, client clnt.WithWatch, cluster, group string) error {
+	// The deleter first deletes the objects of the kinds defined by the CRDs and waits till
+	// they disappear, so it sends the same events than the applier:
+	listener, err := NewApplierListener().
+		SetLogger(logger).
+		SetConsole(console).
+		SetCluster(cluster).
+		Build()
+	if err != nil {
+		return err
+	}
+	deleter, err := NewCRDDeleter().
+		SetLogger(logger).
+		SetListener(listener.Func).
+		SetFlags(flags).
+		SetClient(client).
+		Build()
+	if err != nil {
+		return err
+	}
+	n, err := deleter.DeleteGroup(ctx, group)
+	if err != nil {
+		return err
+	}
+	switch {
+	case n == 0:
+		console.Info(
+			"There are no CRDs to delete in group '%s' in cluster '%s'",
+			group, cluster,
+		)
+	case n == 1:
+		console.Info(
+			"Deleted one CRD in group '%s' in cluster '%s'",
+			group, cluster,
+		)
+	default:
+		console.Info(
+			"Deleted %d CRDs in group '%s' in cluster '%s'",
+			n, group, cluster,
+		)
+	}
+	return nil
+}
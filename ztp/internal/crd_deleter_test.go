@@ -0,0 +1,249 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+)
+
+var _ = Describe("CRD deleter", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+		events []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		events = nil
+	})
+
+	// makeDeleter creates a deleter with a short timeout that saves the events as strings
+	// containing the type and the name of the object.
+	makeDeleter := func(force bool) *CRDDeleter {
+		deleter, err := NewCRDDeleter().
+			SetLogger(logger).
+			SetClient(client).
+			SetTimeout(100 * time.Millisecond).
+			SetForce(force).
+			SetListener(func(event *ApplierEvent) {
+				text := string(event.Type) + " " + event.Object.GetName()
+				if len(event.Finalizers) > 0 {
+					text += " " + strings.Join(event.Finalizers, ",")
+				}
+				events = append(events, text)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		return deleter
+	}
+
+	// createCRD creates the definition of the 'Example' kind of the given group.
+	createCRD := func(group string) *unstructured.Unstructured {
+		crd := &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "apiextensions.k8s.io/v1",
+				"kind":       "CustomResourceDefinition",
+				"metadata": map[string]any{
+					"name": "examples." + group,
+				},
+				"spec": map[string]any{
+					"group": group,
+					"names": map[string]any{
+						"kind": "Example",
+					},
+					"versions": []any{
+						map[string]any{
+							"name":    "v1",
+							"storage": true,
+						},
+					},
+				},
+			},
+		}
+		err := client.Create(ctx, crd)
+		Expect(err).ToNot(HaveOccurred())
+		return crd
+	}
+
+	// createExample creates an object of the 'Example' kind with the given finalizers.
+	createExample := func(name string, finalizers ...string) {
+		object := &unstructured.Unstructured{}
+		object.SetAPIVersion("example.com/v1")
+		object.SetKind("Example")
+		object.SetNamespace("my-ns")
+		object.SetName(name)
+		object.SetFinalizers(finalizers)
+		err := client.Create(ctx, object)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Can't be created without a logger", func() {
+		_, err := NewCRDDeleter().
+			SetClient(client).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("logger is mandatory"))
+	})
+
+	It("Can't be created without a client", func() {
+		_, err := NewCRDDeleter().
+			SetLogger(logger).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("client is mandatory"))
+	})
+
+	It("Deletes CRD without objects", func() {
+		crd := createCRD("example.com")
+		deleter := makeDeleter(false)
+		err := deleter.Delete(ctx, crd)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]string{
+			"ObjectDeleted examples.example.com",
+		}))
+	})
+
+	It("Deletes the objects before the CRD", func() {
+		crd := createCRD("example.com")
+		createExample("first")
+		createExample("second")
+		deleter := makeDeleter(false)
+		err := deleter.Delete(ctx, crd)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]string{
+			"ObjectDeleted first",
+			"ObjectDeleted second",
+			"WaitingDrain examples.example.com",
+			"ObjectDeleted examples.example.com",
+		}))
+	})
+
+	It("Reports the objects blocked by finalizers", func() {
+		crd := createCRD("example.com")
+		createExample("first", "example.com/a", "example.com/b")
+		createExample("second")
+		deleter := makeDeleter(false)
+		err := deleter.Delete(ctx, crd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(
+			"timed out while waiting for objects of kind 'Example' to be deleted, " +
+				"objects 'my-ns/first' are blocked by finalizers 'example.com/a' " +
+				"and 'example.com/b', use the '--force-finalizers' flag to remove " +
+				"them",
+		))
+
+		// Check that the CRD hasn't been deleted:
+		err = client.Get(ctx, clnt.ObjectKeyFromObject(crd), crd)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Removes the finalizers if forced", func() {
+		crd := createCRD("example.com")
+		createExample("first", "example.com/a")
+		deleter := makeDeleter(true)
+		err := deleter.Delete(ctx, crd)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]string{
+			"ObjectDeleted first",
+			"WaitingDrain examples.example.com",
+			"FinalizersRemoved first example.com/a",
+			"ObjectDeleted examples.example.com",
+		}))
+	})
+
+	It("Deletes only the CRDs of the group", func() {
+		createCRD("example.com")
+		createCRD("other.com")
+		deleter := makeDeleter(false)
+		n, err := deleter.DeleteGroup(ctx, "example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(events).To(Equal([]string{
+			"ObjectDeleted examples.example.com",
+		}))
+	})
+
+	It("Reports the deleted CRDs of the group in the console", func() {
+		createCRD("example.com")
+		buffer := &bytes.Buffer{}
+		console, err := NewConsole().
+			SetLogger(logger).
+			SetOut(buffer).
+			SetErr(buffer).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		err = DeleteCRDGroup(ctx, logger, console, flags, client, "my-cluster", "example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(ContainSubstring(
+			"Deleted one CRD in group 'example.com' in cluster 'my-cluster'",
+		))
+	})
+
+	It("Includes the cluster in the events written by the group helper", func() {
+		createCRD("example.com")
+		createExample("first")
+		buffer := &bytes.Buffer{}
+		console, err := NewConsole().
+			SetLogger(logger).
+			SetOut(buffer).
+			SetErr(buffer).
+			SetOutput(ConsoleOutputJSONL).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		err = DeleteCRDGroup(ctx, logger, console, flags, client, "my-cluster", "example.com")
+		Expect(err).ToNot(HaveOccurred())
+		var types []string
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			var record map[string]any
+			err = json.Unmarshal([]byte(line), &record)
+			Expect(err).ToNot(HaveOccurred())
+			if record["source"] != "applier" {
+				continue
+			}
+			Expect(record).To(HaveKeyWithValue("cluster", "my-cluster"))
+			types = append(types, record["type"].(string))
+		}
+		Expect(types).To(Equal([]string{
+			"ObjectDeleted",
+			"WaitingDrain",
+			"ObjectDeleted",
+		}))
+	})
+})
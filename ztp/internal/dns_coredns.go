@@ -0,0 +1,293 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/labels"
+)
+
+// CoreDNSProviderBuilder contains the data and logic needed to create a DNS provider that writes
+// zone files to a config map used by CoreDNS. Don't create instances of this type directly, use
+// the NewCoreDNSProvider function instead.
+type CoreDNSProviderBuilder struct {
+	logger    logr.Logger
+	client    clnt.Client
+	namespace string
+	name      string
+	ttl       uint32
+}
+
+// CoreDNSProvider is a DNS provider that publishes records writing zone files to a config map of
+// the hub cluster. The config map is intended to be mounted by a CoreDNS server that uses the
+// `file` plugin to serve the zones. For example, if the config map is mounted in the
+// `/etc/coredns/zones` directory the Corefile would contain something like this for the
+// `example.com` zone:
+//
+//	example.com {
+//	    file /etc/coredns/zones/db.example.com {
+//	        reload 10s
+//	    }
+//	}
+//
+// Each zone is stored in a key named `db.` followed by the name of the zone. The serial number of
+// the SOA record is updated every time the zone changes, so that CoreDNS reloads it. Don't create
+// instances of this type directly, use the NewCoreDNSProvider function instead.
+type CoreDNSProvider struct {
+	logger    logr.Logger
+	client    clnt.Client
+	namespace string
+	name      string
+	ttl       uint32
+}
+
+// NewCoreDNSProvider creates a builder that can then be used to create a DNS provider that writes
+// zone files to a config map used by CoreDNS.
+func NewCoreDNSProvider() *CoreDNSProviderBuilder {
+	return &CoreDNSProviderBuilder{
+		namespace: coreDNSDefaultNamespace,
+		name:      coreDNSDefaultName,
+		ttl:       dnsDefaultTTL,
+	}
+}
+
+// SetLogger sets the logger that the provider will use to write log messages. This is mandatory.
+func (b *CoreDNSProviderBuilder) SetLogger(value logr.Logger) *CoreDNSProviderBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the provider will use to update the config map.
+// This is mandatory.
+func (b *CoreDNSProviderBuilder) SetClient(value clnt.Client) *CoreDNSProviderBuilder {
+	b.client = value
+	return b
+}
+
+// SetConfigMap sets the namespace and name of the config map that contains the zone files. This is
+// optional, and the default is `ztpfw-dns/coredns-zones`.
+func (b *CoreDNSProviderBuilder) SetConfigMap(namespace, name string) *CoreDNSProviderBuilder {
+	b.namespace = namespace
+	b.name = name
+	return b
+}
+
+// SetTTL sets the time to live of the records. This is optional, and the default is five minutes.
+func (b *CoreDNSProviderBuilder) SetTTL(value time.Duration) *CoreDNSProviderBuilder {
+	b.ttl = uint32(value.Seconds())
+	return b
+}
+
+// Build uses the data stored in the builder to create a new DNS provider.
+func (b *CoreDNSProviderBuilder) Build() (result *CoreDNSProvider, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.client == nil {
+		err = errors.New("client is mandatory")
+		return
+	}
+	if b.namespace == "" || b.name == "" {
+		err = errors.New("namespace and name of the config map are mandatory")
+		return
+	}
+
+	// Create and populate the object:
+	result = &CoreDNSProvider{
+		logger:    b.logger,
+		client:    b.client,
+		namespace: b.namespace,
+		name:      b.name,
+		ttl:       b.ttl,
+	}
+	return
+}
+
+// Publish is the implementation of the DNSProvider interface.
+func (p *CoreDNSProvider) Publish(ctx context.Context, zone, name string,
+	address net.IP) error {
+	// Prepare the record:
+	record, err := dnsRecord(name, address, p.ttl)
+	if err != nil {
+		return err
+	}
+
+	// Get the current config map, if it exists:
+	zone = dns.Fqdn(zone)
+	key := coreDNSKey(zone)
+	configMap := &corev1.ConfigMap{}
+	err = p.client.Get(ctx, clnt.ObjectKey{
+		Namespace: p.namespace,
+		Name:      p.name,
+	}, configMap)
+	exists := err == nil
+	if apierrors.IsNotFound(err) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Parse the current zone, replace the records and update the serial number:
+	var records []dns.RR
+	text, ok := configMap.Data[key]
+	if ok {
+		records, err = p.parseZone(zone, text)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to parse zone '%s' from key '%s' of config map '%s/%s': %w",
+				zone, key, p.namespace, p.name, err,
+			)
+		}
+	}
+	records = p.updateZone(zone, records, record)
+	text = p.renderZone(zone, records)
+
+	// Save the config map:
+	if !exists {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: p.namespace,
+				Name:      p.name,
+				Labels: map[string]string{
+					labels.ZTPFW: "",
+				},
+			},
+			Data: map[string]string{
+				key: text,
+			},
+		}
+		err = p.client.Create(ctx, configMap)
+	} else {
+		update := configMap.DeepCopy()
+		if update.Data == nil {
+			update.Data = map[string]string{}
+		}
+		update.Data[key] = text
+		err = p.client.Patch(ctx, update, clnt.MergeFrom(configMap))
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to save zone '%s' to config map '%s/%s': %w",
+			zone, p.namespace, p.name, err,
+		)
+	}
+	p.logger.Info(
+		"Published DNS record",
+		"namespace", p.namespace,
+		"name", p.name,
+		"zone", zone,
+		"record", record.Header().Name,
+		"address", address,
+	)
+	return nil
+}
+
+func (p *CoreDNSProvider) parseZone(zone, text string) (result []dns.RR, err error) {
+	parser := dns.NewZoneParser(strings.NewReader(text), zone, "")
+	for record, ok := parser.Next(); ok; record, ok = parser.Next() {
+		result = append(result, record)
+	}
+	err = parser.Err()
+	return
+}
+
+// updateZone replaces the records that have the same name and type than the given one, and updates
+// the SOA record, creating it if needed.
+func (p *CoreDNSProvider) updateZone(zone string, records []dns.RR, record dns.RR) []dns.RR {
+	// Remove the SOA and the records that will be replaced:
+	var soa *dns.SOA
+	var result []dns.RR
+	for _, current := range records {
+		header := current.Header()
+		switch {
+		case header.Rrtype == dns.TypeSOA:
+			soa = current.(*dns.SOA)
+		case strings.EqualFold(header.Name, record.Header().Name) &&
+			header.Rrtype == record.Header().Rrtype:
+			// This is replaced by the new record.
+		default:
+			result = append(result, current)
+		}
+	}
+	result = append(result, record)
+
+	// Create the SOA record if needed, and update the serial number. We use the current time as
+	// serial number, unless that is not larger than the previous one.
+	if soa == nil {
+		soa = &dns.SOA{
+			Hdr: dns.RR_Header{
+				Name:   zone,
+				Rrtype: dns.TypeSOA,
+				Class:  dns.ClassINET,
+				Ttl:    p.ttl,
+			},
+			Ns:      "ns." + zone,
+			Mbox:    "hostmaster." + zone,
+			Refresh: 7200,
+			Retry:   3600,
+			Expire:  1209600,
+			Minttl:  p.ttl,
+		}
+	}
+	serial := uint32(time.Now().Unix())
+	if serial <= soa.Serial {
+		serial = soa.Serial + 1
+	}
+	soa.Serial = serial
+
+	// Sort the records so that the SOA is always the first and the rest are in a predictable
+	// order:
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return append([]dns.RR{soa}, result...)
+}
+
+func (p *CoreDNSProvider) renderZone(zone string, records []dns.RR) string {
+	buffer := &strings.Builder{}
+	fmt.Fprintf(buffer, "$ORIGIN %s\n", zone)
+	for _, record := range records {
+		fmt.Fprintf(buffer, "%s\n", record)
+	}
+	return buffer.String()
+}
+
+// coreDNSKey returns the config map key that contains the file of the given zone.
+func coreDNSKey(zone string) string {
+	return "db." + strings.TrimSuffix(zone, ".")
+}
+
+// Default namespace and name of the config map that contains the zone files.
+const (
+	coreDNSDefaultNamespace = "ztpfw-dns"
+	coreDNSDefaultName      = "coredns-zones"
+)
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// DNSProvider is the interface of the objects that know how to publish DNS records, so that the
+// names of the API and ingress of the clusters resolve to their external IP addresses.
+type DNSProvider interface {
+	// Publish creates or replaces the A or AAAA record of the given name inside the given zone,
+	// so that it resolves to the given address. Existing records of the same name and type are
+	// replaced.
+	Publish(ctx context.Context, zone, name string, address net.IP) error
+}
+
+// dnsRecord creates the A or AAAA record, depending on the family of the address, for the given
+// name and address.
+func dnsRecord(name string, address net.IP, ttl uint32) (result dns.RR, err error) {
+	header := dns.RR_Header{
+		Name:  dns.Fqdn(name),
+		Class: dns.ClassINET,
+		Ttl:   ttl,
+	}
+	if ipv4 := address.To4(); ipv4 != nil {
+		header.Rrtype = dns.TypeA
+		result = &dns.A{
+			Hdr: header,
+			A:   ipv4,
+		}
+		return
+	}
+	if ipv6 := address.To16(); ipv6 != nil {
+		header.Rrtype = dns.TypeAAAA
+		result = &dns.AAAA{
+			Hdr:  header,
+			AAAA: ipv6,
+		}
+		return
+	}
+	err = fmt.Errorf("address '%s' isn't a valid IPv4 or IPv6 address", address)
+	return
+}
+
+// Names of the supported DNS providers:
+const (
+	DNSProviderRFC2136 = "rfc2136"
+	DNSProviderCoreDNS = "coredns"
+)
+
+// dnsDefaultTTL is the default time to live of the records published by the DNS providers.
+const dnsDefaultTTL = 300
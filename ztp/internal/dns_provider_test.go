@@ -0,0 +1,264 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+	. "github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/testing"
+)
+
+var _ = Describe("DNS provider", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("RFC 2136", func() {
+		var server *DNSServer
+
+		BeforeEach(func() {
+			server = NewDNSServer()
+			DeferCleanup(server.Close)
+			server.AddZone("example.com")
+		})
+
+		// lookup returns the addresses of the given name using the test server.
+		lookup := func(name string) []string {
+			resolver := &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					dialer := &net.Dialer{}
+					return dialer.DialContext(ctx, network, server.Address())
+				},
+			}
+			addresses, err := resolver.LookupHost(ctx, name)
+			if err != nil {
+				return nil
+			}
+			return addresses
+		}
+
+		It("Publishes and replaces records", func() {
+			provider, err := NewRFC2136DNSProvider().
+				SetLogger(logger).
+				SetServer(server.Address()).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lookup("api.my.example.com")).To(ConsistOf("192.168.150.100"))
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.200"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lookup("api.my.example.com")).To(ConsistOf("192.168.150.200"))
+		})
+
+		It("Signs updates with the TSIG key", func() {
+			server.AddKey("ztp", "c2VjcmV0")
+			provider, err := NewRFC2136DNSProvider().
+				SetLogger(logger).
+				SetServer(server.Address()).
+				SetKey(models.NewSecret("hmac-sha256:ztp:c2VjcmV0")).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lookup("api.my.example.com")).To(ConsistOf("192.168.150.100"))
+		})
+
+		It("Fails if the update isn't signed", func() {
+			server.AddKey("ztp", "c2VjcmV0")
+			provider, err := NewRFC2136DNSProvider().
+				SetLogger(logger).
+				SetServer(server.Address()).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).To(MatchError(ContainSubstring("NOTAUTH")))
+		})
+
+		It("Fails if the zone doesn't exist", func() {
+			provider, err := NewRFC2136DNSProvider().
+				SetLogger(logger).
+				SetServer(server.Address()).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.org", "api.my.example.org",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).To(MatchError(ContainSubstring("NOTZONE")))
+		})
+	})
+
+	Describe("CoreDNS", func() {
+		var client clnt.Client
+
+		BeforeEach(func() {
+			client = fake.NewClientBuilder().Build()
+		})
+
+		// getZone returns the records of the given zone from the config map.
+		getZone := func(zone string) []dns.RR {
+			configMap := &corev1.ConfigMap{}
+			err := client.Get(ctx, clnt.ObjectKey{
+				Namespace: coreDNSDefaultNamespace,
+				Name:      coreDNSDefaultName,
+			}, configMap)
+			Expect(err).ToNot(HaveOccurred())
+			text, ok := configMap.Data["db."+zone]
+			Expect(ok).To(BeTrue())
+			var records []dns.RR
+			parser := dns.NewZoneParser(strings.NewReader(text), "", "")
+			for record, ok := parser.Next(); ok; record, ok = parser.Next() {
+				records = append(records, record)
+			}
+			Expect(parser.Err()).ToNot(HaveOccurred())
+			return records
+		}
+
+		It("Writes the zone to the config map", func() {
+			provider, err := NewCoreDNSProvider().
+				SetLogger(logger).
+				SetClient(client).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "*.apps.my.example.com",
+				net.ParseIP("fd00::1"))
+			Expect(err).ToNot(HaveOccurred())
+			records := getZone("example.com")
+			Expect(records).To(HaveLen(3))
+			Expect(records[0]).To(BeAssignableToTypeOf(&dns.SOA{}))
+			Expect(records[1].String()).To(Equal("*.apps.my.example.com.\t300\tIN\tAAAA\tfd00::1"))
+			Expect(records[2].String()).To(Equal("api.my.example.com.\t300\tIN\tA\t192.168.150.100"))
+		})
+
+		It("Replaces records and increases the serial number", func() {
+			provider, err := NewCoreDNSProvider().
+				SetLogger(logger).
+				SetClient(client).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.100"))
+			Expect(err).ToNot(HaveOccurred())
+			before := getZone("example.com")[0].(*dns.SOA).Serial
+			err = provider.Publish(ctx, "example.com", "api.my.example.com",
+				net.ParseIP("192.168.150.200"))
+			Expect(err).ToNot(HaveOccurred())
+			records := getZone("example.com")
+			Expect(records).To(HaveLen(2))
+			Expect(records[0].(*dns.SOA).Serial).To(BeNumerically(">", before))
+			Expect(records[1].String()).To(Equal("api.my.example.com.\t300\tIN\tA\t192.168.150.200"))
+		})
+	})
+
+	It("Is used by the enricher to publish the names of the cluster", func() {
+		client := fake.NewClientBuilder().Build()
+		provider, err := NewCoreDNSProvider().
+			SetLogger(logger).
+			SetClient(client).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(client).
+			SetDNSProvider(provider).
+			SetSteps(EnricherStepDNSRecords).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := &models.Config{
+			Clusters: []*models.Cluster{{
+				Name: "my",
+				DNS: models.DNS{
+					Domain: "example.com",
+				},
+				API: models.API{
+					ExternalIP: net.ParseIP("192.168.150.100"),
+				},
+				Ingress: models.Ingress{
+					ExternalIP: net.ParseIP("192.168.150.101"),
+				},
+			}},
+		}
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		configMap := &corev1.ConfigMap{}
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: coreDNSDefaultNamespace,
+			Name:      coreDNSDefaultName,
+		}, configMap)
+		Expect(err).ToNot(HaveOccurred())
+		zone := configMap.Data["db.example.com"]
+		Expect(zone).To(ContainSubstring("api.my.example.com.\t300\tIN\tA\t192.168.150.100"))
+		Expect(zone).To(ContainSubstring("apps.my.example.com.\t300\tIN\tA\t192.168.150.101"))
+		Expect(zone).To(ContainSubstring("*.apps.my.example.com.\t300\tIN\tA\t192.168.150.101"))
+	})
+	It("Fails to publish DNS records when the addresses aren't known", func() {
+		client := fake.NewClientBuilder().Build()
+		provider, err := NewCoreDNSProvider().
+			SetLogger(logger).
+			SetClient(client).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(client).
+			SetDNSProvider(provider).
+			SetSteps(EnricherStepDNSRecords).
+			SkipSteps(EnricherStepExternalAPIIP, EnricherStepExternalIngressIP).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := &models.Config{
+			Clusters: []*models.Cluster{{
+				Name: "my",
+				DNS: models.DNS{
+					Domain: "example.com",
+				},
+			}},
+		}
+		err = enricher.Enrich(ctx, config)
+		Expect(err).To(MatchError(ContainSubstring(
+			"failed to publish DNS records of cluster 'my'",
+		)))
+	})
+})
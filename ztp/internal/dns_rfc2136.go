@@ -0,0 +1,215 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// RFC2136DNSProviderBuilder contains the data and logic needed to create a DNS provider that uses
+// RFC 2136 dynamic updates. Don't create instances of this type directly, use the
+// NewRFC2136DNSProvider function instead.
+type RFC2136DNSProviderBuilder struct {
+	logger  logr.Logger
+	server  string
+	key     *models.Secret
+	secrets *SecretResolver
+	ttl     uint32
+}
+
+// RFC2136DNSProvider is a DNS provider that publishes records sending RFC 2136 dynamic updates to
+// a DNS server, for example a BIND server where the zone has been configured with an
+// `allow-update` or `update-policy` clause. Updates can be signed with a TSIG key. Don't create
+// instances of this type directly, use the NewRFC2136DNSProvider function instead.
+type RFC2136DNSProvider struct {
+	logger  logr.Logger
+	server  string
+	key     *models.Secret
+	secrets *SecretResolver
+	ttl     uint32
+}
+
+// NewRFC2136DNSProvider creates a builder that can then be used to create a DNS provider that uses
+// RFC 2136 dynamic updates.
+func NewRFC2136DNSProvider() *RFC2136DNSProviderBuilder {
+	return &RFC2136DNSProviderBuilder{
+		ttl: dnsDefaultTTL,
+	}
+}
+
+// SetLogger sets the logger that the provider will use to write log messages. This is mandatory.
+func (b *RFC2136DNSProviderBuilder) SetLogger(value logr.Logger) *RFC2136DNSProviderBuilder {
+	b.logger = value
+	return b
+}
+
+// SetServer sets the IP address and port number of the DNS server, for example `127.0.0.1:53`. If
+// the port number is omitted 53 will be used. This is mandatory.
+func (b *RFC2136DNSProviderBuilder) SetServer(value string) *RFC2136DNSProviderBuilder {
+	b.server = value
+	return b
+}
+
+// SetKey sets the TSIG key used to sign the updates. The value of the secret should have the same
+// format used by the `-y` option of the `nsupdate` command: `[algorithm:]name:secret`, for example
+// `hmac-sha256:ztp:c2VjcmV0`. The default algorithm is `hmac-sha256`. This is optional, and by
+// default the updates aren't signed.
+func (b *RFC2136DNSProviderBuilder) SetKey(value *models.Secret) *RFC2136DNSProviderBuilder {
+	b.key = value
+	return b
+}
+
+// SetSecrets sets the secret resolver that will be used to find the value of the TSIG key when it
+// is a reference. This is mandatory if the key is a reference.
+func (b *RFC2136DNSProviderBuilder) SetSecrets(value *SecretResolver) *RFC2136DNSProviderBuilder {
+	b.secrets = value
+	return b
+}
+
+// SetTTL sets the time to live of the records. This is optional, and the default is five minutes.
+func (b *RFC2136DNSProviderBuilder) SetTTL(value time.Duration) *RFC2136DNSProviderBuilder {
+	b.ttl = uint32(value.Seconds())
+	return b
+}
+
+// Build uses the data stored in the builder to create a new DNS provider.
+func (b *RFC2136DNSProviderBuilder) Build() (result *RFC2136DNSProvider, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.server == "" {
+		err = errors.New("server is mandatory")
+		return
+	}
+	if b.key != nil && !b.key.Resolved() && b.secrets == nil {
+		err = errors.New("secret resolver is mandatory when the key is a reference")
+		return
+	}
+
+	// Add the default port number if needed:
+	server := b.server
+	_, _, err = net.SplitHostPort(server)
+	if err != nil {
+		server = net.JoinHostPort(server, "53")
+		err = nil
+	}
+
+	// Create and populate the object:
+	result = &RFC2136DNSProvider{
+		logger:  b.logger,
+		server:  server,
+		key:     b.key,
+		secrets: b.secrets,
+		ttl:     b.ttl,
+	}
+	return
+}
+
+// Publish is the implementation of the DNSProvider interface.
+func (p *RFC2136DNSProvider) Publish(ctx context.Context, zone, name string,
+	address net.IP) error {
+	// Prepare the record:
+	record, err := dnsRecord(name, address, p.ttl)
+	if err != nil {
+		return err
+	}
+
+	// Prepare the update message, deleting the existing records of the same name and type
+	// before adding the new one:
+	zone = dns.Fqdn(zone)
+	msg := &dns.Msg{}
+	msg.SetUpdate(zone)
+	msg.RemoveRRset([]dns.RR{record})
+	msg.Insert([]dns.RR{record})
+
+	// Sign the message if needed:
+	client := &dns.Client{}
+	if p.key != nil {
+		var algorithm, keyName, keySecret string
+		algorithm, keyName, keySecret, err = p.parseKey(ctx)
+		if err != nil {
+			return err
+		}
+		msg.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+		client.TsigSecret = map[string]string{
+			keyName: keySecret,
+		}
+	}
+
+	// Send the update:
+	response, _, err := client.ExchangeContext(ctx, msg, p.server)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to send update for '%s' to DNS server '%s': %w",
+			record.Header().Name, p.server, err,
+		)
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf(
+			"DNS server '%s' rejected update for '%s' in zone '%s' with code '%s'",
+			p.server, record.Header().Name, zone, dns.RcodeToString[response.Rcode],
+		)
+	}
+	p.logger.Info(
+		"Published DNS record",
+		"server", p.server,
+		"zone", zone,
+		"name", record.Header().Name,
+		"address", address,
+	)
+	return nil
+}
+
+// parseKey resolves the TSIG key if needed, and extracts the algorithm, name and secret.
+func (p *RFC2136DNSProvider) parseKey(ctx context.Context) (algorithm, name, secret string,
+	err error) {
+	if !p.key.Resolved() {
+		err = p.secrets.Resolve(ctx, p.key)
+		if err != nil {
+			return
+		}
+	}
+	value, err := p.key.Value()
+	if err != nil {
+		return
+	}
+	parts := strings.Split(value, ":")
+	switch len(parts) {
+	case 2:
+		algorithm = dns.HmacSHA256
+		name, secret = parts[0], parts[1]
+	case 3:
+		algorithm, name, secret = dns.Fqdn(parts[0]), parts[1], parts[2]
+	default:
+		err = errors.New(
+			"TSIG key should have the '[algorithm:]name:secret' format",
+		)
+		return
+	}
+	name = dns.Fqdn(name)
+	return
+}
@@ -28,6 +28,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -36,9 +39,12 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clnt "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/jq"
@@ -49,18 +55,73 @@ import (
 // information to the description of a cluster. Don't create instances of this type directly, use
 // the NewEnricher function instead.
 type EnricherBuilder struct {
-	logger   logr.Logger
-	client   clnt.Client
-	resolver string
+	logger       logr.Logger
+	client       clnt.Client
+	resolver     string
+	offline      bool
+	releaseFile  string
+	releaseImage string
+	flags        *pflag.FlagSet
+	cache        *EnricherCache
+	dnsProvider  DNSProvider
+	dnsZone      string
+	dryRun       bool
+	steps        []EnricherStep
+	selected     []string
+	skipped      []string
+	listeners    []func(*EnricherEvent)
 }
 
 // Enricher knows how to add information to the description of a cluster. Don't create instances of
 // this type directly, use the NewEnricher function instead.
 type Enricher struct {
-	logger   logr.Logger
-	client   clnt.Client
-	jq       *jq.Tool
-	resolver *net.Resolver
+	logger       logr.Logger
+	client       clnt.Client
+	jq           *jq.Tool
+	resolver     *net.Resolver
+	secrets      *SecretResolver
+	cache        *EnricherCache
+	ipam         *IPAM
+	dnsProvider  DNSProvider
+	dnsZone      string
+	dryRun       bool
+	steps        []EnricherStep
+	offline      bool
+	releaseFile  string
+	releaseImage string
+	skipped      []string
+	listeners    []func(*EnricherEvent)
+}
+
+// EnricherEventType represents the type of an enricher event.
+type EnricherEventType string
+
+const (
+	// EnricherStepSkipped indicates that a step will not run because it has been explicitly
+	// skipped.
+	EnricherStepSkipped EnricherEventType = "StepSkipped"
+
+	// EnricherStepStarted indicates that a step started to run.
+	EnricherStepStarted EnricherEventType = "StepStarted"
+
+	// EnricherClusterEnriched indicates that a step completed the information of a cluster.
+	EnricherClusterEnriched EnricherEventType = "ClusterEnriched"
+
+	// EnricherStepCompleted indicates that a step completed the information of the configuration
+	// and of all the clusters.
+	EnricherStepCompleted EnricherEventType = "StepCompleted"
+
+	// EnricherStepFailed indicates that a step failed. If it failed while processing a cluster
+	// the event will contain the name of that cluster.
+	EnricherStepFailed EnricherEventType = "StepFailed"
+)
+
+// EnricherEvent represents an event generated by the enricher to inform of the steps that it runs.
+type EnricherEvent struct {
+	Type    EnricherEventType
+	Step    string
+	Cluster string
+	Error   error
 }
 
 // NewEnricher creates a builder that can then be used to create an object that knows how to add
@@ -90,15 +151,142 @@ func (b *EnricherBuilder) SetResolver(value string) *EnricherBuilder {
 	return b
 }
 
+// SetOffline sets the offline mode. In this mode the enricher will not try to download anything
+// from the Internet, and it will fail with an explanation if the information can't be obtained
+// from other sources. This is optional, and the default is false.
+func (b *EnricherBuilder) SetOffline(value bool) *EnricherBuilder {
+	b.offline = value
+	return b
+}
+
+// SetReleaseFile sets the name of a local copy of the `release.txt` file of the OpenShift release.
+// When this is set the enricher will use it instead of downloading it from the mirror. This is
+// optional.
+func (b *EnricherBuilder) SetReleaseFile(value string) *EnricherBuilder {
+	b.releaseFile = value
+	return b
+}
+
+// SetReleaseImage sets the reference of the OpenShift release image, for example one inside the
+// mirror registry. When this is set the enricher will get the release information from the image
+// instead of downloading the `release.txt` file from the mirror. Note that this requires the `oc`
+// command. This is optional.
+func (b *EnricherBuilder) SetReleaseImage(value string) *EnricherBuilder {
+	b.releaseImage = value
+	return b
+}
+
+// SetCache sets the cache that the enricher will use to save the result of the enrichment and to
+// reuse it in later runs. This is optional, and by default the result isn't saved unless the
+// '--enricher-cache' flag is used.
+func (b *EnricherBuilder) SetCache(value *EnricherCache) *EnricherBuilder {
+	b.cache = value
+	return b
+}
+
+// SetDNSProvider sets the provider that the enricher will use to publish the DNS records of the
+// API and ingress of the clusters that have explicit external IP addresses. This is optional, and
+// by default records aren't published unless the '--dns-provider' flag is used.
+func (b *EnricherBuilder) SetDNSProvider(value DNSProvider) *EnricherBuilder {
+	b.dnsProvider = value
+	return b
+}
+
+// SetDNSZone sets the DNS zone where the records are published. This is optional, and the default
+// is to use the DNS domain of each cluster.
+func (b *EnricherBuilder) SetDNSZone(value string) *EnricherBuilder {
+	b.dnsZone = value
+	return b
+}
+
+// SetDryRun sets the dry run flag. When it is true the enricher doesn't save its state, the internal
+// IP addresses or the DNS records. The default is false.
+func (b *EnricherBuilder) SetDryRun(value bool) *EnricherBuilder {
+	b.dryRun = value
+	return b
+}
+
+// AddStep adds a step to the enricher. If there is already a step with the same name it will be
+// replaced, otherwise the step will be added after the built-in ones. This is optional.
+func (b *EnricherBuilder) AddStep(value EnricherStep) *EnricherBuilder {
+	b.steps = append(b.steps, value)
+	return b
+}
+
+// SetSteps sets the names of the steps that the enricher will run. The steps that they depend on
+// will also be added automatically. This is optional, and by default all the steps will run.
+func (b *EnricherBuilder) SetSteps(names ...string) *EnricherBuilder {
+	b.selected = append(b.selected, names...)
+	return b
+}
+
+// SkipSteps sets the names of steps that the enricher will not run, even if they have been
+// explicitly selected with the SetSteps method or if other steps depend on them. This is optional.
+func (b *EnricherBuilder) SkipSteps(names ...string) *EnricherBuilder {
+	b.skipped = append(b.skipped, names...)
+	return b
+}
+
+// SetListener sets a function that will be called when an event is generated. This is optional.
+// Note that this removes any previously added listener. If you want to preserve them use the
+// AddListener function.
+func (b *EnricherBuilder) SetListener(value func(*EnricherEvent)) *EnricherBuilder {
+	b.listeners = []func(*EnricherEvent){value}
+	return b
+}
+
+// AddListener adds a function that will be called when an event is generated. This is optional.
+func (b *EnricherBuilder) AddListener(value func(*EnricherEvent)) *EnricherBuilder {
+	b.listeners = append(b.listeners, value)
+	return b
+}
+
 // SetFlags sets the command line flags that that indicate how to configure the enricher. This is
 // optional.
 func (b *EnricherBuilder) SetFlags(flags *pflag.FlagSet) *EnricherBuilder {
+	b.flags = flags
 	if flags.Changed(enricherResolverFlagName) {
 		value, err := flags.GetString(enricherResolverFlagName)
 		if err == nil {
 			b.resolver = value
 		}
 	}
+	if flags.Changed(enricherOfflineFlagName) {
+		value, err := flags.GetBool(enricherOfflineFlagName)
+		if err == nil {
+			b.offline = value
+		}
+	}
+	if flags.Changed(enricherReleaseFileFlagName) {
+		value, err := flags.GetString(enricherReleaseFileFlagName)
+		if err == nil {
+			b.releaseFile = value
+		}
+	}
+	if flags.Changed(enricherReleaseImageFlagName) {
+		value, err := flags.GetString(enricherReleaseImageFlagName)
+		if err == nil {
+			b.releaseImage = value
+		}
+	}
+	if flags.Changed(enricherDNSZoneFlagName) {
+		value, err := flags.GetString(enricherDNSZoneFlagName)
+		if err == nil {
+			b.dnsZone = value
+		}
+	}
+	if flags.Changed(enricherSkipFlagName) {
+		values, err := flags.GetStringSlice(enricherSkipFlagName)
+		if err == nil {
+			b.SkipSteps(values...)
+		}
+	}
+	if flags.Changed(dryRunFlagName) {
+		value, err := flags.GetBool(dryRunFlagName)
+		if err == nil {
+			b.dryRun = value
+		}
+	}
 	return b
 }
 
@@ -134,12 +322,228 @@ func (b *EnricherBuilder) Build() (result *Enricher, err error) {
 		}
 	}
 
+	// Create the secret resolver:
+	secrets, err := NewSecretResolver().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("failed to create secret resolver: %w", err)
+		return
+	}
+
+	// Create the DNS provider if it has been requested with the flags:
+	dnsProvider := b.dnsProvider
+	if dnsProvider == nil && b.flags != nil && b.flags.Changed(enricherDNSProviderFlagName) {
+		dnsProvider, err = b.createDNSProvider(secrets)
+		if err != nil {
+			err = fmt.Errorf("failed to create DNS provider: %w", err)
+			return
+		}
+	}
+
+	// Create the IPAM:
+	ipam, err := NewIPAM().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		SetDryRun(b.dryRun).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("failed to create IPAM: %w", err)
+		return
+	}
+
+	// Create the cache if it has been requested with the flags:
+	cache := b.cache
+	if cache == nil && b.flags != nil && b.flags.Changed(enricherCacheFlagName) {
+		cache, err = NewEnricherCache().
+			SetLogger(b.logger).
+			SetClient(b.client).
+			SetFlags(b.flags).
+			Build()
+		if err != nil {
+			err = fmt.Errorf("failed to create cache: %w", err)
+			return
+		}
+	}
+
 	// Create and populate the object:
-	result = &Enricher{
-		logger:   b.logger,
-		client:   b.client,
-		jq:       jq,
-		resolver: resolver,
+	enricher := &Enricher{
+		logger:       b.logger,
+		client:       b.client,
+		jq:           jq,
+		resolver:     resolver,
+		secrets:      secrets,
+		cache:        cache,
+		ipam:         ipam,
+		dnsProvider:  dnsProvider,
+		dnsZone:      b.dnsZone,
+		dryRun:       b.dryRun,
+		offline:      b.offline,
+		releaseFile:  b.releaseFile,
+		releaseImage: b.releaseImage,
+		skipped:      slices.Clone(b.skipped),
+		listeners:    slices.Clone(b.listeners),
+	}
+
+	// Calculate the steps that will run, and in what order:
+	enricher.steps, err = b.planSteps(enricher.builtinSteps())
+	if err != nil {
+		return
+	}
+
+	result = enricher
+	return
+}
+
+// createDNSProvider creates the DNS provider selected with the flags.
+func (b *EnricherBuilder) createDNSProvider(secrets *SecretResolver) (result DNSProvider,
+	err error) {
+	kind, err := b.flags.GetString(enricherDNSProviderFlagName)
+	if err != nil {
+		return
+	}
+	switch kind {
+	case DNSProviderRFC2136:
+		var server, text string
+		server, err = b.flags.GetString(enricherDNSServerFlagName)
+		if err != nil {
+			return
+		}
+		text, err = b.flags.GetString(enricherDNSKeyFlagName)
+		if err != nil {
+			return
+		}
+		var key *models.Secret
+		if text != "" {
+			key, err = models.ParseSecret(text)
+			if err != nil {
+				return
+			}
+		}
+		result, err = NewRFC2136DNSProvider().
+			SetLogger(b.logger).
+			SetServer(server).
+			SetKey(key).
+			SetSecrets(secrets).
+			Build()
+	case DNSProviderCoreDNS:
+		var text string
+		text, err = b.flags.GetString(enricherDNSConfigMapFlagName)
+		if err != nil {
+			return
+		}
+		namespace, name, _ := strings.Cut(text, "/")
+		result, err = NewCoreDNSProvider().
+			SetLogger(b.logger).
+			SetClient(b.client).
+			SetConfigMap(namespace, name).
+			Build()
+	default:
+		err = fmt.Errorf(
+			"unknown DNS provider '%s', valid values are '%s' and '%s'",
+			kind, DNSProviderRFC2136, DNSProviderCoreDNS,
+		)
+	}
+	return
+}
+
+// planSteps merges the built-in steps with the ones explicitly added, selects the ones that have
+// been requested and sorts them so that each step runs after the steps that it depends on. When
+// there are no dependencies between two steps they run in the order they were added.
+func (b *EnricherBuilder) planSteps(builtin []EnricherStep) (result []EnricherStep, err error) {
+	// Merge the built-in and the explicitly added steps:
+	steps := slices.Clone(builtin)
+	for _, step := range b.steps {
+		index := slices.IndexFunc(steps, func(candidate EnricherStep) bool {
+			return candidate.Name() == step.Name()
+		})
+		if index >= 0 {
+			steps[index] = step
+		} else {
+			steps = append(steps, step)
+		}
+	}
+	index := map[string]EnricherStep{}
+	for _, step := range steps {
+		index[step.Name()] = step
+	}
+
+	// Check that all the names are known:
+	for _, step := range steps {
+		for _, dependency := range step.Dependencies() {
+			if index[dependency] == nil {
+				err = fmt.Errorf(
+					"step '%s' depends on step '%s', but it doesn't exist",
+					step.Name(), dependency,
+				)
+				return
+			}
+		}
+	}
+	for _, name := range append(slices.Clone(b.selected), b.skipped...) {
+		if index[name] == nil {
+			err = fmt.Errorf("enricher step '%s' doesn't exist", name)
+			return
+		}
+	}
+
+	// Find the steps that have been selected, including their dependencies:
+	selected := map[string]bool{}
+	var selectStep func(name string)
+	selectStep = func(name string) {
+		if selected[name] || slices.Contains(b.skipped, name) {
+			return
+		}
+		selected[name] = true
+		for _, dependency := range index[name].Dependencies() {
+			selectStep(dependency)
+		}
+	}
+	for _, step := range steps {
+		if len(b.selected) == 0 || slices.Contains(b.selected, step.Name()) {
+			selectStep(step.Name())
+		}
+	}
+
+	// Sort the selected steps so that dependencies go first:
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := map[string]int{}
+	var visit func(step EnricherStep, path []string) error
+	visit = func(step EnricherStep, path []string) error {
+		name := step.Name()
+		path = append(path, name)
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf(
+				"enricher steps have a dependency loop: %s",
+				strings.Join(path, " -> "),
+			)
+		}
+		states[name] = visiting
+		for _, dependency := range step.Dependencies() {
+			err := visit(index[dependency], path)
+			if err != nil {
+				return err
+			}
+		}
+		states[name] = visited
+		if selected[name] {
+			result = append(result, step)
+		}
+		return nil
+	}
+	for _, step := range steps {
+		err = visit(step, nil)
+		if err != nil {
+			result = nil
+			return
+		}
 	}
 	return
 }
@@ -160,34 +564,274 @@ func (b *EnricherBuilder) createResolver(address string) (result *net.Resolver,
 }
 
 // Enrich completes the configuration adding the information that will be required later to create
-// the clusters.
+// the clusters. If there is a cache the result of a previous run will be used as the starting
+// point, so the steps will only need to calculate the information that is missing.
 func (e *Enricher) Enrich(ctx context.Context, config *models.Config) error {
-	err := e.enrichConfig(ctx, config)
-	if err != nil {
-		return err
+	var key string
+	if e.cache != nil {
+		var err error
+		key, err = e.cache.Load(ctx, config)
+		if err != nil {
+			return err
+		}
 	}
-	for _, cluster := range config.Clusters {
-		err := e.enrichCluster(ctx, config, cluster)
+	for _, name := range e.skipped {
+		e.fireEvent(&EnricherEvent{
+			Type: EnricherStepSkipped,
+			Step: name,
+		})
+	}
+	for _, step := range e.steps {
+		e.logger.V(1).Info(
+			"Running enricher step",
+			"step", step.Name(),
+		)
+		e.fireEvent(&EnricherEvent{
+			Type: EnricherStepStarted,
+			Step: step.Name(),
+		})
+		err := step.EnrichConfig(ctx, config)
 		if err != nil {
+			e.fireEvent(&EnricherEvent{
+				Type:  EnricherStepFailed,
+				Step:  step.Name(),
+				Error: err,
+			})
 			return err
 		}
+		for _, cluster := range config.Clusters {
+			err = step.EnrichCluster(ctx, config, cluster)
+			if err != nil {
+				e.fireEvent(&EnricherEvent{
+					Type:    EnricherStepFailed,
+					Step:    step.Name(),
+					Cluster: cluster.Name,
+					Error:   err,
+				})
+				return err
+			}
+			e.fireEvent(&EnricherEvent{
+				Type:    EnricherClusterEnriched,
+				Step:    step.Name(),
+				Cluster: cluster.Name,
+			})
+		}
+		e.fireEvent(&EnricherEvent{
+			Type: EnricherStepCompleted,
+			Step: step.Name(),
+		})
+	}
+	if e.cache != nil && !e.dryRun {
+		err := e.cache.Save(ctx, key, config)
+		if err != nil {
+			return fmt.Errorf("failed to save enricher state: %w", err)
+		}
 	}
 	return nil
 }
 
-func (e *Enricher) enrichConfig(ctx context.Context, config *models.Config) error {
-	setters := []func(context.Context, *models.Config) error{
-		e.setOCPTag,
-		e.setRHCOSRelease,
-		e.setConfigImageSet,
-		e.setConfigRegistry,
+func (e *Enricher) fireEvent(event *EnricherEvent) {
+	for _, listener := range e.listeners {
+		listener(event)
 	}
-	for _, setter := range setters {
-		err := setter(ctx, config)
-		if err != nil {
-			return err
+}
+
+// Steps returns the names of the steps that the enricher will run, in the order that they will
+// run.
+func (e *Enricher) Steps() []string {
+	result := make([]string, len(e.steps))
+	for i, step := range e.steps {
+		result[i] = step.Name()
+	}
+	return result
+}
+
+// builtinSteps returns the steps that are built into the enricher, in the default order.
+func (e *Enricher) builtinSteps() []EnricherStep {
+	return []EnricherStep{
+		&EnricherStepFunc{
+			StepName:    EnricherStepArchitecture,
+			ConfigFunc:  e.setArchitecture,
+			ClusterFunc: e.setClusterArchitecture,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepOCPTag,
+			StepDependencies: []string{EnricherStepArchitecture},
+			ConfigFunc:       e.setOCPTag,
+			ClusterFunc:      e.setClusterOCPTag,
+		},
+		&EnricherStepFunc{
+			StepName:   EnricherStepConfigImageSet,
+			ConfigFunc: e.setConfigImageSet,
+		},
+		&EnricherStepFunc{
+			StepName: EnricherStepRHCOSRelease,
+			StepDependencies: []string{
+				EnricherStepArchitecture,
+				EnricherStepConfigImageSet,
+				EnricherStepClusterImageSet,
+			},
+			ConfigFunc:  e.setRHCOSRelease,
+			ClusterFunc: e.setClusterRHCOSRelease,
+		},
+		&EnricherStepFunc{
+			StepName:   EnricherStepConfigRegistry,
+			ConfigFunc: e.setConfigRegistry,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepSNO,
+			ClusterFunc: e.setSNO,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepSecrets,
+			ClusterFunc: e.resolveSecrets,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepPullSecret,
+			StepDependencies: []string{EnricherStepSecrets},
+			ClusterFunc:      e.setPullSecret,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepSSHKeys,
+			ClusterFunc: e.setSSHKeys,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepDNSDomain,
+			ClusterFunc: e.setDNSDomain,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepNetworks,
+			ClusterFunc: e.setNetworks,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepInternalAPIIP,
+			StepDependencies: []string{EnricherStepNetworks},
+			ClusterFunc:      e.setInternalAPIIP,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepInternalIngressIP,
+			StepDependencies: []string{EnricherStepNetworks},
+			ClusterFunc:      e.setInternalIngressIP,
+		},
+		&EnricherStepFunc{
+			StepName: EnricherStepInternalNodeIPs,
+			StepDependencies: []string{
+				EnricherStepNetworks,
+				EnricherStepInternalAPIIP,
+				EnricherStepInternalIngressIP,
+			},
+			ClusterFunc: e.setInternalNodeIPs,
+		},
+		&EnricherStepFunc{
+			StepName: EnricherStepCheckNetworks,
+			StepDependencies: []string{
+				EnricherStepNetworks,
+				EnricherStepInternalAPIIP,
+				EnricherStepInternalIngressIP,
+			},
+			ClusterFunc: e.checkNetworks,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepExternalNodeIPs,
+			ClusterFunc: e.setExternalNodeIPs,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepKubeconfig,
+			ClusterFunc: e.setKubeconfig,
+		},
+		&EnricherStepFunc{
+			StepName: EnricherStepClusterImageSet,
+			StepDependencies: []string{
+				EnricherStepArchitecture,
+				EnricherStepConfigImageSet,
+			},
+			ClusterFunc: e.setClusterImageSet,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepRegistryURL,
+			StepDependencies: []string{EnricherStepConfigRegistry},
+			ClusterFunc:      e.setClusterRegistryURL,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepRegistryCA,
+			StepDependencies: []string{EnricherStepRegistryURL},
+			ClusterFunc:      e.setClusterRegistryCA,
+		},
+		&EnricherStepFunc{
+			StepName:    EnricherStepHostnames,
+			ClusterFunc: e.setHostnames,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepExternalAPIIP,
+			StepDependencies: []string{EnricherStepDNSDomain},
+			ClusterFunc:      e.setExternalAPIIP,
+		},
+		&EnricherStepFunc{
+			StepName:         EnricherStepExternalIngressIP,
+			StepDependencies: []string{EnricherStepDNSDomain},
+			ClusterFunc:      e.setExternalIngressIP,
+		},
+		&EnricherStepFunc{
+			StepName: EnricherStepDNSRecords,
+			StepDependencies: []string{
+				EnricherStepDNSDomain,
+				EnricherStepExternalAPIIP,
+				EnricherStepExternalIngressIP,
+			},
+			ClusterFunc: e.publishDNSRecords,
+		},
+	}
+}
+
+func (e *Enricher) setArchitecture(ctx context.Context, config *models.Config) error {
+	// Check the value if it is already set:
+	architecture := config.Properties[models.OCPArchitectureProperty]
+	if architecture != "" {
+		if !slices.Contains(models.Architectures, models.Architecture(architecture)) {
+			return fmt.Errorf(
+				"architecture '%s' of property '%s' isn't supported, valid values "+
+					"are %s",
+				architecture, models.OCPArchitectureProperty,
+				enricherArchitectureList(),
+			)
 		}
+		return nil
 	}
+
+	// Set the default value:
+	architecture = string(models.ArchitectureX86_64)
+	config.Properties[models.OCPArchitectureProperty] = architecture
+	e.logger.Info(
+		"Set architecture property",
+		"name", models.OCPArchitectureProperty,
+		"value", architecture,
+	)
+	return nil
+}
+
+func (e *Enricher) setClusterArchitecture(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	// Check the value if it is already set:
+	if cluster.Architecture != "" {
+		if !slices.Contains(models.Architectures, cluster.Architecture) {
+			return fmt.Errorf(
+				"architecture '%s' of cluster '%s' isn't supported, valid values "+
+					"are %s",
+				cluster.Architecture, cluster.Name, enricherArchitectureList(),
+			)
+		}
+		return nil
+	}
+
+	// Use the value of the property:
+	cluster.Architecture = models.Architecture(
+		config.Properties[models.OCPArchitectureProperty],
+	)
+	e.logger.Info(
+		"Set cluster architecture",
+		"cluster", cluster.Name,
+		"value", cluster.Architecture,
+	)
 	return nil
 }
 
@@ -208,7 +852,10 @@ func (e *Enricher) setOCPTag(ctx context.Context, config *models.Config) error {
 	}
 
 	// Calculate the tag:
-	ocpTag = fmt.Sprintf("%s-x86_64", ocpVersion)
+	ocpTag = fmt.Sprintf(
+		"%s-%s",
+		ocpVersion, config.Properties[models.OCPArchitectureProperty],
+	)
 
 	// Update the properties:
 	config.Properties[models.OCPTagProperty] = ocpTag
@@ -220,6 +867,34 @@ func (e *Enricher) setOCPTag(ctx context.Context, config *models.Config) error {
 	return nil
 }
 
+// setClusterOCPTag sets the OCP tag of the cluster. Clusters that use the default architecture
+// use the value of the property, and the rest use the same version with their own architecture.
+func (e *Enricher) setClusterOCPTag(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	// Do nothing if already set:
+	if cluster.OCPTag != "" {
+		return nil
+	}
+
+	// Calculate the tag:
+	ocpTag := config.Properties[models.OCPTagProperty]
+	if string(cluster.Architecture) != config.Properties[models.OCPArchitectureProperty] {
+		ocpTag = fmt.Sprintf(
+			"%s-%s",
+			config.Properties[models.OCPVersionProperty], cluster.Architecture,
+		)
+	}
+
+	// Set the value:
+	cluster.OCPTag = ocpTag
+	e.logger.Info(
+		"Set cluster OCP tag",
+		"cluster", cluster.Name,
+		"value", ocpTag,
+	)
+	return nil
+}
+
 func (e *Enricher) setRHCOSRelease(ctx context.Context, config *models.Config) error {
 	// Do nothing if it is already set:
 	rhcosRelease := config.Properties[models.OCPRCHOSReleaseProperty]
@@ -236,8 +911,8 @@ func (e *Enricher) setRHCOSRelease(ctx context.Context, config *models.Config) e
 		)
 	}
 
-	// Download the `release.txt` file:
-	releaseTXT, err := e.downloadReleaseTXT(ctx, config)
+	// Get the release information, in the same format than the `release.txt` file:
+	releaseTXT, source, err := e.getReleaseTXT(ctx, config)
 	if err != nil {
 		return err
 	}
@@ -245,7 +920,7 @@ func (e *Enricher) setRHCOSRelease(ctx context.Context, config *models.Config) e
 	// Extract the RHCOS release:
 	rhcosReleaseMatches := enricherRHCOSReleaseRE.FindStringSubmatch(string(releaseTXT))
 	if len(rhcosReleaseMatches) < 2 {
-		return fmt.Errorf("failed to find RHCOS release inside 'release.txt' file")
+		return fmt.Errorf("failed to find RHCOS release inside %s", source)
 	}
 	rchosRelease := rhcosReleaseMatches[1]
 
@@ -260,11 +935,260 @@ func (e *Enricher) setRHCOSRelease(ctx context.Context, config *models.Config) e
 	return nil
 }
 
-func (e *Enricher) downloadReleaseTXT(ctx context.Context, config *models.Config) (result string,
+// setClusterRHCOSRelease sets the RHCOS release of the cluster. Clusters that use the default
+// architecture use the value of the property, and the rest extract it from the release information
+// of their own architecture.
+func (e *Enricher) setClusterRHCOSRelease(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	// Do nothing if already set:
+	if cluster.RHCOSRelease != "" {
+		return nil
+	}
+
+	// Use the property for the default architecture:
+	rhcosRelease := config.Properties[models.OCPRCHOSReleaseProperty]
+	if string(cluster.Architecture) != config.Properties[models.OCPArchitectureProperty] {
+		releaseTXT, source, err := e.getArchitectureReleaseTXT(ctx, config, cluster)
+		if err != nil {
+			return err
+		}
+		rhcosReleaseMatches := enricherRHCOSReleaseRE.FindStringSubmatch(releaseTXT)
+		if len(rhcosReleaseMatches) < 2 {
+			return fmt.Errorf(
+				"failed to find RHCOS release of cluster '%s' inside %s",
+				cluster.Name, source,
+			)
+		}
+		rhcosRelease = rhcosReleaseMatches[1]
+	}
+
+	// Set the value:
+	cluster.RHCOSRelease = rhcosRelease
+	e.logger.Info(
+		"Set cluster RHCOS release",
+		"cluster", cluster.Name,
+		"value", rhcosRelease,
+	)
+	return nil
+}
+
+// getArchitectureReleaseTXT gets the release information for a cluster that doesn't use the default
+// architecture. The local release file and the release image given with the command line flags
+// are for the default architecture, so they aren't used. In offline mode the information is taken
+// from the release image of the cluster, and otherwise it is downloaded from the mirror.
+func (e *Enricher) getArchitectureReleaseTXT(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) (result string, source string, err error) {
+	if e.offline {
+		if cluster.ReleaseImage == "" {
+			err = fmt.Errorf(
+				"failed to get release information for architecture '%s' of "+
+					"cluster '%s' in offline mode because the release image isn't "+
+					"known",
+				cluster.Architecture, cluster.Name,
+			)
+			return
+		}
+		source = fmt.Sprintf("release image '%s'", cluster.ReleaseImage)
+		result, err = e.inspectReleaseImage(ctx, cluster.ReleaseImage)
+		return
+	}
+	source = "'release.txt' file"
+	result, err = e.downloadReleaseTXT(ctx, config, cluster.Architecture)
+	return
+}
+
+// getReleaseTXT gets the release information from the first source available: the local file, the
+// release image or the mirror. In offline mode the release image is taken from the cluster image
+// set of the hub if it hasn't been explicitly given, and the mirror is never used. The returned
+// source is a description of where the information was taken from, intended for error messages.
+func (e *Enricher) getReleaseTXT(ctx context.Context, config *models.Config) (result string,
+	source string, err error) {
+	// The local file has priority over everything else:
+	if e.releaseFile != "" {
+		source = fmt.Sprintf("release file '%s'", e.releaseFile)
+		var data []byte
+		data, err = os.ReadFile(e.releaseFile)
+		if err != nil {
+			err = fmt.Errorf("failed to read release file: %w", err)
+			return
+		}
+		result = string(data)
+		e.logger.Info(
+			"Loaded release file",
+			"file", e.releaseFile,
+		)
+		return
+	}
+
+	// Then the release image, either the explicitly given one or the one from the cluster image
+	// set when in offline mode:
+	image := e.releaseImage
+	if image == "" && e.offline {
+		image, err = e.getImageSetReleaseImage(ctx, config)
+		if err != nil {
+			return
+		}
+	}
+	if image != "" {
+		source = fmt.Sprintf("release image '%s'", image)
+		result, err = e.inspectReleaseImage(ctx, image)
+		return
+	}
+
+	// Finally the mirror:
+	source = "'release.txt' file"
+	result, err = e.downloadReleaseTXT(
+		ctx, config,
+		models.Architecture(config.Properties[models.OCPArchitectureProperty]),
+	)
+	return
+}
+
+// getImageSetReleaseImage returns the release image of the cluster image set of the hub that
+// corresponds to the configuration.
+func (e *Enricher) getImageSetReleaseImage(ctx context.Context,
+	config *models.Config) (result string, err error) {
+	name := config.Properties[models.ClusterImageSetProperty]
+	if name == "" {
+		err = fmt.Errorf(
+			"failed to get release image in offline mode because property '%s' "+
+				"hasn't been specified",
+			models.ClusterImageSetProperty,
+		)
+		return
+	}
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ClusterImageSetGVK)
+	key := clnt.ObjectKey{
+		Name: name,
+	}
+	err = e.client.Get(ctx, key, object)
+	if apierrors.IsNotFound(err) {
+		err = fmt.Errorf(
+			"failed to get release image in offline mode because cluster image set "+
+				"'%s' doesn't exist, create it, set property '%s' or use the "+
+				"'--%s' or '--%s' flags",
+			name, models.OCPRCHOSReleaseProperty,
+			enricherReleaseFileFlagName, enricherReleaseImageFlagName,
+		)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to get release image from cluster image set '%s': %w",
+			name, err,
+		)
+		return
+	}
+	err = e.jq.Query(`.spec.releaseImage`, object, &result)
+	if err != nil {
+		return
+	}
+	if result == "" {
+		err = fmt.Errorf(
+			"failed to get release image in offline mode because cluster image set "+
+				"'%s' doesn't have a release image",
+			name,
+		)
+		return
+	}
+	e.logger.Info(
+		"Found release image in cluster image set",
+		"imageset", name,
+		"image", result,
+	)
+	return
+}
+
+// inspectReleaseImage uses the `oc adm release info` command to get the information of the given
+// release image. The output of that command has the same format than the `release.txt` file. The
+// pull secret of the hub is used to authenticate to the registry.
+func (e *Enricher) inspectReleaseImage(ctx context.Context, image string) (result string,
 	err error) {
+	ocPath, err := exec.LookPath("oc")
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to find the 'oc' command needed to inspect release image '%s': %w",
+			image, err,
+		)
+		return
+	}
+
+	// Write the pull secret of the hub to a temporary file, so that the command can use it:
+	pullSecret, err := e.getHubPullSecret(ctx)
+	if err != nil {
+		return
+	}
+	tmpDir, err := os.MkdirTemp("", "*.release")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpAuth := filepath.Join(tmpDir, "auth.json")
+	err = os.WriteFile(tmpAuth, pullSecret, 0600)
+	if err != nil {
+		return
+	}
+
+	// Run the command:
+	ocOut := &bytes.Buffer{}
+	ocErr := &bytes.Buffer{}
+	ocCmd := exec.CommandContext(
+		ctx, ocPath,
+		"adm", "release", "info",
+		"--registry-config", tmpAuth,
+		image,
+	)
+	ocCmd.Dir = tmpDir
+	ocCmd.Stdout = ocOut
+	ocCmd.Stderr = ocErr
+	err = ocCmd.Run()
+	e.logger.V(2).Info(
+		"Executed release info command",
+		"args", ocCmd.Args,
+		"stdout", ocOut.String(),
+		"stderr", ocErr.String(),
+		"code", ocCmd.ProcessState.ExitCode(),
+	)
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to inspect release image '%s': %w: %s",
+			image, err, strings.TrimSpace(ocErr.String()),
+		)
+		return
+	}
+	result = ocOut.String()
+	e.logger.Info(
+		"Inspected release image",
+		"image", image,
+	)
+	return
+}
+
+// getHubPullSecret returns the content of the pull secret of the hub.
+func (e *Enricher) getHubPullSecret(ctx context.Context) (result []byte, err error) {
+	secret := &corev1.Secret{}
+	key := clnt.ObjectKey{
+		Namespace: "openshift-config",
+		Name:      "pull-secret",
+	}
+	err = e.client.Get(ctx, key, secret)
+	if err != nil {
+		err = fmt.Errorf("failed to get pull secret: %w", err)
+		return
+	}
+	result, ok := secret.Data[".dockerconfigjson"]
+	if !ok {
+		err = fmt.Errorf("pull secret doesn't contain the '.dockerconfigjson' key")
+	}
+	return
+}
+
+func (e *Enricher) downloadReleaseTXT(ctx context.Context, config *models.Config,
+	architecture models.Architecture) (result string, err error) {
 	mirror := config.Properties[models.OCPMirrorProperty]
 	if mirror == "" {
-		mirror = enricherDefaultMirror
+		mirror = fmt.Sprintf(enricherDefaultMirror, architecture)
 	}
 	version := config.Properties[models.OCPVersionProperty]
 	url := fmt.Sprintf(
@@ -318,7 +1242,7 @@ func (e *Enricher) setConfigImageSet(ctx context.Context, config *models.Config)
 	}
 
 	// Calculate the default value and save
-	imageSet = fmt.Sprintf("openshift-v%s", ocpVersion)
+	imageSet = enricherImageSetName(config.Properties, "")
 	config.Properties[models.ClusterImageSetProperty] = imageSet
 	e.logger.Info(
 		"Set image set property",
@@ -377,44 +1301,34 @@ func (e *Enricher) setConfigRegistry(ctx context.Context, config *models.Config)
 	return nil
 }
 
-func (e *Enricher) enrichCluster(ctx context.Context, config *models.Config,
+func (e *Enricher) setSNO(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
-	setters := []func(context.Context, *models.Config, *models.Cluster) error{
-		e.setSNO,
-		e.setPullSecret,
-		e.setSSHKeys,
-		e.setDNSDomain,
-		e.setInternalAPIIP,
-		e.setInternalIngressIP,
-		e.setNetworks,
-		e.setInternalNodeIPs,
-		e.setExternalNodeIPs,
-		e.setKubeconfig,
-		e.setClusterImageSet,
-		e.setClusterRegistryURL,
-		e.setClusterRegistryCA,
-		e.setHostnames,
-		e.setExternalAPIIP,
-		e.setExternalIngressIP,
-	}
-	for _, setter := range setters {
-		err := setter(ctx, config, cluster)
-		if err != nil {
-			return err
+	count := 0
+	for _, node := range cluster.Nodes {
+		if node.Kind == models.NodeKindControlPlane {
+			count++
 		}
 	}
+	cluster.SNO = count == 1
 	return nil
 }
 
-func (e *Enricher) setSNO(ctx context.Context, config *models.Config,
+func (e *Enricher) resolveSecrets(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
-	count := 0
+	err := e.secrets.Resolve(ctx, cluster.PullSecretRef)
+	if err != nil {
+		return err
+	}
 	for _, node := range cluster.Nodes {
-		if node.Kind == models.NodeKindControlPlane {
-			count++
+		err = e.secrets.Resolve(ctx, node.BMC.User)
+		if err != nil {
+			return err
+		}
+		err = e.secrets.Resolve(ctx, node.BMC.Pass)
+		if err != nil {
+			return err
 		}
 	}
-	cluster.SNO = count == 1
 	return nil
 }
 
@@ -423,24 +1337,29 @@ func (e *Enricher) setPullSecret(ctx context.Context, config *models.Config,
 	if cluster.PullSecret != nil {
 		return nil
 	}
-	secret := &corev1.Secret{}
-	key := clnt.ObjectKey{
-		Namespace: "openshift-config",
-		Name:      "pull-secret",
+
+	// If the configuration contains an explicit pull secret then use it instead of the one from
+	// the hub:
+	if cluster.PullSecretRef != nil {
+		value, err := cluster.PullSecretRef.Value()
+		if err != nil {
+			return err
+		}
+		cluster.PullSecret = []byte(value)
+		e.logger.Info(
+			"Loaded pull secret",
+			"ref", cluster.PullSecretRef,
+		)
+		return nil
 	}
-	err := e.client.Get(ctx, key, secret)
+	data, err := e.getHubPullSecret(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get pull secret: %w", err)
-	}
-	data, ok := secret.Data[".dockerconfigjson"]
-	if !ok {
-		return fmt.Errorf("pull secret doesn't contain the '.dockerconfigjson' key")
+		return err
 	}
 	cluster.PullSecret = data
 	e.logger.Info(
 		"Loaded pull secret",
-		"secret", fmt.Sprintf("%s/%s", secret.Namespace, secret.Name),
-		"!content", string(cluster.PullSecret),
+		"secret", "openshift-config/pull-secret",
 	)
 	return nil
 }
@@ -556,7 +1475,15 @@ func (e *Enricher) setInternalAPIIP(ctx context.Context, config *models.Config,
 	if cluster.API.InternalIP != nil {
 		return nil
 	}
-	cluster.API.InternalIP = enricherInternalAPIIP
+	address, err := e.machineAddress(cluster, enricherInternalAPIOffset)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to calculate internal API IP for cluster '%s', set it explicitly "+
+				"with the 'api_vip' field: %w",
+			cluster.Name, err,
+		)
+	}
+	cluster.API.InternalIP = address
 	e.logger.Info(
 		"Found internal API IP",
 		"value", cluster.API.InternalIP,
@@ -569,7 +1496,15 @@ func (e *Enricher) setInternalIngressIP(ctx context.Context, config *models.Conf
 	if cluster.Ingress.InternalIP != nil {
 		return nil
 	}
-	cluster.Ingress.InternalIP = enricherInternalIngressIP
+	address, err := e.machineAddress(cluster, enricherInternalIngressOffset)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to calculate internal ingress IP for cluster '%s', set it "+
+				"explicitly with the 'ingress_vip' field: %w",
+			cluster.Name, err,
+		)
+	}
+	cluster.Ingress.InternalIP = address
 	e.logger.Info(
 		"Found internal ingress IP",
 		"value", cluster.Ingress.InternalIP,
@@ -579,52 +1514,300 @@ func (e *Enricher) setInternalIngressIP(ctx context.Context, config *models.Conf
 
 func (e *Enricher) setNetworks(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
-	cluster.ClusterNetworks = []*models.ClusterNetwork{{
-		CIDR:       enricherClusterCIDR,
-		HostPrefix: enricherHostPrefix,
-	}}
-	cluster.MachineNetworks = []*models.MachineNetwork{{
-		CIDR: enricherMachineCIDR,
-	}}
-	cluster.ServiceNetworks = []*models.ServiceNetwork{{
-		CIDR: enricherServiceCIDR,
-	}}
+	// The machine networks determine the IP families used by the cluster, so they go first:
+	if len(cluster.MachineNetworks) == 0 {
+		cluster.MachineNetworks = []*models.MachineNetwork{{
+			CIDR: enricherMachineCIDR,
+		}}
+	}
+
+	// For the cluster and service networks the default is one network for each of the families
+	// of the machine networks, in the same order:
+	if len(cluster.ClusterNetworks) == 0 {
+		for _, machineNetwork := range cluster.MachineNetworks {
+			cidr := enricherClusterCIDR
+			if machineNetwork.CIDR.IP.To4() == nil {
+				cidr = enricherClusterCIDRv6
+			}
+			cluster.ClusterNetworks = append(cluster.ClusterNetworks, &models.ClusterNetwork{
+				CIDR: cidr,
+			})
+		}
+	}
+	for _, network := range cluster.ClusterNetworks {
+		if network.HostPrefix == 0 {
+			network.HostPrefix = enricherHostPrefix
+			if network.CIDR.IP.To4() == nil {
+				network.HostPrefix = enricherHostPrefixv6
+			}
+		}
+	}
+	if len(cluster.ServiceNetworks) == 0 {
+		for _, machineNetwork := range cluster.MachineNetworks {
+			cidr := enricherServiceCIDR
+			if machineNetwork.CIDR.IP.To4() == nil {
+				cidr = enricherServiceCIDRv6
+			}
+			cluster.ServiceNetworks = append(cluster.ServiceNetworks, &models.ServiceNetwork{
+				CIDR: cidr,
+			})
+		}
+	}
 	return nil
 }
 
 func (e *Enricher) setInternalNodeIPs(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
-	for i, node := range cluster.Nodes {
-		err := e.setInternalNodeIP(ctx, i, node)
-		if err != nil {
-			return err
+	return e.ipam.Allocate(ctx, cluster)
+}
+
+// machineAddress calculates the address that is at the given offset from the beginning of the
+// first machine network of the cluster. It returns an error if that address is outside of the
+// network.
+func (e *Enricher) machineAddress(cluster *models.Cluster, offset int) (result net.IP,
+	err error) {
+	if len(cluster.MachineNetworks) == 0 {
+		err = errors.New("there are no machine networks")
+		return
+	}
+	result, err = enricherNetworkAddress(cluster.MachineNetworks[0].CIDR, offset)
+	return
+}
+
+// checkNetworks checks that the networks of the cluster don't overlap each other, that the
+// virtual IP addresses are inside the machine network, and that the networks don't overlap the
+// networks of the hub cluster.
+func (e *Enricher) checkNetworks(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	// Collect the networks of the cluster, with a description that we can use in error messages:
+	type Network struct {
+		Kind string
+		CIDR *net.IPNet
+	}
+	var networks []Network
+	for _, network := range cluster.ClusterNetworks {
+		networks = append(networks, Network{"cluster", network.CIDR})
+		size, bits := network.CIDR.Mask.Size()
+		if network.HostPrefix < size || network.HostPrefix > bits {
+			return fmt.Errorf(
+				"host prefix %d of cluster network '%s' of cluster '%s' should "+
+					"be between %d and %d",
+				network.HostPrefix, network.CIDR, cluster.Name, size, bits,
+			)
+		}
+	}
+	for _, network := range cluster.MachineNetworks {
+		networks = append(networks, Network{"machine", network.CIDR})
+	}
+	for _, network := range cluster.ServiceNetworks {
+		networks = append(networks, Network{"service", network.CIDR})
+	}
+
+	// Check that the cluster and service networks use the same IP families than the machine
+	// networks, and in the same order, as the first one is the primary family of the cluster:
+	machineFamilies := make([]bool, len(cluster.MachineNetworks))
+	for i, network := range cluster.MachineNetworks {
+		machineFamilies[i] = network.CIDR.IP.To4() == nil
+	}
+	clusterFamilies := make([]bool, len(cluster.ClusterNetworks))
+	for i, network := range cluster.ClusterNetworks {
+		clusterFamilies[i] = network.CIDR.IP.To4() == nil
+	}
+	serviceFamilies := make([]bool, len(cluster.ServiceNetworks))
+	for i, network := range cluster.ServiceNetworks {
+		serviceFamilies[i] = network.CIDR.IP.To4() == nil
+	}
+	if len(machineFamilies) > 2 {
+		return fmt.Errorf(
+			"cluster '%s' has %d machine networks, but at most two are supported, one "+
+				"for IPv4 and one for IPv6",
+			cluster.Name, len(machineFamilies),
+		)
+	}
+	if len(machineFamilies) == 2 && machineFamilies[0] == machineFamilies[1] {
+		return fmt.Errorf(
+			"machine networks of dual stack cluster '%s' should be one IPv4 and "+
+				"one IPv6",
+			cluster.Name,
+		)
+	}
+	if !slices.Equal(machineFamilies, clusterFamilies) ||
+		!slices.Equal(machineFamilies, serviceFamilies) {
+		return fmt.Errorf(
+			"cluster and service networks of cluster '%s' should have the same IP "+
+				"families, and in the same order, than the machine networks",
+			cluster.Name,
+		)
+	}
+
+	// Check that they don't overlap each other:
+	for i := 0; i < len(networks); i++ {
+		for j := i + 1; j < len(networks); j++ {
+			if enricherOverlap(networks[i].CIDR, networks[j].CIDR) {
+				return fmt.Errorf(
+					"%s network '%s' and %s network '%s' of cluster '%s' overlap",
+					networks[i].Kind, networks[i].CIDR,
+					networks[j].Kind, networks[j].CIDR,
+					cluster.Name,
+				)
+			}
+		}
+	}
+
+	// Check that the virtual IP addresses are inside a machine network and different:
+	vips := []struct {
+		Name    string
+		Address net.IP
+	}{
+		{"API", cluster.API.InternalIP},
+		{"ingress", cluster.Ingress.InternalIP},
+	}
+	for _, vip := range vips {
+		found := false
+		for _, network := range cluster.MachineNetworks {
+			if network.CIDR.Contains(vip.Address) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf(
+				"internal %s IP '%s' of cluster '%s' isn't inside any machine network",
+				vip.Name, vip.Address, cluster.Name,
+			)
+		}
+	}
+	if cluster.API.InternalIP.Equal(cluster.Ingress.InternalIP) {
+		return fmt.Errorf(
+			"internal API and ingress IPs of cluster '%s' are the same '%s'",
+			cluster.Name, cluster.API.InternalIP,
+		)
+	}
+
+	// Check that they don't overlap the networks of the hub. Note that the cluster and service
+	// networks of the hub and of the edge cluster can be the same, and they are by default,
+	// because they are only used inside each cluster. But the machine networks are visible
+	// outside, so they can't overlap with any of the networks of the other cluster.
+	hubMachine, hubInternal, err := e.getHubNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		hubNetworks := hubMachine
+		if network.Kind == "machine" {
+			hubNetworks = append(slices.Clone(hubMachine), hubInternal...)
+		}
+		for _, hubNetwork := range hubNetworks {
+			if enricherOverlap(network.CIDR, hubNetwork) {
+				return fmt.Errorf(
+					"%s network '%s' of cluster '%s' overlaps network '%s' of "+
+						"the hub",
+					network.Kind, network.CIDR, cluster.Name, hubNetwork,
+				)
+			}
 		}
 	}
+
 	return nil
 }
 
-func (e *Enricher) setInternalNodeIP(ctx context.Context, index int, node *models.Node) error {
-	// Do nothing if the IP is already set:
-	if node.InternalIP != nil {
-		return nil
+// getHubNetworks returns the machine networks of the hub and the cluster and service networks. If
+// the hub doesn't have the objects that contain this information the result will be empty.
+func (e *Enricher) getHubNetworks(ctx context.Context) (machine, internal []*net.IPNet,
+	err error) {
+	// The cluster and service networks are in the network configuration:
+	network := &unstructured.Unstructured{}
+	network.SetGroupVersionKind(NetworkConfigGVK)
+	networkKey := clnt.ObjectKey{
+		Name: "cluster",
+	}
+	err = e.client.Get(ctx, networkKey, network)
+	switch {
+	case err == nil:
+		var texts []string
+		err = e.jq.Query(
+			`[(.status.clusterNetwork[]?.cidr), (.status.serviceNetwork[]?)]`,
+			network.Object, &texts,
+		)
+		if err != nil {
+			return
+		}
+		internal, err = e.parseCIDRs(texts)
+		if err != nil {
+			return
+		}
+	case apierrors.IsNotFound(err) || meta.IsNoMatchError(err):
+		e.logger.V(1).Info("Hub network configuration isn't available")
+		err = nil
+	default:
+		return
 	}
 
-	// For nodes of the cluster we want to assign IP addresses within the machine network
-	// 192.168.7.0/24, starting with 192.168.7.10 for the first master node, 192.168.7.11 for
-	// the second one, and so on.
-	address := slices.Clone(enricherMachineCIDR.IP)
-	address[len(address)-1] = byte(10 + index)
-	prefix, _ := enricherMachineCIDR.Mask.Size()
-	node.InternalIP = &models.IP{
-		Address: address,
-		Prefix:  prefix,
+	// The machine networks are only in the installation configuration:
+	installConfig := &corev1.ConfigMap{}
+	installConfigKey := clnt.ObjectKey{
+		Namespace: "kube-system",
+		Name:      "cluster-config-v1",
+	}
+	err = e.client.Get(ctx, installConfigKey, installConfig)
+	switch {
+	case err == nil:
+		var data any
+		err = yaml.Unmarshal([]byte(installConfig.Data["install-config"]), &data)
+		if err != nil {
+			return
+		}
+		var texts []string
+		err = e.jq.Query(`[.networking.machineNetwork[]?.cidr]`, data, &texts)
+		if err != nil {
+			return
+		}
+		machine, err = e.parseCIDRs(texts)
+		if err != nil {
+			return
+		}
+	case apierrors.IsNotFound(err):
+		e.logger.V(1).Info("Hub installation configuration isn't available")
+		err = nil
+	default:
+		return
 	}
 
-	return nil
+	e.logger.V(1).Info(
+		"Found hub networks",
+		"machine", machine,
+		"internal", internal,
+	)
+	return
+}
+
+func (e *Enricher) parseCIDRs(texts []string) (result []*net.IPNet, err error) {
+	for _, text := range texts {
+		var cidr *net.IPNet
+		_, cidr, err = net.ParseCIDR(text)
+		if err != nil {
+			return
+		}
+		result = append(result, cidr)
+	}
+	return
 }
 
 func (e *Enricher) setExternalNodeIPs(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
+	// Nodes with a static external address already have it from the configuration, so there is
+	// no need to look at the agents if all the nodes are like that:
+	pending := false
+	for _, node := range cluster.Nodes {
+		if node.ExternalInterface() != "" && node.ExternalIP == nil {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return nil
+	}
+
 	// Fetch the agents for the nodes of the cluster:
 	agents := &unstructured.UnstructuredList{}
 	agents.SetGroupVersionKind(AgentListGVK)
@@ -633,7 +1816,9 @@ func (e *Enricher) setExternalNodeIPs(ctx context.Context, config *models.Config
 		return err
 	}
 
-	// Index the IP addresses by MAC address:
+	// Index the IP addresses by MAC address. When an interface has addresses of both families we
+	// prefer the IPv4 one, and we always ignore IPv6 link local addresses because they aren't
+	// reachable from outside of the link.
 	index := map[string]string{}
 	type Pair struct {
 		MAC string `json:"mac"`
@@ -644,7 +1829,13 @@ func (e *Enricher) setExternalNodeIPs(ctx context.Context, config *models.Config
 		err = e.jq.Query(
 			`
 				.status.inventory.interfaces[]? |
-				{ "mac": .macAddress, "ip": .ipV4Addresses[0] }
+				{
+					"mac": .macAddress,
+					"ip": (
+						(.ipV4Addresses // []) +
+						(.ipV6Addresses // [] | map(select(startswith("fe80:") | not)))
+					)[0]
+				}
 			`,
 			agent.Object, &pairs,
 		)
@@ -660,15 +1851,18 @@ func (e *Enricher) setExternalNodeIPs(ctx context.Context, config *models.Config
 
 	// Find the external IP addresses of the nodes:
 	for _, node := range cluster.Nodes {
-		if node.ExternalNIC == nil {
-			continue
-		}
 		if node.ExternalIP != nil {
 			continue
 		}
-		mac := node.ExternalNIC.MAC
-		ip, ok := index[strings.ToLower(mac)]
-		if ok {
+
+		// When the external network uses a bond the address may be reported for any of the
+		// members, so we try all of them:
+		for _, nic := range node.ExternalNICs() {
+			mac := nic.MAC
+			ip, ok := index[strings.ToLower(mac)]
+			if !ok {
+				continue
+			}
 			e.logger.Info(
 				"Found external IP address for node",
 				"cluster", cluster.Name,
@@ -680,6 +1874,7 @@ func (e *Enricher) setExternalNodeIPs(ctx context.Context, config *models.Config
 			if err != nil {
 				return err
 			}
+			break
 		}
 	}
 
@@ -733,6 +1928,22 @@ func (e *Enricher) setClusterImageSet(ctx context.Context, config *models.Config
 		)
 	}
 
+	// Clusters that don't use the default architecture need their own image set, pointing to the
+	// release image for their architecture. That image set is created together with the rest of
+	// the objects of the cluster, using the release image of the default one with the
+	// architecture suffix replaced.
+	architecture := config.Properties[models.OCPArchitectureProperty]
+	if cluster.Architecture != "" && string(cluster.Architecture) != architecture {
+		releaseImage, err := e.getArchitectureReleaseImage(
+			ctx, imageSet, architecture, string(cluster.Architecture),
+		)
+		if err != nil {
+			return err
+		}
+		cluster.ReleaseImage = releaseImage
+		imageSet = enricherImageSetName(config.Properties, cluster.Architecture)
+	}
+
 	// Set the value:
 	cluster.ImageSet = imageSet
 	e.logger.Info(
@@ -743,6 +1954,47 @@ func (e *Enricher) setClusterImageSet(ctx context.Context, config *models.Config
 	return nil
 }
 
+// getArchitectureReleaseImage calculates the release image for the given architecture from the
+// release image of the given cluster image set, which should be for the other given architecture.
+func (e *Enricher) getArchitectureReleaseImage(ctx context.Context, name, from,
+	to string) (result string, err error) {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ClusterImageSetGVK)
+	key := clnt.ObjectKey{
+		Name: name,
+	}
+	err = e.client.Get(ctx, key, object)
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to get release image from cluster image set '%s': %w",
+			name, err,
+		)
+		return
+	}
+	var image string
+	err = e.jq.Query(`.spec.releaseImage`, object, &image)
+	if err != nil {
+		return
+	}
+	suffix := "-" + from
+	if !strings.HasSuffix(image, suffix) {
+		err = fmt.Errorf(
+			"release image '%s' of cluster image set '%s' doesn't end with '%s', so "+
+				"it isn't possible to calculate the release image for architecture '%s'",
+			image, name, suffix, to,
+		)
+		return
+	}
+	result = strings.TrimSuffix(image, suffix) + "-" + to
+	e.logger.Info(
+		"Calculated release image for architecture",
+		"imageset", name,
+		"architecture", to,
+		"image", result,
+	)
+	return
+}
+
 func (e *Enricher) setClusterRegistryURL(ctx context.Context, config *models.Config,
 	cluster *models.Cluster) error {
 	// Do nothing if it is already set:
@@ -858,6 +2110,91 @@ func (e *Enricher) setExternalIngressIP(ctx context.Context, config *models.Conf
 	return nil
 }
 
+func (e *Enricher) publishDNSRecords(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	// Do nothing if there is no DNS provider:
+	if e.dnsProvider == nil {
+		return nil
+	}
+
+	// Check that the DNS domain is set:
+	if cluster.DNS.Domain == "" {
+		return fmt.Errorf("failed to publish DNS records because DNS domain isn't set")
+	}
+	zone := e.dnsZone
+	if zone == "" {
+		zone = cluster.DNS.Domain
+	}
+
+	// Publish the names of the API and the ingress, including the wildcard used by the
+	// applications. The addresses may be missing if the steps that calculate them have been
+	// skipped, and in that case we publish what we have, but fail if there is nothing at all, as
+	// the DNS provider was explicitly requested.
+	type record struct {
+		name    string
+		address net.IP
+	}
+	var records []record
+	if cluster.API.ExternalIP != nil {
+		records = append(records, record{
+			name:    fmt.Sprintf("api.%s.%s", cluster.Name, cluster.DNS.Domain),
+			address: cluster.API.ExternalIP,
+		})
+	}
+	if cluster.Ingress.ExternalIP != nil {
+		records = append(
+			records,
+			record{
+				name:    fmt.Sprintf("apps.%s.%s", cluster.Name, cluster.DNS.Domain),
+				address: cluster.Ingress.ExternalIP,
+			},
+			record{
+				name:    fmt.Sprintf("*.apps.%s.%s", cluster.Name, cluster.DNS.Domain),
+				address: cluster.Ingress.ExternalIP,
+			},
+		)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf(
+			"failed to publish DNS records of cluster '%s' because the external API "+
+				"and ingress IP addresses aren't known",
+			cluster.Name,
+		)
+	}
+	if cluster.API.ExternalIP == nil {
+		e.logger.Info(
+			"Not publishing DNS record for API because the external IP isn't known",
+			"cluster", cluster.Name,
+		)
+	}
+	if cluster.Ingress.ExternalIP == nil {
+		e.logger.Info(
+			"Not publishing DNS records for ingress because the external IP isn't known",
+			"cluster", cluster.Name,
+		)
+	}
+	for _, record := range records {
+		if e.dryRun {
+			e.logger.Info(
+				"Not publishing DNS record because of dry run",
+				"cluster", cluster.Name,
+				"zone", zone,
+				"name", record.name,
+				"address", record.address,
+			)
+			continue
+		}
+		err := e.dnsProvider.Publish(ctx, zone, record.name, record.address)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to publish DNS record '%s' of cluster '%s': %w",
+				record.name, cluster.Name, err,
+			)
+		}
+	}
+	return nil
+}
+
 func (e *Enricher) getCA(address string) (result []byte, err error) {
 	// Connect to the server and do the TLS handshake to obtain the certificate chain:
 	conn, err := tls.Dial("tcp", address, &tls.Config{
@@ -901,20 +2238,29 @@ func (e *Enricher) setHostname(ctx context.Context, config *models.Config,
 	if node.Hostname != "" {
 		return nil
 	}
-	var kind string
-	switch node.Kind {
-	case models.NodeKindControlPlane:
-		kind = "master"
-	case models.NodeKindWorker:
-		kind = "worker"
-	default:
+	hostname := node.DefaultHostname(cluster.Name)
+	if hostname == "" {
 		return fmt.Errorf(
 			"failed to set hostname for node '%s' of cluster '%s' because node "+
 				"kind '%s' is unknown",
 			node.Name, cluster.Name, node.Kind,
 		)
 	}
-	node.Hostname = fmt.Sprintf("ztpfw-%s-%s-%s", cluster.Name, kind, node.Index())
+	errs := validation.IsDNS1123Label(hostname)
+	if len(errs) > 0 {
+		return fmt.Errorf(
+			"failed to set hostname for node '%s' of cluster '%s' because "+
+				"generated hostname '%s' isn't valid: %s",
+			node.Name, cluster.Name, hostname, strings.Join(errs, ", "),
+		)
+	}
+	node.Hostname = hostname
+	e.logger.V(1).Info(
+		"Set node hostname",
+		"cluster", cluster.Name,
+		"node", node.Name,
+		"hostname", node.Hostname,
+	)
 	return nil
 }
 
@@ -995,22 +2341,29 @@ func (e *Enricher) resolveDomain(ctx context.Context, domain string) (result str
 	return
 }
 
-// Hardcoded blocks of addresses used by the cluster, the machines and the services (assigned in the
+// Default blocks of addresses used by the cluster, the machines and the services (assigned in the
 // init function below).
 var (
-	enricherClusterCIDR *net.IPNet
-	enricherMachineCIDR *net.IPNet
-	enricherServiceCIDR *net.IPNet
+	enricherClusterCIDR   *net.IPNet
+	enricherClusterCIDRv6 *net.IPNet
+	enricherMachineCIDR   *net.IPNet
+	enricherServiceCIDR   *net.IPNet
+	enricherServiceCIDRv6 *net.IPNet
 )
 
-// Hardcoded internal API and ingress IP addresses (assigned in the init function below).
-var (
-	enricherInternalAPIIP     net.IP
-	enricherInternalIngressIP net.IP
+// Default offsets, from the beginning of the machine network, of the internal API and ingress IP
+// addresses. With the default machine network these are 192.168.7.243 and 192.168.7.242. The
+// offset of the first node is in the IPAM.
+const (
+	enricherInternalAPIOffset     = 243
+	enricherInternalIngressOffset = 242
 )
 
-// Harccoded prefix for the block of addressed assigned to the hosts of the cluster.
-const enricherHostPrefix = 23
+// Default prefixes for the block of addressed assigned to the hosts of the cluster.
+const (
+	enricherHostPrefix   = 23
+	enricherHostPrefixv6 = 64
+)
 
 func init() {
 	var err error
@@ -1029,17 +2382,82 @@ func init() {
 		panic(err)
 	}
 
-	// Assign the internal IP addresses, 192.168.7.242 for the ingress and 192.168.7.243 for the
-	// API.
-	enricherInternalIngressIP = slices.Clone(enricherMachineCIDR.IP)
-	enricherInternalIngressIP[len(enricherInternalIngressIP)-1] = 242
-	enricherInternalAPIIP = slices.Clone(enricherMachineCIDR.IP)
-	enricherInternalAPIIP[len(enricherInternalAPIIP)-1] = 243
+	// These are used for the clusters that have IPv6 machine networks:
+	_, enricherClusterCIDRv6, err = net.ParseCIDR("fd01::/48")
+	if err != nil {
+		panic(err)
+	}
+	_, enricherServiceCIDRv6, err = net.ParseCIDR("fd02::/112")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// enricherArchitectureList returns a human readable list of the supported architectures, for use
+// in error messages.
+func enricherArchitectureList() string {
+	texts := make([]string, len(models.Architectures))
+	for i, architecture := range models.Architectures {
+		texts[i] = fmt.Sprintf("'%s'", architecture)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// enricherOverlap checks if the given networks overlap.
+func enricherOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// enricherNetworkAddress calculates the address that is at the given offset from the beginning of
+// the given network. It works for IPv4 and IPv6, and returns an error if that address is outside
+// of the network.
+func enricherNetworkAddress(cidr *net.IPNet, offset int) (result net.IP, err error) {
+	address := slices.Clone(cidr.IP)
+	carry := offset
+	for i := len(address) - 1; i >= 0 && carry > 0; i-- {
+		sum := int(address[i]) + carry
+		address[i] = byte(sum % 256)
+		carry = sum / 256
+	}
+	if carry > 0 || !cidr.Contains(address) {
+		err = fmt.Errorf(
+			"network '%s' is too small to contain an address at offset %d",
+			cidr, offset,
+		)
+		return
+	}
+	result = address
+	return
+}
+
+// enricherImageSetName calculates the name of the cluster image set for the given architecture from
+// the properties of the configuration. When the architecture is empty or is the default one the
+// result is the default image set, which is `openshift-v<version>` unless the image set property
+// has been set explicitly. For other architectures the result has the architecture as suffix. The
+// result will be empty if neither the image set nor the version properties have been set.
+func enricherImageSetName(properties map[string]string,
+	architecture models.Architecture) string {
+	name := properties[models.ClusterImageSetProperty]
+	if name == "" {
+		version := properties[models.OCPVersionProperty]
+		if version == "" {
+			return ""
+		}
+		name = fmt.Sprintf("openshift-v%s", version)
+	}
+	base := properties[models.OCPArchitectureProperty]
+	if base == "" {
+		base = string(models.ArchitectureX86_64)
+	}
+	if architecture != "" && string(architecture) != base {
+		name = fmt.Sprintf("%s-%s", name, architecture)
+	}
+	return name
 }
 
 // enricherDefaultMirror is the default base URL for downloading the `release.txt` files, used when
-// the `OC_OCP_MIRROR` property isn't set.
-const enricherDefaultMirror = "https://mirror.openshift.com/pub/openshift-v4/clients/ocp"
+// the `OC_OCP_MIRROR` property isn't set. The placeholder is replaced with the architecture.
+const enricherDefaultMirror = "https://mirror.openshift.com/pub/openshift-v4/%s/clients/ocp"
 
 // enricherRHCOSReleaseRE is the regular expressions used by the enricher to extract the RHCOS
 // release number for the OpenShift `release.txt` file. For example, if the file contains a line
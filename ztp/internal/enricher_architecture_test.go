@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/ghttp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Enricher architecture", func() {
+	var (
+		ctx      context.Context
+		logger   logr.Logger
+		client   clnt.Client
+		enricher *Enricher
+		config   *models.Config
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// The steps that depend on the architecture don't need to talk to the API server, so
+		// a fake client containing the default image set is enough:
+		imageSet := &unstructured.Unstructured{}
+		imageSet.SetGroupVersionKind(ClusterImageSetGVK)
+		imageSet.SetName("openshift-v4.10.38")
+		imageSet.Object["spec"] = map[string]any{
+			"releaseImage": "quay.io/openshift-release-dev/ocp-release:4.10.38-x86_64",
+		}
+		client = fake.NewClientBuilder().
+			WithObjects(imageSet).
+			Build()
+		enricher, err = NewEnricher().
+			SetLogger(logger).
+			SetClient(client).
+			SetSteps(EnricherStepOCPTag, EnricherStepClusterImageSet).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		config = &models.Config{
+			Properties: map[string]string{
+				models.OCPVersionProperty: "4.10.38",
+			},
+			Clusters: []*models.Cluster{{
+				Name: "my",
+			}},
+		}
+	})
+
+	It("Uses x86_64 by default", func() {
+		err := enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPArchitectureProperty, "x86_64"))
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPTagProperty, "4.10.38-x86_64"))
+		cluster := config.Clusters[0]
+		Expect(cluster.Architecture).To(Equal(models.ArchitectureX86_64))
+		Expect(cluster.ImageSet).To(Equal("openshift-v4.10.38"))
+	})
+
+	It("Uses the architecture property", func() {
+		config.Properties[models.OCPArchitectureProperty] = "aarch64"
+		err := enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPTagProperty, "4.10.38-aarch64"))
+		cluster := config.Clusters[0]
+		Expect(cluster.Architecture).To(Equal(models.ArchitectureAARCH64))
+		Expect(cluster.ImageSet).To(Equal("openshift-v4.10.38"))
+	})
+
+	It("Selects a different image set for clusters with other architecture", func() {
+		config.Clusters = append(config.Clusters, &models.Cluster{
+			Name:         "your",
+			Architecture: models.ArchitectureAARCH64,
+		})
+		err := enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPTagProperty, "4.10.38-x86_64"))
+		Expect(config.Clusters[0].ImageSet).To(Equal("openshift-v4.10.38"))
+		Expect(config.Clusters[0].ReleaseImage).To(BeEmpty())
+		Expect(config.Clusters[1].ImageSet).To(Equal("openshift-v4.10.38-aarch64"))
+		Expect(config.Clusters[1].ReleaseImage).To(Equal(
+			"quay.io/openshift-release-dev/ocp-release:4.10.38-aarch64",
+		))
+	})
+
+	It("Calculates the OCP tag of each cluster from its architecture", func() {
+		config.Clusters = append(config.Clusters, &models.Cluster{
+			Name:         "your",
+			Architecture: models.ArchitectureAARCH64,
+		})
+		err := enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPTagProperty, "4.10.38-x86_64"))
+		Expect(config.Clusters[0].OCPTag).To(Equal("4.10.38-x86_64"))
+		Expect(config.Clusters[1].OCPTag).To(Equal("4.10.38-aarch64"))
+	})
+
+	It("Gets the RHCOS release of each cluster from its architecture", func() {
+		// Prepare the mirror server, which will be called only for the cluster that doesn't
+		// use the default architecture:
+		server := NewServer()
+		defer server.Close()
+		server.AppendHandlers(CombineHandlers(
+			VerifyRequest(http.MethodGet, "/4.10.38/release.txt"),
+			RespondWith(
+				http.StatusOK,
+				text.Dedent(`
+					Component Versions:
+					  machine-os your-release Red Hat Enterprise Linux CoreOS
+				`),
+			),
+		))
+
+		// Run only the RHCOS release step and its dependencies:
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(client).
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config.Properties[models.OCPRCHOSReleaseProperty] = "my-release"
+		config.Properties[models.OCPMirrorProperty] = server.URL()
+		config.Clusters = append(config.Clusters, &models.Cluster{
+			Name:         "your",
+			Architecture: models.ArchitectureAARCH64,
+		})
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].RHCOSRelease).To(Equal("my-release"))
+		Expect(config.Clusters[1].RHCOSRelease).To(Equal("your-release"))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Uses the same image set name than the hub checker", func() {
+		config.Clusters[0].Architecture = models.ArchitectureAARCH64
+		err := enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].ImageSet).To(Equal(
+			enricherImageSetName(config.Properties, models.ArchitectureAARCH64),
+		))
+		Expect(enricherImageSetName(config.Properties, "")).To(Equal("openshift-v4.10.38"))
+	})
+
+	It("Rejects unsupported architecture property", func() {
+		config.Properties[models.OCPArchitectureProperty] = "ppc64le"
+		err := enricher.Enrich(ctx, config)
+		Expect(err).To(MatchError(
+			"architecture 'ppc64le' of property 'OC_OCP_ARCH' isn't supported, " +
+				"valid values are 'x86_64', 'aarch64'",
+		))
+	})
+
+	It("Rejects unsupported cluster architecture", func() {
+		config.Clusters[0].Architecture = "ppc64le"
+		err := enricher.Enrich(ctx, config)
+		Expect(err).To(MatchError(ContainSubstring(
+			"architecture 'ppc64le' of cluster 'my' isn't supported",
+		)))
+	})
+})
@@ -0,0 +1,423 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// EnricherCacheBuilder contains the data and logic needed to create an enricher cache. Don't
+// create instances of this type directly, use the NewEnricherCache function instead.
+type EnricherCacheBuilder struct {
+	logger   logr.Logger
+	client   clnt.Client
+	location string
+	ttl      time.Duration
+}
+
+// EnricherCache knows how to save the result of the enrichment of a configuration and how to load
+// it again in later runs, so that the enricher doesn't need to repeat expensive operations like
+// downloading files or resolving DNS names. The state can be saved to a local file or to a secret
+// in the hub cluster. It is a secret and not a configmap because the state contains security
+// sensitive data like the SSH keys of the clusters. Don't create instances of this type directly,
+// use the NewEnricherCache function instead.
+//
+// The saved state is ignored, and eventually replaced, when any of the following is true:
+//
+// - It was created by a version of the tool that uses a different format.
+//
+// - It is older than the TTL.
+//
+// - It was calculated from a configuration that isn't exactly the same than the current one.
+//
+// Values of secrets, including the pull secrets of the clusters, are never saved, they are always
+// resolved again.
+type EnricherCache struct {
+	logger    logr.Logger
+	client    clnt.Client
+	file      string
+	namespace string
+	name      string
+	ttl       time.Duration
+}
+
+// enricherCacheState is the content of the state file.
+type enricherCacheState struct {
+	Version     int            `json:"version"`
+	Created     time.Time      `json:"created"`
+	Fingerprint string         `json:"fingerprint"`
+	Config      *models.Config `json:"config"`
+}
+
+// NewEnricherCache creates a builder that can then be used to create an enricher cache.
+func NewEnricherCache() *EnricherCacheBuilder {
+	return &EnricherCacheBuilder{
+		ttl: enricherCacheDefaultTTL,
+	}
+}
+
+// SetLogger sets the logger that the cache will use to write log messages. This is mandatory.
+func (b *EnricherCacheBuilder) SetLogger(value logr.Logger) *EnricherCacheBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the cache will use to read and write the state
+// when it is stored in the hub cluster. This is mandatory only in that case.
+func (b *EnricherCacheBuilder) SetClient(value clnt.Client) *EnricherCacheBuilder {
+	b.client = value
+	return b
+}
+
+// SetLocation sets the place where the state will be stored. It can be the name of a local file
+// or a reference to a secret in the hub cluster, like `secret:my-namespace/my-name`. This is
+// mandatory.
+func (b *EnricherCacheBuilder) SetLocation(value string) *EnricherCacheBuilder {
+	b.location = value
+	return b
+}
+
+// SetTTL sets the time that the saved state will be considered valid. This is optional, and the
+// default is one hour.
+func (b *EnricherCacheBuilder) SetTTL(value time.Duration) *EnricherCacheBuilder {
+	b.ttl = value
+	return b
+}
+
+// SetFlags sets the command line flags that indicate how to configure the cache. This is
+// optional.
+func (b *EnricherCacheBuilder) SetFlags(flags *pflag.FlagSet) *EnricherCacheBuilder {
+	if flags.Changed(enricherCacheFlagName) {
+		value, err := flags.GetString(enricherCacheFlagName)
+		if err == nil {
+			b.location = value
+		}
+	}
+	if flags.Changed(enricherCacheTTLFlagName) {
+		value, err := flags.GetDuration(enricherCacheTTLFlagName)
+		if err == nil {
+			b.ttl = value
+		}
+	}
+	return b
+}
+
+// Build uses the data stored in the builder to create a new enricher cache.
+func (b *EnricherCacheBuilder) Build() (result *EnricherCache, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.location == "" {
+		err = errors.New("location is mandatory")
+		return
+	}
+	if b.ttl < 0 {
+		err = fmt.Errorf("TTL should be positive, but it is %s", b.ttl)
+		return
+	}
+	var file, namespace, name string
+	if strings.HasPrefix(b.location, enricherCacheSecretPrefix) {
+		ref := strings.TrimPrefix(b.location, enricherCacheSecretPrefix)
+		var ok bool
+		namespace, name, ok = strings.Cut(ref, "/")
+		if !ok || namespace == "" || name == "" {
+			err = fmt.Errorf(
+				"location '%s' isn't valid, it should be like "+
+					"'secret:namespace/name'",
+				b.location,
+			)
+			return
+		}
+		if b.client == nil {
+			err = errors.New("client is mandatory when the state is stored in the hub")
+			return
+		}
+	} else {
+		file = b.location
+	}
+
+	// Create and populate the object:
+	result = &EnricherCache{
+		logger:    b.logger,
+		client:    b.client,
+		file:      file,
+		namespace: namespace,
+		name:      name,
+		ttl:       b.ttl,
+	}
+	return
+}
+
+// Load tries to load the saved state for the given configuration. If there is a valid state the
+// configuration is replaced with it. The returned key should be passed to the Save method after
+// the enrichment. Problems reading the state aren't errors, they are reported in the log and the
+// state is ignored.
+func (c *EnricherCache) Load(ctx context.Context, config *models.Config) (key string,
+	err error) {
+	// Calculate the fingerprint of the configuration before it is modified:
+	key, err = c.fingerprint(config)
+	if err != nil {
+		return
+	}
+
+	// Read the state:
+	data, err := c.read(ctx)
+	if err != nil {
+		c.logger.Info(
+			"Failed to read enricher state, will ignore it",
+			"location", c.location(),
+			"error", err.Error(),
+		)
+		err = nil
+		return
+	}
+	if data == nil {
+		c.logger.V(1).Info(
+			"Enricher state doesn't exist",
+			"location", c.location(),
+		)
+		return
+	}
+	state := &enricherCacheState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		c.logger.Info(
+			"Failed to parse enricher state, will ignore it",
+			"location", c.location(),
+			"error", err.Error(),
+		)
+		err = nil
+		return
+	}
+
+	// Check if the state is still valid:
+	switch {
+	case state.Version != enricherCacheVersion:
+		c.logger.Info(
+			"Enricher state has a different version, will ignore it",
+			"location", c.location(),
+			"expected", enricherCacheVersion,
+			"actual", state.Version,
+		)
+		return
+	case time.Since(state.Created) > c.ttl:
+		c.logger.Info(
+			"Enricher state has expired, will ignore it",
+			"location", c.location(),
+			"created", state.Created,
+			"ttl", c.ttl,
+		)
+		return
+	case state.Fingerprint != key || state.Config == nil:
+		c.logger.Info(
+			"Enricher state was calculated for a different configuration, will "+
+				"ignore it",
+			"location", c.location(),
+		)
+		return
+	}
+
+	// The state doesn't contain the values of the secrets, so we need to take them from the
+	// current configuration. Note that the structure is the same because the fingerprint
+	// matches.
+	for i, cluster := range state.Config.Clusters {
+		current := config.Clusters[i]
+		cluster.PullSecretRef = current.PullSecretRef
+		cluster.PullSecret = current.PullSecret
+		for j, node := range cluster.Nodes {
+			node.BMC.User = current.Nodes[j].BMC.User
+			node.BMC.Pass = current.Nodes[j].BMC.Pass
+		}
+	}
+	*config = *state.Config
+	c.logger.Info(
+		"Loaded enricher state",
+		"location", c.location(),
+		"created", state.Created,
+	)
+	return
+}
+
+// Save saves the state of the given configuration, so that it can be loaded later by the Load
+// method. The key should be the one returned by the Load method.
+func (c *EnricherCache) Save(ctx context.Context, key string, config *models.Config) error {
+	// The pull secret isn't saved because it may have been copied from the hub, and then it
+	// would not be updated when the pull secret of the hub is rotated:
+	saved := *config
+	saved.Clusters = make([]*models.Cluster, len(config.Clusters))
+	for i, cluster := range config.Clusters {
+		clone := *cluster
+		clone.PullSecret = nil
+		saved.Clusters[i] = &clone
+	}
+	state := &enricherCacheState{
+		Version:     enricherCacheVersion,
+		Created:     time.Now().UTC(),
+		Fingerprint: key,
+		Config:      &saved,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = c.write(ctx, data)
+	if err != nil {
+		return err
+	}
+	c.logger.Info(
+		"Saved enricher state",
+		"location", c.location(),
+	)
+	return nil
+}
+
+// fingerprint calculates a digest of the configuration that is used to detect if it has changed.
+func (c *EnricherCache) fingerprint(config *models.Config) (result string, err error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	result = hex.EncodeToString(sum[:])
+	return
+}
+
+func (c *EnricherCache) location() string {
+	if c.file != "" {
+		return c.file
+	}
+	return fmt.Sprintf("%s%s/%s", enricherCacheSecretPrefix, c.namespace, c.name)
+}
+
+func (c *EnricherCache) read(ctx context.Context) (result []byte, err error) {
+	if c.file != "" {
+		result, err = os.ReadFile(c.file)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	secret := &corev1.Secret{}
+	key := clnt.ObjectKey{
+		Namespace: c.namespace,
+		Name:      c.name,
+	}
+	err = c.client.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	result = secret.Data[enricherCacheSecretKey]
+	return
+}
+
+func (c *EnricherCache) write(ctx context.Context, data []byte) error {
+	if c.file != "" {
+		return c.writeFile(data)
+	}
+	return c.writeSecret(ctx, data)
+}
+
+func (c *EnricherCache) writeFile(data []byte) error {
+	// Write to a temporary file first and then rename it, so that a concurrent reader will never
+	// see a partially written state:
+	dir := filepath.Dir(c.file)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.file)
+}
+
+func (c *EnricherCache) writeSecret(ctx context.Context, data []byte) error {
+	secret := &corev1.Secret{}
+	key := clnt.ObjectKey{
+		Namespace: c.namespace,
+		Name:      c.name,
+	}
+	err := c.client.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.namespace,
+				Name:      c.name,
+			},
+			Data: map[string][]byte{
+				enricherCacheSecretKey: data,
+			},
+		}
+		return c.client.Create(ctx, secret)
+	}
+	if err != nil {
+		return err
+	}
+	update := secret.DeepCopy()
+	if update.Data == nil {
+		update.Data = map[string][]byte{}
+	}
+	update.Data[enricherCacheSecretKey] = data
+	return c.client.Patch(ctx, update, clnt.MergeFrom(secret))
+}
+
+// enricherCacheVersion is the version of the format of the saved state. It should be incremented
+// when the models change in a way that makes previously saved states incompatible.
+const enricherCacheVersion = 1
+
+// enricherCacheDefaultTTL is the default time that the saved state is considered valid.
+const enricherCacheDefaultTTL = time.Hour
+
+// enricherCacheSecretPrefix is the prefix used to indicate that the state is stored in a secret of
+// the hub cluster.
+const enricherCacheSecretPrefix = "secret:"
+
+// enricherCacheSecretKey is the key of the secret that contains the state.
+const enricherCacheSecretKey = "state.json"
@@ -0,0 +1,343 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+var _ = Describe("Enricher cache", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		tmp    string
+		file   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		tmp, err = os.MkdirTemp("", "*.test")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmp)
+		file = filepath.Join(tmp, "state.json")
+	})
+
+	// makeConfig creates a configuration similar to what the loader would return, before it
+	// is enriched.
+	makeConfig := func() *models.Config {
+		return &models.Config{
+			Properties: map[string]string{
+				models.OCPVersionProperty: "4.10.38",
+			},
+			Clusters: []*models.Cluster{{
+				Name: "my",
+				Nodes: []*models.Node{{
+					Kind: models.NodeKindControlPlane,
+					Name: "master0",
+					BMC: models.BMC{
+						User: models.NewSecret("my-user"),
+						Pass: models.NewSecret("my-pass"),
+					},
+				}},
+			}},
+		}
+	}
+
+	// enrich simulates what the enricher does, adding some information to the configuration.
+	enrich := func(config *models.Config) {
+		config.Properties[models.OCPTagProperty] = "4.10.38-x86_64"
+		cluster := config.Clusters[0]
+		cluster.DNS.Domain = "my-domain.com"
+		cluster.SSH.PrivateKey = []byte("my-key")
+		cluster.API.ExternalIP = net.ParseIP("192.168.150.100")
+		cluster.Nodes[0].InternalIPs = []*models.IP{{
+			Address: net.ParseIP("192.168.7.10"),
+			Prefix:  24,
+		}}
+	}
+
+	It("Can't be created without a location", func() {
+		_, err := NewEnricherCache().
+			SetLogger(logger).
+			Build()
+		Expect(err).To(MatchError(ContainSubstring("location is mandatory")))
+	})
+
+	It("Rejects invalid secret location", func() {
+		_, err := NewEnricherCache().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build()).
+			SetLocation("secret:junk").
+			Build()
+		Expect(err).To(MatchError(ContainSubstring("secret:namespace/name")))
+	})
+
+	It("Restores the saved state", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Save the state:
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		enrich(config)
+		err = cache.Save(ctx, key, config)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Load it into a fresh configuration:
+		config = makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(models.OCPTagProperty, "4.10.38-x86_64"))
+		cluster := config.Clusters[0]
+		Expect(cluster.DNS.Domain).To(Equal("my-domain.com"))
+		Expect(cluster.SSH.PrivateKey).To(Equal([]byte("my-key")))
+		Expect(cluster.API.ExternalIP.String()).To(Equal("192.168.150.100"))
+		node := cluster.Nodes[0]
+		Expect(node.InternalIPs).To(HaveLen(1))
+		Expect(node.InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+
+		// Check that the secrets are the ones from the configuration:
+		Expect(node.BMC.User.Value()).To(Equal("my-user"))
+		Expect(node.BMC.Pass.Value()).To(Equal("my-pass"))
+	})
+
+	It("Doesn't write the values of the secrets", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		err = cache.Save(ctx, key, config)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := os.ReadFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).ToNot(ContainSubstring("my-pass"))
+		info, err := os.Stat(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("Doesn't save the pull secret", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Save the state with the pull secret that the enricher copies from the hub:
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		enrich(config)
+		config.Clusters[0].PullSecret = []byte("my-pull-secret")
+		err = cache.Save(ctx, key, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].PullSecret).To(Equal([]byte("my-pull-secret")))
+
+		// Check that the saved state doesn't contain it:
+		data, err := os.ReadFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		var state map[string]any
+		err = json.Unmarshal(data, &state)
+		Expect(err).ToNot(HaveOccurred())
+		clusters := state["config"].(map[string]any)["Clusters"].([]any)
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0]).To(HaveKeyWithValue("PullSecret", BeNil()))
+
+		// Check that it isn't restored, so that the enricher fetches it again:
+		config = makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(Equal("my-domain.com"))
+		Expect(config.Clusters[0].PullSecret).To(BeNil())
+	})
+
+	It("Ignores the state if the configuration changed", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		enrich(config)
+		err = cache.Save(ctx, key, config)
+		Expect(err).ToNot(HaveOccurred())
+		config = makeConfig()
+		config.Clusters[0].Nodes[0].RootDisk = "/dev/sda"
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(BeEmpty())
+	})
+
+	It("Ignores the state if it has expired", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			SetTTL(time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		enrich(config)
+		err = cache.Save(ctx, key, config)
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(10 * time.Millisecond)
+		config = makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(BeEmpty())
+	})
+
+	It("Ignores the state if it has a different version", func() {
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := makeConfig()
+		key, err := cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		enrich(config)
+		data, err := json.Marshal(&enricherCacheState{
+			Version:     enricherCacheVersion + 1,
+			Created:     time.Now(),
+			Fingerprint: key,
+			Config:      config,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		err = os.WriteFile(file, data, 0600)
+		Expect(err).ToNot(HaveOccurred())
+		config = makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(BeEmpty())
+	})
+
+	It("Ignores corrupted state", func() {
+		err := os.WriteFile(file, []byte("junk"), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetLocation(file).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(BeEmpty())
+	})
+
+	It("Saves the state to a secret of the hub", func() {
+		client := fake.NewClientBuilder().Build()
+		cache, err := NewEnricherCache().
+			SetLogger(logger).
+			SetClient(client).
+			SetLocation("secret:my-ns/my-state").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Save the state twice, to check that both creation and update work:
+		for i := 0; i < 2; i++ {
+			config := makeConfig()
+			key, err := cache.Load(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			enrich(config)
+			err = cache.Save(ctx, key, config)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		secret := &corev1.Secret{}
+		err = client.Get(ctx, clnt.ObjectKey{Namespace: "my-ns", Name: "my-state"}, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKey("state.json"))
+
+		// Load it:
+		config := makeConfig()
+		_, err = cache.Load(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters[0].DNS.Domain).To(Equal("my-domain.com"))
+	})
+
+	It("Is used by the enricher to skip steps that already have the result", func() {
+		// Prepare an enricher with a step that counts how many times it calculates the
+		// value:
+		count := 0
+		newEnricher := func() *Enricher {
+			cache, err := NewEnricherCache().
+				SetLogger(logger).
+				SetLocation(file).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			enricher, err := NewEnricher().
+				SetLogger(logger).
+				SetClient(fake.NewClientBuilder().Build()).
+				SetCache(cache).
+				AddStep(&EnricherStepFunc{
+					StepName: "my-step",
+					ClusterFunc: func(ctx context.Context, config *models.Config,
+						cluster *models.Cluster) error {
+						if cluster.DNS.Domain == "" {
+							count++
+							cluster.DNS.Domain = "my-domain.com"
+						}
+						return nil
+					},
+				}).
+				SetSteps("my-step").
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			return enricher
+		}
+
+		// Run it twice, with fresh objects each time, like different commands would do:
+		for i := 0; i < 2; i++ {
+			config := makeConfig()
+			err := newEnricher().Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Clusters[0].DNS.Domain).To(Equal("my-domain.com"))
+		}
+		Expect(count).To(Equal(1))
+	})
+})
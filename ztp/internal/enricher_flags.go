@@ -29,9 +29,96 @@ func AddEnricherFlags(set *pflag.FlagSet) {
 			"intended for use in tests.",
 	)
 	_ = set.MarkHidden(enricherResolverFlagName)
+	_ = set.Bool(
+		enricherOfflineFlagName,
+		false,
+		"Don't try to download anything from the Internet. The release information "+
+			"will be taken from the file given with the '--"+enricherReleaseFileFlagName+
+			"' flag, from the image given with the '--"+enricherReleaseImageFlagName+
+			"' flag or from the release image of the cluster image set of the hub.",
+	)
+	_ = set.String(
+		enricherReleaseFileFlagName,
+		"",
+		"Local copy of the 'release.txt' file of the OpenShift release, used instead "+
+			"of downloading it from the mirror.",
+	)
+	_ = set.String(
+		enricherReleaseImageFlagName,
+		"",
+		"OpenShift release image, for example in the mirror registry, used to get the "+
+			"release information instead of downloading the 'release.txt' file "+
+			"from the mirror. Requires the 'oc' command.",
+	)
+	_ = set.StringSlice(
+		enricherSkipFlagName,
+		[]string{},
+		"Names of enricher steps that will not run, for example 'external-api-ip'. "+
+			"This is intended for situations where the information that the step "+
+			"calculates isn't available and isn't needed.",
+	)
+	_ = set.String(
+		enricherCacheFlagName,
+		"",
+		"Location where the result of the enrichment will be saved and reused in "+
+			"later runs. It can be the name of a local file or a reference to a "+
+			"secret of the hub cluster, like 'secret:my-namespace/my-name'. By "+
+			"default the result isn't saved.",
+	)
+	_ = set.Duration(
+		enricherCacheTTLFlagName,
+		enricherCacheDefaultTTL,
+		"Time that the saved result of the enrichment will be considered valid.",
+	)
+	_ = set.String(
+		enricherDNSProviderFlagName,
+		"",
+		"Provider used to publish the DNS records of the API and ingress of the "+
+			"clusters that have explicit external IP addresses. Can be '"+
+			DNSProviderRFC2136+"' to send dynamic updates to a DNS server like BIND, "+
+			"or '"+DNSProviderCoreDNS+"' to write zone files to a config map of the "+
+			"hub. By default records aren't published.",
+	)
+	_ = set.String(
+		enricherDNSServerFlagName,
+		"",
+		"IP address and port number of the DNS server that receives the dynamic "+
+			"updates, for example '192.168.7.1:53'.",
+	)
+	_ = set.String(
+		enricherDNSKeyFlagName,
+		"",
+		"TSIG key used to sign the dynamic updates, with the "+
+			"'[algorithm:]name:secret' format used by 'nsupdate -y'. It can also "+
+			"be a reference like 'env:VAR', 'file:/path' or "+
+			"'secret:namespace/name#key'.",
+	)
+	_ = set.String(
+		enricherDNSZoneFlagName,
+		"",
+		"DNS zone where records are published. The default is the DNS domain of "+
+			"the cluster.",
+	)
+	_ = set.String(
+		enricherDNSConfigMapFlagName,
+		coreDNSDefaultNamespace+"/"+coreDNSDefaultName,
+		"Namespace and name of the config map of the hub where the '"+
+			DNSProviderCoreDNS+"' provider writes the zone files.",
+	)
 }
 
 // Names of the flags:
 const (
-	enricherResolverFlagName = "resolver"
+	enricherResolverFlagName     = "resolver"
+	enricherOfflineFlagName      = "offline"
+	enricherReleaseFileFlagName  = "release-txt"
+	enricherReleaseImageFlagName = "release-image"
+	enricherSkipFlagName         = "enricher-skip"
+	enricherCacheFlagName        = "enricher-cache"
+	enricherCacheTTLFlagName     = "enricher-cache-ttl"
+	enricherDNSProviderFlagName  = "dns-provider"
+	enricherDNSServerFlagName    = "dns-server"
+	enricherDNSKeyFlagName       = "dns-key"
+	enricherDNSZoneFlagName      = "dns-zone"
+	enricherDNSConfigMapFlagName = "dns-configmap"
 )
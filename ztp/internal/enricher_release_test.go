@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/text"
+)
+
+var _ = Describe("Enricher release sources", func() {
+	var (
+		ctx     context.Context
+		logger  logr.Logger
+		tmp     string
+		config  *models.Config
+		release string
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		tmp, err = os.MkdirTemp("", "*.test")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmp)
+
+		// Prepare a configuration where only the RHCOS release is missing:
+		config = &models.Config{
+			Properties: map[string]string{
+				models.OCPVersionProperty:      "4.10.38",
+				models.ClusterImageSetProperty: "openshift-v4.10.38",
+			},
+		}
+
+		// Prepare the release information:
+		release = text.Dedent(`
+			Name:      4.10.38
+			Digest:    sha256:7ea1f4e1d9e7e6a9f1f2d6c2d2f6f3c8b3c1a2f7e5d4c3b2a1f0e9d8c7b6a5f4
+
+			Component Versions:
+			  kubernetes 1.23.12
+			  machine-os 410.84.202210130022-0 Red Hat Enterprise Linux CoreOS
+		`)
+	})
+
+	// hubObjects returns the objects that are usually present in the hub: the pull secret and
+	// the cluster image set.
+	hubObjects := func() []clnt.Object {
+		pullSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "openshift-config",
+				Name:      "pull-secret",
+			},
+			Data: map[string][]byte{
+				".dockerconfigjson": []byte(`{"auths":{}}`),
+			},
+		}
+		imageSet := &unstructured.Unstructured{}
+		imageSet.SetGroupVersionKind(ClusterImageSetGVK)
+		imageSet.SetName("openshift-v4.10.38")
+		imageSet.Object["spec"] = map[string]any{
+			"releaseImage": "mirror.example.com/ocp/release:4.10.38-x86_64",
+		}
+		return []clnt.Object{pullSecret, imageSet}
+	}
+
+	// installFakeOC puts in the path a fake `oc` command that writes the arguments to a file and
+	// prints the release information.
+	installFakeOC := func() string {
+		dir := filepath.Join(tmp, "bin")
+		err := os.Mkdir(dir, 0700)
+		Expect(err).ToNot(HaveOccurred())
+		releaseFile := filepath.Join(tmp, "release.txt")
+		err = os.WriteFile(releaseFile, []byte(release), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		argsFile := filepath.Join(tmp, "args.txt")
+		script := fmt.Sprintf(
+			"#!/bin/sh\necho \"$@\" > %s\ncat %s\n",
+			argsFile, releaseFile,
+		)
+		err = os.WriteFile(filepath.Join(dir, "oc"), []byte(script), 0700)
+		Expect(err).ToNot(HaveOccurred())
+		path := os.Getenv("PATH")
+		DeferCleanup(os.Setenv, "PATH", path)
+		err = os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+		Expect(err).ToNot(HaveOccurred())
+		return argsFile
+	}
+
+	It("Takes the RHCOS release from the local file", func() {
+		file := filepath.Join(tmp, "release.txt")
+		err := os.WriteFile(file, []byte(release), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build()).
+			SetOffline(true).
+			SetReleaseFile(file).
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(
+			models.OCPRCHOSReleaseProperty, "410.84.202210130022-0",
+		))
+	})
+
+	It("Fails if the local file doesn't contain the RHCOS release", func() {
+		file := filepath.Join(tmp, "release.txt")
+		err := os.WriteFile(file, []byte("junk"), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build()).
+			SetReleaseFile(file).
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, config)
+		Expect(err).To(MatchError(ContainSubstring(file)))
+	})
+
+	It("Takes the RHCOS release from the cluster image set in offline mode", func() {
+		argsFile := installFakeOC()
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().WithObjects(hubObjects()...).Build()).
+			SetOffline(true).
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Properties).To(HaveKeyWithValue(
+			models.OCPRCHOSReleaseProperty, "410.84.202210130022-0",
+		))
+		args, err := os.ReadFile(argsFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(args)).To(HavePrefix("adm release info --registry-config "))
+		Expect(string(args)).To(ContainSubstring(
+			"mirror.example.com/ocp/release:4.10.38-x86_64",
+		))
+	})
+
+	It("Takes the RHCOS release from the explicitly given release image", func() {
+		argsFile := installFakeOC()
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().WithObjects(hubObjects()...).Build()).
+			SetReleaseImage("my-registry.example.com/release:4.10.38").
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		args, err := os.ReadFile(argsFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(args)).To(ContainSubstring("my-registry.example.com/release:4.10.38"))
+	})
+
+	It("Fails with a clear error in offline mode if the cluster image set doesn't exist", func() {
+		enricher, err := NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build()).
+			SetOffline(true).
+			SetSteps(EnricherStepRHCOSRelease).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, config)
+		Expect(err).To(HaveOccurred())
+		msg := err.Error()
+		Expect(msg).To(ContainSubstring("offline mode"))
+		Expect(msg).To(ContainSubstring("'openshift-v4.10.38' doesn't exist"))
+		Expect(msg).To(ContainSubstring("--release-txt"))
+	})
+})
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// EnricherStep is one of the steps that the enricher runs to complete the configuration. Each step
+// has a name that commands can use to request only the steps that they need, and it can declare
+// the names of other steps that need to run before it.
+type EnricherStep interface {
+	// Name returns the name that identifies the step, for example `pull-secret`.
+	Name() string
+
+	// Dependencies returns the names of the steps that need to run before this one.
+	Dependencies() []string
+
+	// EnrichConfig is called once for the complete configuration, before any cluster is
+	// processed by this step.
+	EnrichConfig(ctx context.Context, config *models.Config) error
+
+	// EnrichCluster is called once for each cluster of the configuration.
+	EnrichCluster(ctx context.Context, config *models.Config, cluster *models.Cluster) error
+}
+
+// EnricherStepFunc is an implementation of the EnricherStep interface that delegates to functions.
+// Any of the functions can be nil, and then the corresponding method will do nothing.
+type EnricherStepFunc struct {
+	StepName         string
+	StepDependencies []string
+	ConfigFunc       func(ctx context.Context, config *models.Config) error
+	ClusterFunc      func(ctx context.Context, config *models.Config, cluster *models.Cluster) error
+}
+
+// Name is the implementation of the EnricherStep interface.
+func (s *EnricherStepFunc) Name() string {
+	return s.StepName
+}
+
+// Dependencies is the implementation of the EnricherStep interface.
+func (s *EnricherStepFunc) Dependencies() []string {
+	return s.StepDependencies
+}
+
+// EnrichConfig is the implementation of the EnricherStep interface.
+func (s *EnricherStepFunc) EnrichConfig(ctx context.Context, config *models.Config) error {
+	if s.ConfigFunc == nil {
+		return nil
+	}
+	return s.ConfigFunc(ctx, config)
+}
+
+// EnrichCluster is the implementation of the EnricherStep interface.
+func (s *EnricherStepFunc) EnrichCluster(ctx context.Context, config *models.Config,
+	cluster *models.Cluster) error {
+	if s.ClusterFunc == nil {
+		return nil
+	}
+	return s.ClusterFunc(ctx, config, cluster)
+}
+
+// Names of the steps built into the enricher:
+const (
+	EnricherStepArchitecture      = "architecture"
+	EnricherStepOCPTag            = "ocp-tag"
+	EnricherStepRHCOSRelease      = "rhcos-release"
+	EnricherStepConfigImageSet    = "config-image-set"
+	EnricherStepConfigRegistry    = "config-registry"
+	EnricherStepSNO               = "sno"
+	EnricherStepSecrets           = "secrets"
+	EnricherStepPullSecret        = "pull-secret"
+	EnricherStepSSHKeys           = "ssh-keys"
+	EnricherStepDNSDomain         = "dns-domain"
+	EnricherStepNetworks          = "networks"
+	EnricherStepInternalAPIIP     = "internal-api-ip"
+	EnricherStepInternalIngressIP = "internal-ingress-ip"
+	EnricherStepInternalNodeIPs   = "internal-node-ips"
+	EnricherStepCheckNetworks     = "check-networks"
+	EnricherStepExternalNodeIPs   = "external-node-ips"
+	EnricherStepDNSRecords        = "dns-records"
+	EnricherStepKubeconfig        = "kubeconfig"
+	EnricherStepClusterImageSet   = "cluster-image-set"
+	EnricherStepRegistryURL       = "registry-url"
+	EnricherStepRegistryCA        = "registry-ca"
+	EnricherStepHostnames         = "hostnames"
+	EnricherStepExternalAPIIP     = "external-api-ip"
+	EnricherStepExternalIngressIP = "external-ingress-ip"
+)
@@ -0,0 +1,271 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+var _ = Describe("Enricher steps", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// The steps used in these tests don't need to talk to the API server, so a fake client is
+	// enough.
+	newEnricher := func() *EnricherBuilder {
+		return NewEnricher().
+			SetLogger(logger).
+			SetClient(fake.NewClientBuilder().Build())
+	}
+
+	It("Runs all the built-in steps by default", func() {
+		enricher, err := newEnricher().Build()
+		Expect(err).ToNot(HaveOccurred())
+		steps := enricher.Steps()
+		Expect(steps).To(HaveLen(24))
+		Expect(steps[0]).To(Equal(EnricherStepArchitecture))
+		Expect(steps[len(steps)-1]).To(Equal(EnricherStepDNSRecords))
+	})
+
+	It("Adds the dependencies of the selected steps", func() {
+		enricher, err := newEnricher().
+			SetSteps(EnricherStepClusterImageSet, EnricherStepRegistryCA).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(enricher.Steps()).To(Equal([]string{
+			EnricherStepArchitecture,
+			EnricherStepConfigImageSet,
+			EnricherStepClusterImageSet,
+			EnricherStepConfigRegistry,
+			EnricherStepRegistryURL,
+			EnricherStepRegistryCA,
+		}))
+	})
+
+	It("Runs the steps that calculate the addresses before checking networks", func() {
+		enricher, err := newEnricher().
+			SetSteps(EnricherStepCheckNetworks).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(enricher.Steps()).To(Equal([]string{
+			EnricherStepNetworks,
+			EnricherStepInternalAPIIP,
+			EnricherStepInternalIngressIP,
+			EnricherStepCheckNetworks,
+		}))
+	})
+
+	It("Calculates the external addresses before publishing DNS records", func() {
+		enricher, err := newEnricher().
+			SetSteps(EnricherStepDNSRecords).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(enricher.Steps()).To(Equal([]string{
+			EnricherStepDNSDomain,
+			EnricherStepExternalAPIIP,
+			EnricherStepExternalIngressIP,
+			EnricherStepDNSRecords,
+		}))
+	})
+
+	It("Doesn't run skipped steps", func() {
+		enricher, err := newEnricher().
+			SkipSteps(EnricherStepExternalAPIIP, EnricherStepExternalIngressIP).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		steps := enricher.Steps()
+		Expect(steps).ToNot(ContainElement(EnricherStepExternalAPIIP))
+		Expect(steps).ToNot(ContainElement(EnricherStepExternalIngressIP))
+		Expect(steps).To(ContainElement(EnricherStepDNSDomain))
+	})
+
+	It("Doesn't run skipped dependencies", func() {
+		enricher, err := newEnricher().
+			SetSteps(EnricherStepInternalAPIIP).
+			SkipSteps(EnricherStepNetworks).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(enricher.Steps()).To(Equal([]string{
+			EnricherStepInternalAPIIP,
+		}))
+	})
+
+	It("Rejects unknown step names", func() {
+		_, err := newEnricher().
+			SetSteps("junk").
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("'junk'"))
+	})
+
+	It("Rejects unknown dependencies", func() {
+		_, err := newEnricher().
+			AddStep(&EnricherStepFunc{
+				StepName:         "my-step",
+				StepDependencies: []string{"junk"},
+			}).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("'my-step'"))
+		Expect(err.Error()).To(ContainSubstring("'junk'"))
+	})
+
+	It("Rejects dependency loops", func() {
+		_, err := newEnricher().
+			AddStep(&EnricherStepFunc{
+				StepName:         "first",
+				StepDependencies: []string{"second"},
+			}).
+			AddStep(&EnricherStepFunc{
+				StepName:         "second",
+				StepDependencies: []string{"first"},
+			}).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("first -> second -> first"))
+	})
+
+	It("Runs custom steps after their dependencies", func() {
+		var hostnames []string
+		enricher, err := newEnricher().
+			AddStep(&EnricherStepFunc{
+				StepName:         "my-step",
+				StepDependencies: []string{EnricherStepHostnames},
+				ClusterFunc: func(ctx context.Context, config *models.Config,
+					cluster *models.Cluster) error {
+					for _, node := range cluster.Nodes {
+						hostnames = append(hostnames, node.Hostname)
+					}
+					return nil
+				},
+			}).
+			SetSteps("my-step").
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(enricher.Steps()).To(Equal([]string{
+			EnricherStepHostnames,
+			"my-step",
+		}))
+		config := &models.Config{
+			Clusters: []*models.Cluster{{
+				Name: "my",
+				Nodes: []*models.Node{{
+					Kind: models.NodeKindControlPlane,
+					Name: "master0",
+				}},
+			}},
+		}
+		err = enricher.Enrich(ctx, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hostnames).To(Equal([]string{"ztpfw-my-master-0"}))
+	})
+
+	It("Replaces built-in steps with custom steps with the same name", func() {
+		failure := errors.New("my failure")
+		enricher, err := newEnricher().
+			AddStep(&EnricherStepFunc{
+				StepName: EnricherStepOCPTag,
+				ConfigFunc: func(ctx context.Context, config *models.Config) error {
+					return failure
+				},
+			}).
+			SetSteps(EnricherStepOCPTag).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		err = enricher.Enrich(ctx, &models.Config{})
+		Expect(err).To(MatchError(failure))
+	})
+
+	It("Informs listeners of the steps", func() {
+		var events []*EnricherEvent
+		failure := errors.New("my failure")
+		enricher, err := newEnricher().
+			AddStep(&EnricherStepFunc{
+				StepName: "my-step",
+			}).
+			AddStep(&EnricherStepFunc{
+				StepName:         "your-step",
+				StepDependencies: []string{"my-step"},
+				ClusterFunc: func(ctx context.Context, config *models.Config,
+					cluster *models.Cluster) error {
+					return failure
+				},
+			}).
+			SetSteps("my-step", "your-step", EnricherStepOCPTag).
+			SkipSteps(EnricherStepOCPTag).
+			SetListener(func(event *EnricherEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		config := &models.Config{
+			Clusters: []*models.Cluster{{
+				Name: "my",
+			}},
+		}
+		err = enricher.Enrich(ctx, config)
+		Expect(err).To(MatchError(failure))
+		Expect(events).To(Equal([]*EnricherEvent{
+			{
+				Type: EnricherStepSkipped,
+				Step: EnricherStepOCPTag,
+			},
+			{
+				Type: EnricherStepStarted,
+				Step: "my-step",
+			},
+			{
+				Type:    EnricherClusterEnriched,
+				Step:    "my-step",
+				Cluster: "my",
+			},
+			{
+				Type: EnricherStepCompleted,
+				Step: "my-step",
+			},
+			{
+				Type: EnricherStepStarted,
+				Step: "your-step",
+			},
+			{
+				Type:    EnricherStepFailed,
+				Step:    "your-step",
+				Cluster: "my",
+				Error:   failure,
+			},
+		}))
+	})
+})
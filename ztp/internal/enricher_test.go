@@ -23,8 +23,10 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
@@ -212,6 +214,60 @@ var _ = Describe("Enricher", Ordered, func() {
 			Expect(config.Clusters[0].SNO).To(BeFalse())
 		})
 
+		It("Calculates host names", func() {
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "master0",
+						},
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-node",
+						},
+						{
+							Kind:     models.NodeKindControlPlane,
+							Name:     "your-node",
+							Hostname: "your-host",
+						},
+						{
+							Kind: models.NodeKindWorker,
+							Name: "worker12",
+						},
+					},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			nodes := config.Clusters[0].Nodes
+			Expect(nodes[0].Hostname).To(Equal(fmt.Sprintf("ztpfw-%s-master-0", name)))
+			Expect(nodes[1].Hostname).To(Equal(fmt.Sprintf("ztpfw-%s-my-node", name)))
+			Expect(nodes[2].Hostname).To(Equal("your-host"))
+			Expect(nodes[3].Hostname).To(Equal(fmt.Sprintf("ztpfw-%s-worker-12", name)))
+		})
+
+		It("Rejects node names that generate invalid host names", func() {
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "My_Node",
+						},
+					},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("My_Node"))
+			Expect(err.Error()).To(ContainSubstring("isn't valid"))
+		})
+
 		It("Doesn't change the pull secret if already set", func() {
 			// Prepare a pull secret different to the one in the environment:
 			custom := []byte(`{
@@ -246,6 +302,55 @@ var _ = Describe("Enricher", Ordered, func() {
 			Expect(config.Clusters[0].PullSecret).To(Equal(custom))
 		})
 
+		It("Gets the pull secret from the configuration reference", func() {
+			tmp, err := os.MkdirTemp("", "*.test")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(tmp)
+			file := filepath.Join(tmp, "pull-secret.json")
+			err = os.WriteFile(file, []byte(`{"auths":{}}`), 0600)
+			Expect(err).ToNot(HaveOccurred())
+			ref, err := models.ParseSecret("file:" + file)
+			Expect(err).ToNot(HaveOccurred())
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name:          name,
+					PullSecretRef: ref,
+				}},
+			}
+			err = enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Clusters[0].PullSecret).To(MatchJSON(`{"auths":{}}`))
+		})
+
+		It("Resolves BMC credentials", func() {
+			os.Setenv("ZTP_TEST_BMC_PASS", "my-pass")
+			defer os.Unsetenv("ZTP_TEST_BMC_PASS")
+			pass, err := models.ParseSecret("env:ZTP_TEST_BMC_PASS")
+			Expect(err).ToNot(HaveOccurred())
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "master0",
+							BMC: models.BMC{
+								User: models.NewSecret("my-user"),
+								Pass: pass,
+							},
+						},
+					},
+				}},
+			}
+			err = enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			node := config.Clusters[0].Nodes[0]
+			Expect(node.BMC.User.Value()).To(Equal("my-user"))
+			Expect(node.BMC.Pass.Value()).To(Equal("my-pass"))
+		})
+
 		It("Gets the pull secret from the environment", func() {
 			// Create the config without a pull secret:
 			config := &models.Config{
@@ -563,10 +668,14 @@ var _ = Describe("Enricher", Ordered, func() {
 			err := enricher.Enrich(ctx, config)
 			Expect(err).ToNot(HaveOccurred())
 			cluster := config.Clusters[0]
-			Expect(cluster.Nodes[0].InternalIP.String()).To(Equal("192.168.7.10/24"))
-			Expect(cluster.Nodes[1].InternalIP.String()).To(Equal("192.168.7.11/24"))
-			Expect(cluster.Nodes[2].InternalIP.String()).To(Equal("192.168.7.12/24"))
-			Expect(cluster.Nodes[3].InternalIP.String()).To(Equal("192.168.7.13/24"))
+			Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+			Expect(cluster.Nodes[1].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[1].InternalIPs[0].String()).To(Equal("192.168.7.11/24"))
+			Expect(cluster.Nodes[2].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[2].InternalIPs[0].String()).To(Equal("192.168.7.12/24"))
+			Expect(cluster.Nodes[3].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[3].InternalIPs[0].String()).To(Equal("192.168.7.13/24"))
 			Expect(cluster.Ingress.InternalIP.String()).To(Equal("192.168.7.242"))
 			Expect(cluster.API.InternalIP.String()).To(Equal("192.168.7.243"))
 		})
@@ -595,11 +704,197 @@ var _ = Describe("Enricher", Ordered, func() {
 			err := enricher.Enrich(ctx, config)
 			Expect(err).ToNot(HaveOccurred())
 			cluster := config.Clusters[0]
-			Expect(cluster.Nodes[0].InternalIP.String()).To(Equal("192.168.7.10/24"))
+			Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
 			Expect(cluster.Ingress.InternalIP.String()).To(Equal("192.168.7.242"))
 			Expect(cluster.API.InternalIP.String()).To(Equal("192.168.7.243"))
 		})
 
+		It("Uses the configured networks to calculate internal IP addresses", func() {
+			_, clusterCIDR, _ := net.ParseCIDR("10.132.0.0/14")
+			_, machineCIDR, _ := net.ParseCIDR("192.168.8.0/24")
+			_, serviceCIDR, _ := net.ParseCIDR("172.31.0.0/16")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					ClusterNetworks: []*models.ClusterNetwork{{
+						CIDR: clusterCIDR,
+					}},
+					MachineNetworks: []*models.MachineNetwork{{
+						CIDR: machineCIDR,
+					}},
+					ServiceNetworks: []*models.ServiceNetwork{{
+						CIDR: serviceCIDR,
+					}},
+					API: models.API{
+						InternalIP: net.ParseIP("192.168.8.100"),
+					},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			cluster := config.Clusters[0]
+			Expect(cluster.ClusterNetworks[0].CIDR.String()).To(Equal("10.132.0.0/14"))
+			Expect(cluster.ClusterNetworks[0].HostPrefix).To(Equal(23))
+			Expect(cluster.MachineNetworks[0].CIDR.String()).To(Equal("192.168.8.0/24"))
+			Expect(cluster.ServiceNetworks[0].CIDR.String()).To(Equal("172.31.0.0/16"))
+			Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.8.10/24"))
+			Expect(cluster.Ingress.InternalIP.String()).To(Equal("192.168.8.242"))
+			Expect(cluster.API.InternalIP.String()).To(Equal("192.168.8.100"))
+		})
+
+		It("Calculates IPv6 networks and addresses", func() {
+			_, machineCIDR, _ := net.ParseCIDR("fd00:7::/64")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					MachineNetworks: []*models.MachineNetwork{{
+						CIDR: machineCIDR,
+					}},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			cluster := config.Clusters[0]
+			Expect(cluster.ClusterNetworks).To(HaveLen(1))
+			Expect(cluster.ClusterNetworks[0].CIDR.String()).To(Equal("fd01::/48"))
+			Expect(cluster.ClusterNetworks[0].HostPrefix).To(Equal(64))
+			Expect(cluster.ServiceNetworks).To(HaveLen(1))
+			Expect(cluster.ServiceNetworks[0].CIDR.String()).To(Equal("fd02::/112"))
+			Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+			Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("fd00:7::a/64"))
+			Expect(cluster.Ingress.InternalIP.String()).To(Equal("fd00:7::f2"))
+			Expect(cluster.API.InternalIP.String()).To(Equal("fd00:7::f3"))
+		})
+
+		It("Calculates dual stack networks and addresses", func() {
+			_, machineCIDRv4, _ := net.ParseCIDR("192.168.7.0/24")
+			_, machineCIDRv6, _ := net.ParseCIDR("fd00:7::/64")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					MachineNetworks: []*models.MachineNetwork{
+						{
+							CIDR: machineCIDRv4,
+						},
+						{
+							CIDR: machineCIDRv6,
+						},
+					},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			cluster := config.Clusters[0]
+			Expect(cluster.ClusterNetworks).To(HaveLen(2))
+			Expect(cluster.ClusterNetworks[0].CIDR.String()).To(Equal("10.128.0.0/14"))
+			Expect(cluster.ClusterNetworks[1].CIDR.String()).To(Equal("fd01::/48"))
+			Expect(cluster.ServiceNetworks).To(HaveLen(2))
+			Expect(cluster.ServiceNetworks[0].CIDR.String()).To(Equal("172.30.0.0/16"))
+			Expect(cluster.ServiceNetworks[1].CIDR.String()).To(Equal("fd02::/112"))
+			Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(2))
+			Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+			Expect(cluster.Nodes[0].InternalIPs[1].String()).To(Equal("fd00:7::a/64"))
+			Expect(cluster.API.InternalIP.String()).To(Equal("192.168.7.243"))
+		})
+
+		It("Fails if network families don't match", func() {
+			_, machineCIDR, _ := net.ParseCIDR("fd00:7::/64")
+			_, serviceCIDR, _ := net.ParseCIDR("172.30.0.0/16")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					MachineNetworks: []*models.MachineNetwork{{
+						CIDR: machineCIDR,
+					}},
+					ServiceNetworks: []*models.ServiceNetwork{{
+						CIDR: serviceCIDR,
+					}},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("same IP families"))
+		})
+
+		It("Fails if networks overlap", func() {
+			_, machineCIDR, _ := net.ParseCIDR("172.30.1.0/24")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					MachineNetworks: []*models.MachineNetwork{{
+						CIDR: machineCIDR,
+					}},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).To(HaveOccurred())
+			msg := err.Error()
+			Expect(msg).To(ContainSubstring("172.30.1.0/24"))
+			Expect(msg).To(ContainSubstring("172.30.0.0/16"))
+			Expect(msg).To(ContainSubstring("overlap"))
+		})
+
+		It("Fails if the machine network is too small for the default addresses", func() {
+			_, machineCIDR, _ := net.ParseCIDR("192.168.8.0/25")
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "my-master-0",
+						},
+					},
+					MachineNetworks: []*models.MachineNetwork{{
+						CIDR: machineCIDR,
+					}},
+				}},
+			}
+			err := enricher.Enrich(ctx, config)
+			Expect(err).To(HaveOccurred())
+			msg := err.Error()
+			Expect(msg).To(ContainSubstring("api_vip"))
+			Expect(msg).To(ContainSubstring("192.168.8.0/25"))
+		})
+
 		It("Doesn't change the SSH keys if already set", func() {
 			// Generate the key pair:
 			rsaKey, err := rsa.GenerateKey(rand.Reader, 4096)
@@ -773,6 +1068,33 @@ var _ = Describe("Enricher", Ordered, func() {
 			Expect(cluster.Nodes[2].ExternalIP.String()).To(Equal("192.168.150.102/24"))
 		})
 
+		It("Uses static external IP addresses without looking at agents", func() {
+			address, err := models.ParseIP("192.168.150.200/24")
+			Expect(err).ToNot(HaveOccurred())
+			config := &models.Config{
+				Properties: properties,
+				Clusters: []*models.Cluster{{
+					Name: name,
+					Nodes: []*models.Node{
+						{
+							Kind: models.NodeKindControlPlane,
+							Name: "master0",
+							ExternalNIC: &models.NIC{
+								MAC: "a2:87:c3:6d:61:d0",
+							},
+							ExternalIP:      address,
+							ExternalStatic:  true,
+							ExternalGateway: net.ParseIP("192.168.150.1"),
+						},
+					},
+				}},
+			}
+			err = enricher.Enrich(ctx, config)
+			Expect(err).ToNot(HaveOccurred())
+			node := config.Clusters[0].Nodes[0]
+			Expect(node.ExternalIP.String()).To(Equal("192.168.150.200/24"))
+		})
+
 		It("Takes kubeconfig from secret", func() {
 			// Prepare the secret:
 			data := map[string]any{
@@ -61,6 +61,20 @@ var (
 	}
 	ClusterDeploymentListGVK = listGVK(ClusterDeploymentGVK)
 
+	ClusterImageSetGVK = schema.GroupVersionKind{
+		Group:   "hive.openshift.io",
+		Version: "v1",
+		Kind:    "ClusterImageSet",
+	}
+	ClusterImageSetListGVK = listGVK(ClusterImageSetGVK)
+
+	ClusterServiceVersionGVK = schema.GroupVersionKind{
+		Group:   "operators.coreos.com",
+		Version: "v1alpha1",
+		Kind:    "ClusterServiceVersion",
+	}
+	ClusterServiceVersionListGVK = listGVK(ClusterServiceVersionGVK)
+
 	CustomResourceDefinitionGVK = schema.GroupVersionKind{
 		Group:   "apiextensions.k8s.io",
 		Version: "v1",
@@ -117,6 +131,13 @@ var (
 	}
 	NamespaceListGVK = listGVK(NamespaceGVK)
 
+	NetworkConfigGVK = schema.GroupVersionKind{
+		Group:   "config.openshift.io",
+		Version: "v1",
+		Kind:    "Network",
+	}
+	NetworkConfigListGVK = listGVK(NetworkConfigGVK)
+
 	NMStateConfigGVK = schema.GroupVersionKind{
 		Group:   "agent-install.openshift.io",
 		Version: "v1beta1",
@@ -124,6 +145,13 @@ var (
 	}
 	NMStateConfigListGVK = listGVK(NMStateConfigGVK)
 
+	OperatorGroupGVK = schema.GroupVersionKind{
+		Group:   "operators.coreos.com",
+		Version: "v1",
+		Kind:    "OperatorGroup",
+	}
+	OperatorGroupListGVK = listGVK(OperatorGroupGVK)
+
 	QuayRegistryGVK = schema.GroupVersionKind{
 		Group:   "quay.redhat.com",
 		Version: "v1",
@@ -144,6 +172,13 @@ var (
 		Kind:    "StorageCluster",
 	}
 	StorageClusterListGVK = listGVK(StorageClusterGVK)
+
+	SubscriptionGVK = schema.GroupVersionKind{
+		Group:   "operators.coreos.com",
+		Version: "v1alpha1",
+		Kind:    "Subscription",
+	}
+	SubscriptionListGVK = listGVK(SubscriptionGVK)
 )
 
 func listGVK(gvk schema.GroupVersionKind) schema.GroupVersionKind {
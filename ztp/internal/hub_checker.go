@@ -0,0 +1,297 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/config"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// HubCheckerBuilder contains the data and logic needed to create an object that checks that the
+// hub cluster has the things that the enricher will need. Don't create instances of this type
+// directly, use the NewHubChecker function instead.
+type HubCheckerBuilder struct {
+	logger logr.Logger
+	client clnt.Client
+}
+
+// HubChecker knows how to check that the hub cluster contains the objects that the enricher will
+// need to complete the configuration: the pull secret, the ingress domain, the registry
+// configuration and the cluster image set. It only reads objects, it never creates or modifies
+// them. Don't create instances of this type directly, use the NewHubChecker function instead.
+type HubChecker struct {
+	logger   logr.Logger
+	client   clnt.Client
+	enricher *Enricher
+	secrets  *SecretResolver
+}
+
+// NewHubChecker creates a builder that can then be used to create a hub checker.
+func NewHubChecker() *HubCheckerBuilder {
+	return &HubCheckerBuilder{}
+}
+
+// SetLogger sets the logger that the checker will use to write log messages. This is mandatory.
+func (b *HubCheckerBuilder) SetLogger(value logr.Logger) *HubCheckerBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the checker will use to talk to the hub cluster.
+// This is mandatory.
+func (b *HubCheckerBuilder) SetClient(value clnt.Client) *HubCheckerBuilder {
+	b.client = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new hub checker.
+func (b *HubCheckerBuilder) Build() (result *HubChecker, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.client == nil {
+		err = errors.New("client is mandatory")
+		return
+	}
+
+	// Some of the checks are the same that the enricher does, so we create one to reuse them:
+	enricher, err := NewEnricher().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("failed to create enricher: %w", err)
+		return
+	}
+
+	// Create the secret resolver:
+	secrets, err := NewSecretResolver().
+		SetLogger(b.logger).
+		SetClient(b.client).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("failed to create secret resolver: %w", err)
+		return
+	}
+
+	// Create and populate the object:
+	result = &HubChecker{
+		logger:   b.logger,
+		client:   b.client,
+		enricher: enricher,
+		secrets:  secrets,
+	}
+	return
+}
+
+// Check checks the hub cluster and returns the list of problems found. The result will be empty
+// if there are no problems. The returned error will only be used for problems that prevent the
+// checks from running, like failing to connect to the API server.
+func (c *HubChecker) Check(ctx context.Context, cfg *models.Config) (result []*config.Problem,
+	err error) {
+	checks := []func(context.Context, *models.Config) (*config.Problem, error){
+		c.checkPullSecret,
+		c.checkIngressDomain,
+		c.checkRegistry,
+		c.checkClusterImageSet,
+	}
+	for _, check := range checks {
+		var problem *config.Problem
+		problem, err = check(ctx, cfg)
+		if err != nil {
+			return
+		}
+		if problem != nil {
+			c.logger.V(1).Info(
+				"Found hub problem",
+				"path", problem.Path,
+				"message", problem.Message,
+			)
+			result = append(result, problem)
+		}
+	}
+	result = append(result, c.checkSecrets(ctx, cfg)...)
+	return
+}
+
+func (c *HubChecker) checkPullSecret(ctx context.Context,
+	cfg *models.Config) (result *config.Problem, err error) {
+	secret := &corev1.Secret{}
+	key := clnt.ObjectKey{
+		Namespace: "openshift-config",
+		Name:      "pull-secret",
+	}
+	err = c.client.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		result = c.problem(
+			"",
+			"pull secret '%s/%s' doesn't exist",
+			key.Namespace, key.Name,
+		)
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	_, ok := secret.Data[".dockerconfigjson"]
+	if !ok {
+		result = c.problem(
+			"",
+			"pull secret '%s/%s' doesn't contain the '.dockerconfigjson' key",
+			key.Namespace, key.Name,
+		)
+	}
+	return
+}
+
+func (c *HubChecker) checkIngressDomain(ctx context.Context,
+	cfg *models.Config) (result *config.Problem, err error) {
+	_, err = c.enricher.getDNSDomain(ctx)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		result = c.problem("", "default ingress controller doesn't exist")
+		err = nil
+		return
+	}
+	if err != nil {
+		result = c.problem("", "failed to get DNS domain: %v", err)
+		err = nil
+	}
+	return
+}
+
+func (c *HubChecker) checkRegistry(ctx context.Context,
+	cfg *models.Config) (result *config.Problem, err error) {
+	// If the registry is explicitly set the configmap isn't used:
+	if cfg.Properties[models.RegistryProperty] != "" {
+		return
+	}
+
+	// The configmap is optional, but if it exists it needs to contain a valid URI:
+	registryConfig := &corev1.ConfigMap{}
+	registryKey := clnt.ObjectKey{
+		Namespace: "ztpfw-registry",
+		Name:      "ztpfw-config",
+	}
+	err = c.client.Get(ctx, registryKey, registryConfig)
+	if apierrors.IsNotFound(err) {
+		c.logger.V(1).Info(
+			"Registry configmap doesn't exist",
+			"configmap", registryKey,
+		)
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	uriData, ok := registryConfig.Data["uri"]
+	if !ok {
+		result = c.problem(
+			"",
+			"registry configmap '%s' doesn't have the 'uri' key",
+			registryKey,
+		)
+		return
+	}
+	_, decodeErr := base64.StdEncoding.DecodeString(uriData)
+	if decodeErr != nil {
+		result = c.problem(
+			"",
+			"registry configmap '%s' contains an invalid URI: %v",
+			registryKey, decodeErr,
+		)
+	}
+	return
+}
+
+func (c *HubChecker) checkClusterImageSet(ctx context.Context,
+	cfg *models.Config) (result *config.Problem, err error) {
+	// Calculate the name of the image set the same way that the enricher does. Note that only
+	// the default image set needs to exist, the ones for clusters with other architectures are
+	// created from it together with the rest of the objects of the cluster.
+	name := enricherImageSetName(cfg.Properties, "")
+	if name == "" {
+		result = c.problem(
+			"config."+models.OCPVersionProperty,
+			"property '%s' is mandatory",
+			models.OCPVersionProperty,
+		)
+		return
+	}
+
+	// Check that it exists:
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ClusterImageSetGVK)
+	key := clnt.ObjectKey{
+		Name: name,
+	}
+	err = c.client.Get(ctx, key, object)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		result = c.problem(
+			"config."+models.ClusterImageSetProperty,
+			"cluster image set '%s' doesn't exist",
+			name,
+		)
+		err = nil
+	}
+	return
+}
+
+func (c *HubChecker) checkSecrets(ctx context.Context,
+	cfg *models.Config) (result []*config.Problem) {
+	check := func(path string, secret *models.Secret) {
+		err := c.secrets.Resolve(ctx, secret)
+		if err != nil {
+			problem := c.problem(path, "%v", err)
+			c.logger.V(1).Info(
+				"Found hub problem",
+				"path", problem.Path,
+				"message", problem.Message,
+			)
+			result = append(result, problem)
+		}
+	}
+	for _, cluster := range cfg.Clusters {
+		check(cluster.Name+".config.pull_secret", cluster.PullSecretRef)
+		for _, node := range cluster.Nodes {
+			path := cluster.Name + "." + node.Name
+			check(path+".bmc_user", node.BMC.User)
+			check(path+".bmc_pass", node.BMC.Pass)
+		}
+	}
+	return
+}
+
+func (c *HubChecker) problem(path, format string, args ...any) *config.Problem {
+	return &config.Problem{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
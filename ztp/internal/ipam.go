@@ -0,0 +1,523 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/exp/maps"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/labels"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// IPAMBuilder contains the data and logic needed to create an object that knows how to allocate the
+// internal IP addresses of the nodes of a cluster. Don't create instances of this type directly, use
+// the NewIPAM function instead.
+type IPAMBuilder struct {
+	logger logr.Logger
+	client clnt.Client
+	dryRun bool
+}
+
+// IPAM knows how to allocate the internal IP addresses of the nodes of a cluster. Addresses are
+// taken from the machine networks of the cluster, excluding the internal API and ingress addresses
+// and any other address already in use. The allocations are saved to a config map inside the
+// namespace of the cluster, indexed by the MAC address of the node, so that each node gets the
+// same addresses every time, even if other nodes are added or removed. Don't create instances of
+// this type directly, use the NewIPAM function instead.
+type IPAM struct {
+	logger logr.Logger
+	client clnt.Client
+	dryRun bool
+}
+
+// ipamTask contains the data needed to allocate the addresses of one cluster.
+type ipamTask struct {
+	logger  logr.Logger
+	cluster *models.Cluster
+	saved   map[string][]net.IP
+	owners  map[string]string
+}
+
+// NewIPAM creates a builder that can then be used to create an object that knows how to allocate
+// the internal IP addresses of the nodes of a cluster.
+func NewIPAM() *IPAMBuilder {
+	return &IPAMBuilder{}
+}
+
+// SetLogger sets the logger that the IPAM will use to write log messages. This is mandatory.
+func (b *IPAMBuilder) SetLogger(value logr.Logger) *IPAMBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the IPAM will use to load and save the allocations.
+// This is mandatory.
+func (b *IPAMBuilder) SetClient(value clnt.Client) *IPAMBuilder {
+	b.client = value
+	return b
+}
+
+// SetDryRun sets the dry run flag. When it is true the addresses are allocated but not saved or
+// released. The default is false.
+func (b *IPAMBuilder) SetDryRun(value bool) *IPAMBuilder {
+	b.dryRun = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new IPAM.
+func (b *IPAMBuilder) Build() (result *IPAM, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.client == nil {
+		err = errors.New("client is mandatory")
+		return
+	}
+
+	// Create and populate the object:
+	result = &IPAM{
+		logger: b.logger,
+		client: b.client,
+		dryRun: b.dryRun,
+	}
+	return
+}
+
+// Allocate assigns internal IP addresses to the nodes of the given cluster that don't have them
+// yet, one for each machine network. Nodes that already have addresses saved from a previous run
+// get the same ones again. New nodes get the lowest free addresses, starting with the tenth
+// address of each machine network. It returns an error if the addresses conflict with each other
+// or with the internal API and ingress addresses, or if there are no free addresses left.
+func (i *IPAM) Allocate(ctx context.Context, cluster *models.Cluster) error {
+	// Load the allocations saved by previous runs:
+	saved, err := i.load(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	// Allocate the addresses:
+	task := &ipamTask{
+		logger:  i.logger,
+		cluster: cluster,
+		saved:   saved,
+		owners:  map[string]string{},
+	}
+	err = task.run()
+	if err != nil {
+		return err
+	}
+
+	// Save the allocations if they have changed:
+	data := ipamData(cluster)
+	if maps.Equal(data, ipamFormat(saved)) {
+		return nil
+	}
+	for key := range saved {
+		_, ok := data[key]
+		if !ok {
+			i.logger.Info(
+				"Released internal IP addresses",
+				"cluster", cluster.Name,
+				"key", key,
+				"addresses", saved[key],
+			)
+		}
+	}
+	return i.save(ctx, cluster, data)
+}
+
+// Save saves the internal IP addresses of the nodes of the given cluster, so that they will be
+// reused by later runs. This is intended for commands that create the namespace of the cluster
+// after allocating the addresses, as the allocations can't be saved before the namespace exists.
+func (i *IPAM) Save(ctx context.Context, cluster *models.Cluster) error {
+	return i.save(ctx, cluster, ipamData(cluster))
+}
+
+// Release deletes the saved allocations of the given cluster. This is intended for commands that
+// delete the cluster.
+func (i *IPAM) Release(ctx context.Context, cluster *models.Cluster) error {
+	if i.dryRun {
+		i.logger.Info(
+			"Not releasing internal IP addresses because of dry run",
+			"cluster", cluster.Name,
+		)
+		return nil
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Name,
+			Name:      ipamConfigMapName,
+		},
+	}
+	err := i.client.Delete(ctx, configMap)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to release IP address allocations of cluster '%s': %w",
+			cluster.Name, err,
+		)
+	}
+	i.logger.Info(
+		"Released IP address allocations",
+		"cluster", cluster.Name,
+		"namespace", configMap.Namespace,
+		"name", configMap.Name,
+	)
+	return nil
+}
+
+func (i *IPAM) load(ctx context.Context, cluster *models.Cluster) (result map[string][]net.IP,
+	err error) {
+	configMap := &corev1.ConfigMap{}
+	key := clnt.ObjectKey{
+		Namespace: cluster.Name,
+		Name:      ipamConfigMapName,
+	}
+	err = i.client.Get(ctx, key, configMap)
+	if apierrors.IsNotFound(err) {
+		result = map[string][]net.IP{}
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf(
+			"failed to load IP address allocations of cluster '%s': %w",
+			cluster.Name, err,
+		)
+		return
+	}
+	result = make(map[string][]net.IP, len(configMap.Data))
+	for name, value := range configMap.Data {
+		var addresses []net.IP
+		for _, text := range strings.Split(value, ",") {
+			address := net.ParseIP(strings.TrimSpace(text))
+			if address == nil {
+				err = fmt.Errorf(
+					"failed to parse IP address '%s' of key '%s' in config map '%s/%s'",
+					text, name, key.Namespace, key.Name,
+				)
+				return
+			}
+			addresses = append(addresses, address)
+		}
+		result[name] = addresses
+	}
+	return
+}
+
+func (i *IPAM) save(ctx context.Context, cluster *models.Cluster, data map[string]string) error {
+	if i.dryRun {
+		i.logger.Info(
+			"Not saving internal IP addresses because of dry run",
+			"cluster", cluster.Name,
+		)
+		return nil
+	}
+
+	// The config map lives in the namespace of the cluster, and that doesn't exist before the
+	// objects of the cluster are created. We don't create it here because that would leave an
+	// empty namespace behind if the rest of the process fails, so in that case the command that
+	// creates the namespace is responsible for calling the Save method later.
+	namespace := &corev1.Namespace{}
+	err := i.client.Get(ctx, clnt.ObjectKey{Name: cluster.Name}, namespace)
+	if apierrors.IsNotFound(err) {
+		i.logger.V(1).Info(
+			"Not saving internal IP addresses because the namespace doesn't exist yet",
+			"cluster", cluster.Name,
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to check namespace for IP address allocations of cluster '%s': %w",
+			cluster.Name, err,
+		)
+	}
+
+	// Create or update the config map:
+	configMap := &corev1.ConfigMap{}
+	key := clnt.ObjectKey{
+		Namespace: cluster.Name,
+		Name:      ipamConfigMapName,
+	}
+	err = i.client.Get(ctx, key, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+				Labels: map[string]string{
+					labels.ZTPFW: "",
+				},
+			},
+			Data: data,
+		}
+		err = i.client.Create(ctx, configMap)
+	} else if err == nil {
+		update := configMap.DeepCopy()
+		update.Data = data
+		err = i.client.Patch(ctx, update, clnt.MergeFrom(configMap))
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to save IP address allocations of cluster '%s': %w",
+			cluster.Name, err,
+		)
+	}
+	i.logger.Info(
+		"Saved IP address allocations",
+		"cluster", cluster.Name,
+		"namespace", key.Namespace,
+		"name", key.Name,
+	)
+	return nil
+}
+
+func (t *ipamTask) run() (err error) {
+	// Reserve the virtual IP addresses and the addresses of the nodes that have been set
+	// explicitly, so that they are never allocated to other nodes:
+	if t.cluster.API.InternalIP != nil {
+		err = t.reserve(t.cluster.API.InternalIP, "internal API IP")
+		if err != nil {
+			return
+		}
+	}
+	if t.cluster.Ingress.InternalIP != nil {
+		err = t.reserve(t.cluster.Ingress.InternalIP, "internal ingress IP")
+		if err != nil {
+			return
+		}
+	}
+	var pending []*models.Node
+	for _, node := range t.cluster.Nodes {
+		if len(node.InternalIPs) == 0 {
+			pending = append(pending, node)
+			continue
+		}
+		for _, ip := range node.InternalIPs {
+			err = t.reserve(ip.Address, t.describe(node))
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// Calculate the keys of the nodes, and check that they are unique:
+	keys := make([]string, len(pending))
+	nodes := map[string]*models.Node{}
+	for j, node := range pending {
+		key := ipamKey(node)
+		other, ok := nodes[key]
+		if ok {
+			err = fmt.Errorf(
+				"nodes '%s' and '%s' of cluster '%s' have the same MAC address",
+				other.Name, node.Name, t.cluster.Name,
+			)
+			return
+		}
+		nodes[key] = node
+		keys[j] = key
+	}
+
+	// Reserve the addresses that were allocated in previous runs. Note that this needs to be
+	// done for all the nodes before allocating new addresses, otherwise a new node could take
+	// the address of a node that is processed later.
+	addresses := make([][]net.IP, len(pending))
+	for j, node := range pending {
+		addresses[j] = make([]net.IP, len(t.cluster.MachineNetworks))
+		for k, network := range t.cluster.MachineNetworks {
+			for _, address := range t.saved[keys[j]] {
+				if !network.CIDR.Contains(address) {
+					continue
+				}
+				err = t.reserve(address, t.describe(node))
+				if err != nil {
+					err = fmt.Errorf(
+						"%w, remove key '%s' from config map '%s/%s' to allocate "+
+							"a new address",
+						err, keys[j], t.cluster.Name, ipamConfigMapName,
+					)
+					return
+				}
+				addresses[j][k] = address
+				break
+			}
+		}
+	}
+
+	// Allocate new addresses where needed:
+	for j, node := range pending {
+		for k, network := range t.cluster.MachineNetworks {
+			if addresses[j][k] != nil {
+				continue
+			}
+			addresses[j][k], err = t.allocate(network.CIDR, node)
+			if err != nil {
+				return
+			}
+			t.logger.Info(
+				"Allocated internal IP address",
+				"cluster", t.cluster.Name,
+				"node", node.Name,
+				"key", keys[j],
+				"address", addresses[j][k],
+			)
+		}
+	}
+
+	// Update the nodes:
+	for j, node := range pending {
+		for k, network := range t.cluster.MachineNetworks {
+			prefix, _ := network.CIDR.Mask.Size()
+			node.InternalIPs = append(node.InternalIPs, &models.IP{
+				Address: addresses[j][k],
+				Prefix:  prefix,
+			})
+		}
+	}
+	return
+}
+
+// reserve marks the given address as used by the given owner. It returns an error if the address
+// is already used by a different owner.
+func (t *ipamTask) reserve(address net.IP, owner string) error {
+	text := address.String()
+	current, ok := t.owners[text]
+	if ok && current != owner {
+		return fmt.Errorf(
+			"address '%s' of %s of cluster '%s' is already used by %s",
+			text, owner, t.cluster.Name, current,
+		)
+	}
+	t.owners[text] = owner
+	return nil
+}
+
+// allocate finds the lowest free address of the given network, starting at the node offset, and
+// reserves it for the given node.
+func (t *ipamTask) allocate(cidr *net.IPNet, node *models.Node) (result net.IP, err error) {
+	for offset := ipamNodeOffset; ; offset++ {
+		var address net.IP
+		address, err = enricherNetworkAddress(cidr, offset)
+		if err != nil || ipamBroadcast(cidr, address) {
+			err = fmt.Errorf(
+				"failed to allocate internal IP for node '%s' of cluster '%s', there are "+
+					"no free addresses left in machine network '%s'",
+				node.Name, t.cluster.Name, cidr,
+			)
+			return
+		}
+		_, used := t.owners[address.String()]
+		if used {
+			continue
+		}
+		t.owners[address.String()] = t.describe(node)
+		result = address
+		return
+	}
+}
+
+func (t *ipamTask) describe(node *models.Node) string {
+	return fmt.Sprintf("node '%s'", node.Name)
+}
+
+// ipamKey calculates the key used to save the addresses of the given node. This is the MAC address
+// of the internal NIC, replacing colons with dashes because colons aren't allowed in the keys of
+// config maps. If the node doesn't have an internal NIC the external MAC address is used instead,
+// and if it doesn't have that either then the name of the node.
+func ipamKey(node *models.Node) string {
+	var mac string
+	if node.InternalNIC != nil {
+		mac = node.InternalNIC.MAC
+	}
+	if mac == "" {
+		mac = node.ExternalMAC()
+	}
+	if mac == "" {
+		return ipamNodeKeyPrefix + node.Name
+	}
+	return strings.ReplaceAll(strings.ToLower(mac), ":", "-")
+}
+
+// ipamBroadcast checks if the given address is the broadcast address of the given network. Only
+// IPv4 networks have a broadcast address.
+func ipamBroadcast(cidr *net.IPNet, address net.IP) bool {
+	if cidr.IP.To4() == nil || len(address) != len(cidr.Mask) {
+		return false
+	}
+	for i := range address {
+		if address[i]|cidr.Mask[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// ipamData calculates the data of the config map from the internal IP addresses of the nodes of the
+// given cluster.
+func ipamData(cluster *models.Cluster) map[string]string {
+	allocations := map[string][]net.IP{}
+	for _, node := range cluster.Nodes {
+		if len(node.InternalIPs) == 0 {
+			continue
+		}
+		addresses := make([]net.IP, len(node.InternalIPs))
+		for i, ip := range node.InternalIPs {
+			addresses[i] = ip.Address
+		}
+		allocations[ipamKey(node)] = addresses
+	}
+	return ipamFormat(allocations)
+}
+
+// ipamFormat converts the allocations to the format used to save them in the config map, where the
+// value of each key is the list of addresses separated by commas.
+func ipamFormat(allocations map[string][]net.IP) map[string]string {
+	result := make(map[string]string, len(allocations))
+	for key, addresses := range allocations {
+		texts := make([]string, len(addresses))
+		for i, address := range addresses {
+			texts[i] = address.String()
+		}
+		result[key] = strings.Join(texts, ",")
+	}
+	return result
+}
+
+// ipamConfigMapName is the name of the config map, inside the namespace of the cluster, that
+// contains the IP address allocations.
+const ipamConfigMapName = "ztpfw-ipam"
+
+// ipamNodeKeyPrefix is the prefix used for the keys of nodes that don't have a MAC address.
+const ipamNodeKeyPrefix = "node."
+
+// ipamNodeOffset is the offset, from the beginning of the machine network, of the first address
+// allocated to nodes. With the default machine network that is 192.168.7.10.
+const ipamNodeOffset = 10
@@ -0,0 +1,367 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+var _ = Describe("IPAM", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.Client
+		ipam   *IPAM
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		err = client.Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "my",
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		ipam, err = NewIPAM().
+			SetLogger(logger).
+			SetClient(client).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// makeNode creates a node with the given name and internal MAC address.
+	makeNode := func(name, mac string) *models.Node {
+		return &models.Node{
+			Kind: models.NodeKindControlPlane,
+			Name: name,
+			InternalNIC: &models.NIC{
+				Name: "eth0",
+				MAC:  mac,
+			},
+		}
+	}
+
+	// makeCluster creates a cluster with the default machine network and virtual IP addresses
+	// and the given nodes.
+	makeCluster := func(nodes ...*models.Node) *models.Cluster {
+		_, cidr, err := net.ParseCIDR("192.168.7.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		cluster := &models.Cluster{
+			Name: "my",
+			MachineNetworks: []*models.MachineNetwork{{
+				CIDR: cidr,
+			}},
+			Nodes: nodes,
+		}
+		cluster.API.InternalIP = net.ParseIP("192.168.7.243")
+		cluster.Ingress.InternalIP = net.ParseIP("192.168.7.242")
+		return cluster
+	}
+
+	// getData returns the data of the config map that contains the allocations.
+	getData := func() map[string]string {
+		configMap := &corev1.ConfigMap{}
+		err := client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my",
+			Name:      ipamConfigMapName,
+		}, configMap)
+		Expect(err).ToNot(HaveOccurred())
+		return configMap.Data
+	}
+
+	It("Can't be created without a client", func() {
+		_, err := NewIPAM().
+			SetLogger(logger).
+			Build()
+		Expect(err).To(MatchError("client is mandatory"))
+	})
+
+	It("Allocates consecutive addresses and saves them", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:02"),
+		)
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+		Expect(cluster.Nodes[1].InternalIPs).To(HaveLen(1))
+		Expect(cluster.Nodes[1].InternalIPs[0].String()).To(Equal("192.168.7.11/24"))
+		Expect(getData()).To(Equal(map[string]string{
+			"52-54-00-00-00-01": "192.168.7.10",
+			"52-54-00-00-00-02": "192.168.7.11",
+		}))
+	})
+
+	It("Doesn't save the addresses in dry run mode", func() {
+		dryRun, err := NewIPAM().
+			SetLogger(logger).
+			SetClient(client).
+			SetDryRun(true).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		err = dryRun.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+		configMap := &corev1.ConfigMap{}
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my",
+			Name:      ipamConfigMapName,
+		}, configMap)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Doesn't create the namespace", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		cluster.Name = "your"
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+		err = client.Get(ctx, clnt.ObjectKey{Name: "your"}, &corev1.Namespace{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "your",
+			Name:      ipamConfigMapName,
+		}, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Saves the addresses once the namespace exists", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		cluster.Name = "your"
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		err = client.Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "your",
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		err = ipam.Save(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		configMap := &corev1.ConfigMap{}
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "your",
+			Name:      ipamConfigMapName,
+		}, configMap)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configMap.Data).To(Equal(map[string]string{
+			"52-54-00-00-00-01": "192.168.7.10",
+		}))
+	})
+
+	It("Releases the addresses of the cluster", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getData()).ToNot(BeEmpty())
+		err = ipam.Release(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		err = client.Get(ctx, clnt.ObjectKey{
+			Namespace: "my",
+			Name:      ipamConfigMapName,
+		}, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		// Releasing again shouldn't fail:
+		err = ipam.Release(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Keeps addresses stable when nodes are added", func() {
+		err := ipam.Allocate(ctx, makeCluster(
+			makeNode("master1", "52:54:00:00:00:02"),
+		))
+		Expect(err).ToNot(HaveOccurred())
+
+		// Add a node that sorts before the existing one:
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:02"),
+		)
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.11/24"))
+		Expect(cluster.Nodes[1].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+	})
+
+	It("Releases the addresses of removed nodes", func() {
+		err := ipam.Allocate(ctx, makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:02"),
+		))
+		Expect(err).ToNot(HaveOccurred())
+		cluster := makeCluster(
+			makeNode("master1", "52:54:00:00:00:02"),
+		)
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.11/24"))
+		Expect(getData()).To(Equal(map[string]string{
+			"52-54-00-00-00-02": "192.168.7.11",
+		}))
+	})
+
+	It("Skips the virtual IP addresses", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:02"),
+		)
+		cluster.API.InternalIP = net.ParseIP("192.168.7.10")
+		cluster.Ingress.InternalIP = net.ParseIP("192.168.7.11")
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.12/24"))
+		Expect(cluster.Nodes[1].InternalIPs[0].String()).To(Equal("192.168.7.13/24"))
+	})
+
+	It("Allocates more than 245 nodes", func() {
+		_, cidr, err := net.ParseCIDR("10.0.0.0/16")
+		Expect(err).ToNot(HaveOccurred())
+		var nodes []*models.Node
+		for i := 0; i < 300; i++ {
+			nodes = append(nodes, makeNode(
+				fmt.Sprintf("worker%d", i),
+				fmt.Sprintf("52:54:00:00:%02x:%02x", i/256, i%256),
+			))
+		}
+		cluster := makeCluster(nodes...)
+		cluster.MachineNetworks[0].CIDR = cidr
+		cluster.API.InternalIP = net.ParseIP("10.0.0.243")
+		cluster.Ingress.InternalIP = net.ParseIP("10.0.0.242")
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[299].InternalIPs[0].String()).To(Equal("10.0.1.55/16"))
+	})
+
+	It("Fails when the network is full", func() {
+		_, cidr, err := net.ParseCIDR("192.168.7.0/28")
+		Expect(err).ToNot(HaveOccurred())
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:02"),
+			makeNode("master2", "52:54:00:00:00:03"),
+			makeNode("master3", "52:54:00:00:00:04"),
+			makeNode("master4", "52:54:00:00:00:05"),
+			makeNode("master5", "52:54:00:00:00:06"),
+		)
+		cluster.MachineNetworks[0].CIDR = cidr
+		cluster.API.InternalIP = net.ParseIP("192.168.7.1")
+		cluster.Ingress.InternalIP = net.ParseIP("192.168.7.2")
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).To(MatchError(ContainSubstring(
+			"no free addresses left in machine network '192.168.7.0/28'",
+		)))
+	})
+
+	It("Detects nodes with the same MAC address", func() {
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+			makeNode("master1", "52:54:00:00:00:01"),
+		)
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).To(MatchError(ContainSubstring(
+			"nodes 'master0' and 'master1' of cluster 'my' have the same MAC address",
+		)))
+	})
+
+	It("Detects saved address that conflicts with a virtual IP address", func() {
+		err := client.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my",
+				Name:      ipamConfigMapName,
+			},
+			Data: map[string]string{
+				"52-54-00-00-00-01": "192.168.7.243",
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).To(MatchError(ContainSubstring(
+			"address '192.168.7.243' of node 'master0' of cluster 'my' is already used " +
+				"by internal API IP",
+		)))
+	})
+
+	It("Doesn't change nodes that already have addresses", func() {
+		explicit := makeNode("master0", "52:54:00:00:00:01")
+		explicit.InternalIPs = []*models.IP{{
+			Address: net.ParseIP("192.168.7.10"),
+			Prefix:  24,
+		}}
+		cluster := makeCluster(
+			explicit,
+			makeNode("master1", "52:54:00:00:00:02"),
+		)
+		err := ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(1))
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+		Expect(cluster.Nodes[1].InternalIPs[0].String()).To(Equal("192.168.7.11/24"))
+	})
+
+	It("Allocates one address for each machine network", func() {
+		_, cidr, err := net.ParseCIDR("fd00:7::/64")
+		Expect(err).ToNot(HaveOccurred())
+		cluster := makeCluster(
+			makeNode("master0", "52:54:00:00:00:01"),
+		)
+		cluster.MachineNetworks = append(cluster.MachineNetworks, &models.MachineNetwork{
+			CIDR: cidr,
+		})
+		err = ipam.Allocate(ctx, cluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cluster.Nodes[0].InternalIPs).To(HaveLen(2))
+		Expect(cluster.Nodes[0].InternalIPs[0].String()).To(Equal("192.168.7.10/24"))
+		Expect(cluster.Nodes[0].InternalIPs[1].String()).To(Equal("fd00:7::a/64"))
+		Expect(getData()).To(Equal(map[string]string{
+			"52-54-00-00-00-01": "192.168.7.10,fd00:7::a",
+		}))
+	})
+})
@@ -30,6 +30,8 @@ import (
 	. "github.com/onsi/ginkgo/v2/dsl/table"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/pflag"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
 )
 
 var _ = Describe("Logger", func() {
@@ -940,6 +942,33 @@ var _ = Describe("Logger", func() {
 		Expect(msg.MyField).To(Equal("my-value"))
 	})
 
+	It("Always redacts secrets even if redacting is disabled", func() {
+		// Create the logger:
+		buffer := &bytes.Buffer{}
+		logger, err := NewLogger().
+			SetWriter(io.MultiWriter(buffer, GinkgoWriter)).
+			SetRedact(false).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Write a message:
+		logger.Info(
+			"my message",
+			"my-field", models.NewSecret("my-value"),
+		)
+
+		// Check that the value isn't in the log:
+		Expect(buffer.String()).ToNot(ContainSubstring("my-value"))
+		lines := strings.Split(buffer.String(), "\n")
+		Expect(lines).To(HaveLen(2))
+		var msg struct {
+			MyField string `json:"my-field"`
+		}
+		err = json.Unmarshal([]byte(lines[0]), &msg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg.MyField).To(Equal("***"))
+	})
+
 	It("Logger with fields redacts sensitive fields like parent", func() {
 		// Create the logger:
 		buffer := &bytes.Buffer{}
@@ -0,0 +1,31 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package models
+
+// Architecture is the CPU architecture of the nodes of a cluster. The values are the ones used by
+// OpenShift in the names of the release images and in the `cpuArchitecture` field of the
+// InfraEnv.
+type Architecture string
+
+const (
+	ArchitectureX86_64  Architecture = "x86_64"
+	ArchitectureAARCH64 Architecture = "aarch64"
+)
+
+// Architectures contains the architectures that are supported.
+var Architectures = []Architecture{
+	ArchitectureX86_64,
+	ArchitectureAARCH64,
+}
@@ -15,7 +15,7 @@ License.
 package models
 
 type BMC struct {
-	Pass string
+	Pass *Secret
 	URL  string
-	User string
+	User *Secret
 }
@@ -0,0 +1,32 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package models
+
+// Bond is an interface that aggregates several physical NICs.
+type Bond struct {
+	Name    string
+	Mode    string
+	Options map[string]string
+	Members []*NIC
+}
+
+// MAC returns the MAC address of the first member of the bond, which is the one that the bond uses
+// by default.
+func (b *Bond) MAC() string {
+	if len(b.Members) == 0 {
+		return ""
+	}
+	return b.Members[0].MAC
+}
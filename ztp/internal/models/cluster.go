@@ -16,12 +16,17 @@ package models
 
 type Cluster struct {
 	API             API
+	Architecture    Architecture
 	DNS             DNS
 	ImageSet        string
+	ReleaseImage    string
+	OCPTag          string
+	RHCOSRelease    string
 	Ingress         Ingress
 	Name            string
 	Nodes           []*Node
 	PullSecret      []byte
+	PullSecretRef   *Secret
 	SNO             bool
 	SSH             SSH
 	TPM             bool
@@ -72,3 +77,23 @@ func (c *Cluster) NodeNames() []string {
 	}
 	return names
 }
+
+// IPv4 returns true if any of the machine networks of the cluster is an IPv4 network.
+func (c *Cluster) IPv4() bool {
+	for _, network := range c.MachineNetworks {
+		if network.CIDR.IP.To4() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IPv6 returns true if any of the machine networks of the cluster is an IPv6 network.
+func (c *Cluster) IPv6() bool {
+	for _, network := range c.MachineNetworks {
+		if network.CIDR.IP.To4() == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -15,7 +15,10 @@ License.
 package models
 
 import (
+	"net"
+
 	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/ginkgo/v2/dsl/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -55,4 +58,23 @@ var _ = Describe("Clusters", func() {
 		Expect(workerNodes[0].Kind).To(Equal(NodeKindWorker))
 		Expect(workerNodes[1].Kind).To(Equal(NodeKindWorker))
 	})
+
+	DescribeTable(
+		"Calculates IP families from machine networks",
+		func(cidrs []string, ipv4, ipv6 bool) {
+			cluster := &Cluster{}
+			for _, cidr := range cidrs {
+				_, network, err := net.ParseCIDR(cidr)
+				Expect(err).ToNot(HaveOccurred())
+				cluster.MachineNetworks = append(cluster.MachineNetworks, &MachineNetwork{
+					CIDR: network,
+				})
+			}
+			Expect(cluster.IPv4()).To(Equal(ipv4))
+			Expect(cluster.IPv6()).To(Equal(ipv6))
+		},
+		Entry("IPv4", []string{"192.168.7.0/24"}, true, false),
+		Entry("IPv6", []string{"fd00:7::/64"}, false, true),
+		Entry("Dual stack", []string{"192.168.7.0/24", "fd00:7::/64"}, true, true),
+	)
 })
@@ -20,3 +20,9 @@ type Ingress struct {
 	InternalIP net.IP
 	ExternalIP net.IP
 }
+
+// ExternalPrefix returns the prefix length that selects exactly the external IP address, 32 for
+// IPv4 and 128 for IPv6.
+func (i Ingress) ExternalPrefix() int {
+	return HostPrefix(i.ExternalIP)
+}
@@ -33,6 +33,25 @@ func (i *IP) String() string {
 	return fmt.Sprintf("%s/%d", i.Address.String(), i.Prefix)
 }
 
+// IsIPv4 returns true if this is an IPv4 address.
+func (i *IP) IsIPv4() bool {
+	return i.Address.To4() != nil
+}
+
+// IsIPv6 returns true if this is an IPv6 address.
+func (i *IP) IsIPv6() bool {
+	return i.Address.To4() == nil && i.Address.To16() != nil
+}
+
+// HostPrefix returns the prefix length that selects exactly the given address, 32 for IPv4 and 128
+// for IPv6.
+func HostPrefix(address net.IP) int {
+	if address.To4() != nil {
+		return 32
+	}
+	return 128
+}
+
 // ParseIP parses the given text as an IP address and mask. The expected input is a string like
 // 192.168.122.123/24.
 func ParseIP(s string) (ip *IP, err error) {
@@ -103,4 +103,41 @@ var _ = Describe("IP", func() {
 			"192.168.122.123/26",
 		),
 	)
+
+	DescribeTable(
+		"Parses IPv6 string correctly",
+		func(s string, expected *IP) {
+			actual, err := ParseIP(s)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual.Address.Equal(expected.Address)).To(BeTrue())
+			Expect(actual.Prefix).To(Equal(expected.Prefix))
+			Expect(actual.IsIPv6()).To(BeTrue())
+			Expect(actual.IsIPv4()).To(BeFalse())
+		},
+		Entry(
+			"Unique local",
+			"fd00:7::a/64",
+			&IP{
+				Address: net.ParseIP("fd00:7::a"),
+				Prefix:  64,
+			},
+		),
+		Entry(
+			"Global",
+			"2001:db8::1/48",
+			&IP{
+				Address: net.ParseIP("2001:db8::1"),
+				Prefix:  48,
+			},
+		),
+	)
+
+	DescribeTable(
+		"Calculates host prefix",
+		func(s string, expected int) {
+			Expect(HostPrefix(net.ParseIP(s))).To(Equal(expected))
+		},
+		Entry("IPv4", "192.168.122.123", 32),
+		Entry("IPv6", "fd00:7::a", 128),
+	)
 })
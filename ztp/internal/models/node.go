@@ -15,6 +15,8 @@ License.
 package models
 
 import (
+	"fmt"
+	"net"
 	"regexp"
 )
 
@@ -33,23 +35,98 @@ type Node struct {
 	RootDisk     string
 	StorageDisks []string
 	InternalNIC  *NIC
-	InternalIP   *IP
+	InternalIPs  []*IP
 	ExternalNIC  *NIC
+	ExternalBond *Bond
+	ExternalVLAN *VLAN
 	ExternalIP   *IP
-	IgnoredNICs  []string
+
+	// ExternalStatic indicates that the external IP address, gateway and DNS servers have been
+	// explicitly configured, so the external NIC will not use DHCP.
+	ExternalStatic  bool
+	ExternalGateway net.IP
+	ExternalDNS     []net.IP
+
+	IgnoredNICs []string
+}
+
+// ExternalInterface returns the name of the interface that carries the external IP address. That
+// is the VLAN if there is one, otherwise the bond or the NIC. The result will be empty if the node
+// doesn't have an external interface.
+func (n *Node) ExternalInterface() string {
+	switch {
+	case n.ExternalVLAN != nil:
+		return n.ExternalVLAN.Name
+	case n.ExternalBond != nil:
+		return n.ExternalBond.Name
+	case n.ExternalNIC != nil:
+		return n.ExternalNIC.Name
+	default:
+		return ""
+	}
+}
+
+// ExternalBase returns the name of the interface that the external VLAN is built on top of, the
+// bond or the NIC.
+func (n *Node) ExternalBase() string {
+	switch {
+	case n.ExternalBond != nil:
+		return n.ExternalBond.Name
+	case n.ExternalNIC != nil:
+		return n.ExternalNIC.Name
+	default:
+		return ""
+	}
 }
 
-// Index extracts the index from the name of the node. For example, if the name is `worker123` then
-// the index will be `123`. This is needed because currently some of our pipelines rely on node
-// names having that index. This will be removed when those pipelines have been updatedd, so refrain
-// from using it.
-func (n *Node) Index() string {
-	matches := nodeIndexRE.FindStringSubmatch(n.Name)
-	if matches == nil {
+// ExternalMAC returns the MAC address of the external NIC, or of the first member of the external
+// bond. This is the address that the node uses to boot.
+func (n *Node) ExternalMAC() string {
+	switch {
+	case n.ExternalBond != nil:
+		return n.ExternalBond.MAC()
+	case n.ExternalNIC != nil:
+		return n.ExternalNIC.MAC
+	default:
 		return ""
 	}
-	return matches[1]
 }
 
-// nodeIndexRE is the regular expression used to extract the numeric index from the name of the node.
-var nodeIndexRE = regexp.MustCompile(`(\d+)$`)
+// ExternalNICs returns the physical NICs used for the external network. That is the members of the
+// bond if there is one, otherwise the external NIC.
+func (n *Node) ExternalNICs() []*NIC {
+	switch {
+	case n.ExternalBond != nil:
+		return n.ExternalBond.Members
+	case n.ExternalNIC != nil:
+		return []*NIC{n.ExternalNIC}
+	default:
+		return nil
+	}
+}
+
+// DefaultHostname calculates the host name that is used for the node when the configuration doesn't
+// explicitly set one. Nodes that use the old naming convention, like `master0` or `worker1`, get a
+// host name that contains the kind and the index extracted from the name, so that clusters created
+// before the `role` and `hostname` fields were introduced keep the same host names. Other nodes get a
+// host name derived from their name. The result will be empty if the kind of the node is unknown.
+func (n *Node) DefaultHostname(cluster string) string {
+	var kind string
+	switch n.Kind {
+	case NodeKindControlPlane:
+		kind = "master"
+	case NodeKindWorker:
+		kind = "worker"
+	default:
+		return ""
+	}
+	matches := legacyNodeNameRE.FindStringSubmatch(n.Name)
+	if matches != nil {
+		return fmt.Sprintf("ztpfw-%s-%s-%s", cluster, kind, matches[1])
+	}
+	return fmt.Sprintf("ztpfw-%s-%s", cluster, n.Name)
+}
+
+// legacyNodeNameRE is the regular expression used to detect node names that follow the old naming
+// convention, like `master0` or `worker1`, and to extract the index from them.
+var legacyNodeNameRE = regexp.MustCompile(`^(?:master|worker)(\d+)$`)
@@ -20,68 +20,80 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("Nodes", func() {
+var _ = Describe("Node", func() {
 	DescribeTable(
-		"Extracts index from node name",
-		func(node *Node, expected string) {
-			actual := node.Index()
-			Expect(actual).To(Equal(expected))
+		"Calculates external interface",
+		func(node *Node, iface, base, mac string) {
+			Expect(node.ExternalInterface()).To(Equal(iface))
+			Expect(node.ExternalBase()).To(Equal(base))
+			Expect(node.ExternalMAC()).To(Equal(mac))
 		},
 		Entry(
-			"Control plane node one digit",
-			&Node{
-				Name: "master0",
-			},
-			"0",
-		),
-		Entry(
-			"Control plane node two digits",
-			&Node{
-				Name: "master12",
-			},
-			"12",
-		),
-		Entry(
-			"Control plane node three digits",
-			&Node{
-				Name: "master123",
-			},
-			"123",
-		),
-		Entry(
-			"Worker node one digit",
-			&Node{
-				Name: "worker0",
-			},
-			"0",
+			"Nothing",
+			&Node{},
+			"", "", "",
 		),
 		Entry(
-			"Worker node two digits",
+			"NIC",
 			&Node{
-				Name: "worker12",
+				ExternalNIC: &NIC{
+					Name: "eth0",
+					MAC:  "aa:bb:cc:dd:ee:00",
+				},
 			},
-			"12",
+			"eth0", "eth0", "aa:bb:cc:dd:ee:00",
 		),
 		Entry(
-			"Worker node three digits",
+			"VLAN on NIC",
 			&Node{
-				Name: "worker123",
+				ExternalNIC: &NIC{
+					Name: "eth0",
+					MAC:  "aa:bb:cc:dd:ee:00",
+				},
+				ExternalVLAN: &VLAN{
+					Name: "eth0.100",
+					ID:   100,
+				},
 			},
-			"123",
+			"eth0.100", "eth0", "aa:bb:cc:dd:ee:00",
 		),
 		Entry(
-			"Unknown node kind",
+			"Bond",
 			&Node{
-				Name: "junk123",
+				ExternalBond: &Bond{
+					Name: "bond0",
+					Members: []*NIC{
+						{
+							Name: "eth0",
+							MAC:  "aa:bb:cc:dd:ee:00",
+						},
+						{
+							Name: "eth1",
+							MAC:  "aa:bb:cc:dd:ee:01",
+						},
+					},
+				},
 			},
-			"123",
+			"bond0", "bond0", "aa:bb:cc:dd:ee:00",
 		),
 		Entry(
-			"No index",
+			"VLAN on bond",
 			&Node{
-				Name: "worker",
+				ExternalBond: &Bond{
+					Name: "bond0",
+					Members: []*NIC{
+						{
+							Name: "eth0",
+							MAC:  "aa:bb:cc:dd:ee:00",
+						},
+					},
+				},
+				ExternalVLAN: &VLAN{
+					Name: "bond0.100",
+					ID:   100,
+				},
 			},
-			"",
+			"bond0.100", "bond0", "aa:bb:cc:dd:ee:00",
 		),
 	)
 })
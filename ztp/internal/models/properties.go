@@ -20,9 +20,14 @@ package models
 // for the installation of clusters.
 const OCPVersionProperty = "OC_OCP_VERSION"
 
+// OCPArchitectureProperty is the default CPU architecture of the clusters, for example `x86_64` or
+// `aarch64`. If not specified then it will be `x86_64`. Clusters can use a different architecture
+// with the `architecture` field of their configuration.
+const OCPArchitectureProperty = "OC_OCP_ARCH"
+
 // OCPTagProperty is the image tag of the OpenShift version. If not specified then the tag will be
-// calculated adding the `-x86_64` suffix. For example, of the value of `OC_OCP_VERSION` is
-// `4.10.38` then the value of this will be `4.10.38-x86_64`.
+// calculated adding the architecture as suffix. For example, of the value of `OC_OCP_VERSION` is
+// `4.10.38` and the architecture is `x86_64` then the value of this will be `4.10.38-x86_64`.
 const OCPTagProperty = "OC_OCP_TAG"
 
 // OCPRCHOSReleaseProperty is the full release number of the Red Hat Enterprise Linux CoreOS to be
@@ -30,14 +35,15 @@ const OCPTagProperty = "OC_OCP_TAG"
 // `release.txt` file corresponding to the version specified in `OC_OCP_VERSION`.
 const OCPRCHOSReleaseProperty = "OC_RHCOS_RELEASE"
 
-// OCPMirrorProperty is the base URL for the OCP mirror that will be used to download the
-// `release.txt` file. The default is to use `https://mirror.openshift.com/pub/openshift-v4/clients/ocp/`
-// and there is usually no need to change it. This is only intended for use in unit tests.
+// OCPMirrorProperty is the base URL of the mirror used to download the `release.txt` file. There
+// is usually no need to change it, it is intended for use in unit tests.
 const OCPMirrorProperty = "OC_OCP_MIRROR"
 
 // ClusterImageSetProperty is the name of the Hive cluster image set that will be used for the
-// installation of the cluster. The default is to calculate it from the OCM version. For example, if
-// the OCP version is `4.10.38` then the value will be `openshift-v4.10.38`.
+// installation of the cluster. The default is to calculate it from the OCP version. For example, if
+// the OCP version is `4.10.38` then the value will be `openshift-v4.10.38`. Clusters that use an
+// architecture different to the default one use this name followed by the architecture, for
+// example `openshift-v4.10.38-aarch64`.
 const ClusterImageSetProperty = "clusterimageset"
 
 // RegistryProperty is the URL of a custom image registry to use for the clusters.
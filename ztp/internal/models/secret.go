@@ -0,0 +1,210 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SecretSource indicates where the value of a secret comes from.
+type SecretSource string
+
+const (
+	// SecretSourceValue is used when the value is given directly in the configuration.
+	SecretSourceValue SecretSource = ""
+
+	// SecretSourceEnv is used when the value is taken from an environment variable.
+	SecretSourceEnv SecretSource = "env"
+
+	// SecretSourceFile is used when the value is taken from a file.
+	SecretSourceFile SecretSource = "file"
+
+	// SecretSourceKube is used when the value is taken from a Kubernetes secret in the hub.
+	SecretSourceKube SecretSource = "secret"
+)
+
+// Secret contains a security sensitive value, like a password. The value can be given directly or
+// as a reference to the place where it is stored:
+//
+//	env:VAR             - The value of the environment variable `VAR`.
+//	file:/path          - The content of the file `/path`.
+//	secret:ns/name#key  - The `key` entry of the Kubernetes secret `name` in namespace `ns` of the
+//	                      hub cluster.
+//
+// References aren't resolved when the configuration is loaded, only when the value is needed, see
+// the SecretResolver type for details. The value is never included in the text generated by the
+// String, MarshalLog or MarshalJSON methods, so it is safe to write secrets to the log.
+type Secret struct {
+	Source    SecretSource
+	Env       string
+	File      string
+	Namespace string
+	Name      string
+	Key       string
+
+	value    string
+	resolved bool
+}
+
+// NewSecret creates a secret that contains the given value.
+func NewSecret(value string) *Secret {
+	return &Secret{
+		value:    value,
+		resolved: true,
+	}
+}
+
+// ParseSecret parses the given text as a secret. If the text starts with one of the `env:`, `file:`
+// or `secret:` prefixes it will be parsed as a reference, otherwise it will be used as the value.
+func ParseSecret(text string) (result *Secret, err error) {
+	prefix, rest, found := strings.Cut(text, ":")
+	if !found {
+		result = NewSecret(text)
+		return
+	}
+	switch SecretSource(prefix) {
+	case SecretSourceEnv:
+		if rest == "" {
+			err = fmt.Errorf(
+				"failed to parse secret reference '%s' because the name of the "+
+					"environment variable is empty",
+				text,
+			)
+			return
+		}
+		result = &Secret{
+			Source: SecretSourceEnv,
+			Env:    rest,
+		}
+	case SecretSourceFile:
+		if rest == "" {
+			err = fmt.Errorf(
+				"failed to parse secret reference '%s' because the name of the "+
+					"file is empty",
+				text,
+			)
+			return
+		}
+		result = &Secret{
+			Source: SecretSourceFile,
+			File:   rest,
+		}
+	case SecretSourceKube:
+		path, key, _ := strings.Cut(rest, "#")
+		namespace, name, _ := strings.Cut(path, "/")
+		if namespace == "" || name == "" || key == "" {
+			err = fmt.Errorf(
+				"failed to parse secret reference '%s' because it doesn't have "+
+					"the 'secret:namespace/name#key' format",
+				text,
+			)
+			return
+		}
+		result = &Secret{
+			Source:    SecretSourceKube,
+			Namespace: namespace,
+			Name:      name,
+			Key:       key,
+		}
+	default:
+		result = NewSecret(text)
+	}
+	return
+}
+
+// Ref returns the text of the reference, for example `env:BMC_PASS`. For secrets that were given
+// directly it returns an empty string.
+func (s *Secret) Ref() string {
+	switch s.Source {
+	case SecretSourceEnv:
+		return fmt.Sprintf("env:%s", s.Env)
+	case SecretSourceFile:
+		return fmt.Sprintf("file:%s", s.File)
+	case SecretSourceKube:
+		return fmt.Sprintf("secret:%s/%s#%s", s.Namespace, s.Name, s.Key)
+	default:
+		return ""
+	}
+}
+
+// Resolved returns true if the value of the secret is already available.
+func (s *Secret) Resolved() bool {
+	return s.resolved
+}
+
+// Resolve saves the value of the secret. This is intended for use by the resolver.
+func (s *Secret) Resolve(value string) {
+	s.value = value
+	s.resolved = true
+}
+
+// Value returns the value of the secret. It returns an error if the secret is a reference that
+// hasn't been resolved yet.
+func (s *Secret) Value() (result string, err error) {
+	if !s.resolved {
+		err = fmt.Errorf("secret '%s' hasn't been resolved", s.Ref())
+		return
+	}
+	result = s.value
+	return
+}
+
+// String returns a text that describes the secret without the value, so that it can be safely
+// written to the log or to the terminal.
+func (s *Secret) String() string {
+	ref := s.Ref()
+	if ref != "" {
+		return ref
+	}
+	return secretRedacted
+}
+
+// MarshalLog implements the logr.Marshaler interface so that the value of the secret is never
+// written to the log.
+func (s *Secret) MarshalLog() any {
+	return s.String()
+}
+
+// MarshalJSON implements the json.Marshaler interface so that the value of the secret is never
+// included in the generated JSON documents.
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. References are parsed again, but values
+// that were redacted by the MarshalJSON method result in a secret without value, so that trying to
+// use it fails instead of silently using the redacted text.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+	if text == secretRedacted {
+		*s = Secret{}
+		return nil
+	}
+	parsed, err := ParseSecret(text)
+	if err != nil {
+		return err
+	}
+	*s = *parsed
+	return nil
+}
+
+// secretRedacted is the text that replaces the values of secrets in logs and JSON documents.
+const secretRedacted = "***"
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/ginkgo/v2/dsl/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret", func() {
+	DescribeTable(
+		"Parses references",
+		func(text string, expected *Secret) {
+			actual, err := ParseSecret(text)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+			Expect(actual.Ref()).To(Equal(text))
+		},
+		Entry(
+			"Environment variable",
+			"env:BMC_PASS",
+			&Secret{
+				Source: SecretSourceEnv,
+				Env:    "BMC_PASS",
+			},
+		),
+		Entry(
+			"File",
+			"file:/etc/bmc/pass",
+			&Secret{
+				Source: SecretSourceFile,
+				File:   "/etc/bmc/pass",
+			},
+		),
+		Entry(
+			"Kubernetes secret",
+			"secret:my-ns/my-secret#password",
+			&Secret{
+				Source:    SecretSourceKube,
+				Namespace: "my-ns",
+				Name:      "my-secret",
+				Key:       "password",
+			},
+		),
+	)
+
+	DescribeTable(
+		"Uses text without known prefix as value",
+		func(text string) {
+			secret, err := ParseSecret(text)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Source).To(Equal(SecretSourceValue))
+			Expect(secret.Resolved()).To(BeTrue())
+			Expect(secret.Value()).To(Equal(text))
+		},
+		Entry("Plain", "my-pass"),
+		Entry("With colon", "my:pass"),
+		Entry("Empty", ""),
+	)
+
+	DescribeTable(
+		"Rejects invalid references",
+		func(text string) {
+			_, err := ParseSecret(text)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("Empty variable", "env:"),
+		Entry("Empty file", "file:"),
+		Entry("Missing key", "secret:my-ns/my-secret"),
+		Entry("Missing namespace", "secret:my-secret#my-key"),
+	)
+
+	It("Fails to return value of unresolved reference", func() {
+		secret, err := ParseSecret("env:BMC_PASS")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = secret.Value()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("env:BMC_PASS"))
+		secret.Resolve("my-pass")
+		Expect(secret.Value()).To(Equal("my-pass"))
+	})
+
+	It("Never includes the value in generated text", func() {
+		secret := NewSecret("my-pass")
+		Expect(secret.String()).ToNot(ContainSubstring("my-pass"))
+		Expect(fmt.Sprintf("%v", secret)).ToNot(ContainSubstring("my-pass"))
+		Expect(secret.MarshalLog()).ToNot(ContainSubstring("my-pass"))
+		data, err := json.Marshal(&BMC{
+			User: NewSecret("my-user"),
+			Pass: secret,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).ToNot(ContainSubstring("my-user"))
+		Expect(string(data)).ToNot(ContainSubstring("my-pass"))
+	})
+
+	It("Shows the reference in generated text", func() {
+		secret, err := ParseSecret("env:BMC_PASS")
+		Expect(err).ToNot(HaveOccurred())
+		secret.Resolve("my-pass")
+		Expect(secret.String()).To(Equal("env:BMC_PASS"))
+		data, err := json.Marshal(secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`"env:BMC_PASS"`))
+	})
+
+	It("Parses the reference from JSON without resolving it", func() {
+		var bmc BMC
+		err := json.Unmarshal([]byte(`{"User": "env:BMC_USER", "Pass": "***"}`), &bmc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmc.User.Source).To(Equal(SecretSourceEnv))
+		Expect(bmc.User.Env).To(Equal("BMC_USER"))
+		Expect(bmc.User.Resolved()).To(BeFalse())
+		Expect(bmc.Pass.Resolved()).To(BeFalse())
+		_, err = bmc.Pass.Value()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+// SecretResolverBuilder contains the data and logic needed to create an object that resolves
+// secret references. Don't create instances of this type directly, use the NewSecretResolver
+// function instead.
+type SecretResolverBuilder struct {
+	logger logr.Logger
+	client clnt.Client
+}
+
+// SecretResolver knows how to find the values of secrets that are given as references to
+// environment variables, files or Kubernetes secrets in the hub cluster. Don't create instances of
+// this type directly, use the NewSecretResolver function instead.
+type SecretResolver struct {
+	logger logr.Logger
+	client clnt.Client
+}
+
+// NewSecretResolver creates a builder that can then be used to create a secret resolver.
+func NewSecretResolver() *SecretResolverBuilder {
+	return &SecretResolverBuilder{}
+}
+
+// SetLogger sets the logger that the resolver will use to write log messages. This is mandatory.
+func (b *SecretResolverBuilder) SetLogger(value logr.Logger) *SecretResolverBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the resolver will use to read secrets from the hub
+// cluster. This is optional, but without it references to Kubernetes secrets can't be resolved.
+func (b *SecretResolverBuilder) SetClient(value clnt.Client) *SecretResolverBuilder {
+	b.client = value
+	return b
+}
+
+// Build uses the data stored in the builder to create a new secret resolver.
+func (b *SecretResolverBuilder) Build() (result *SecretResolver, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+
+	// Create and populate the object:
+	result = &SecretResolver{
+		logger: b.logger,
+		client: b.client,
+	}
+	return
+}
+
+// Resolve finds the value of the given secret and saves it inside the secret. It does nothing if
+// the secret is nil or if it has already been resolved.
+func (r *SecretResolver) Resolve(ctx context.Context, secret *models.Secret) error {
+	if secret == nil || secret.Resolved() {
+		return nil
+	}
+	var value string
+	var err error
+	switch secret.Source {
+	case models.SecretSourceEnv:
+		value, err = r.resolveEnv(secret)
+	case models.SecretSourceFile:
+		value, err = r.resolveFile(secret)
+	case models.SecretSourceKube:
+		value, err = r.resolveKube(ctx, secret)
+	default:
+		err = fmt.Errorf("unknown secret source '%s'", secret.Source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve secret '%s': %w", secret, err)
+	}
+	secret.Resolve(value)
+	r.logger.V(1).Info(
+		"Resolved secret",
+		"ref", secret.Ref(),
+	)
+	return nil
+}
+
+func (r *SecretResolver) resolveEnv(secret *models.Secret) (result string, err error) {
+	result, ok := os.LookupEnv(secret.Env)
+	if !ok {
+		err = fmt.Errorf("environment variable '%s' isn't set", secret.Env)
+	}
+	return
+}
+
+func (r *SecretResolver) resolveFile(secret *models.Secret) (result string, err error) {
+	data, err := os.ReadFile(secret.File)
+	if errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("file '%s' doesn't exist", secret.File)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	// Files are usually created with editors or with `echo`, and both add a line terminator that
+	// isn't part of the value, so we remove it:
+	result = strings.TrimSuffix(string(data), "\n")
+	return
+}
+
+func (r *SecretResolver) resolveKube(ctx context.Context,
+	secret *models.Secret) (result string, err error) {
+	if r.client == nil {
+		err = errors.New("access to the hub cluster is needed to read Kubernetes secrets")
+		return
+	}
+	object := &corev1.Secret{}
+	key := clnt.ObjectKey{
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+	}
+	err = r.client.Get(ctx, key, object)
+	if apierrors.IsNotFound(err) {
+		err = fmt.Errorf("secret '%s/%s' doesn't exist", key.Namespace, key.Name)
+		return
+	}
+	if err != nil {
+		return
+	}
+	data, ok := object.Data[secret.Key]
+	if !ok {
+		err = fmt.Errorf(
+			"secret '%s/%s' doesn't contain the '%s' key",
+			key.Namespace, key.Name, secret.Key,
+		)
+		return
+	}
+	result = string(data)
+	return
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/models"
+)
+
+var _ = Describe("Secret resolver", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Create a context:
+		ctx = context.Background()
+
+		// Create the logger:
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Can't be created without a logger", func() {
+		resolver, err := NewSecretResolver().Build()
+		Expect(err).To(MatchError("logger is mandatory"))
+		Expect(resolver).To(BeNil())
+	})
+
+	It("Does nothing for values given directly", func() {
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		secret := models.NewSecret("my-value")
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Value()).To(Equal("my-value"))
+	})
+
+	It("Resolves environment variable", func() {
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		name := "ZTP_TEST_" + uuid.NewString()[0:8]
+		os.Setenv(name, "my-value")
+		defer os.Unsetenv(name)
+		secret, err := models.ParseSecret("env:" + name)
+		Expect(err).ToNot(HaveOccurred())
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Value()).To(Equal("my-value"))
+	})
+
+	It("Fails if environment variable isn't set", func() {
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		secret, err := models.ParseSecret("env:ZTP_TEST_DOES_NOT_EXIST")
+		Expect(err).ToNot(HaveOccurred())
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("ZTP_TEST_DOES_NOT_EXIST"))
+		Expect(secret.Resolved()).To(BeFalse())
+	})
+
+	It("Resolves file removing the line terminator", func() {
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		tmp, err := os.MkdirTemp("", "*.test")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmp)
+		file := filepath.Join(tmp, "pass")
+		err = os.WriteFile(file, []byte("my-value\n"), 0600)
+		Expect(err).ToNot(HaveOccurred())
+		secret, err := models.ParseSecret("file:" + file)
+		Expect(err).ToNot(HaveOccurred())
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Value()).To(Equal("my-value"))
+	})
+
+	It("Fails to resolve Kubernetes secret without client", func() {
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		secret, err := models.ParseSecret("secret:my-ns/my-secret#my-key")
+		Expect(err).ToNot(HaveOccurred())
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("hub cluster"))
+	})
+
+	It("Resolves Kubernetes secret", func() {
+		// Create the client:
+		client, err := NewClient().
+			SetLogger(logger).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+
+		// Create the namespace and the secret:
+		name := "a" + uuid.NewString()
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		}
+		err = client.Create(ctx, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() {
+			err := client.Delete(ctx, namespace)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		object := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: name,
+				Name:      "my-secret",
+			},
+			Data: map[string][]byte{
+				"my-key": []byte("my-value"),
+			},
+		}
+		err = client.Create(ctx, object)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Resolve the reference:
+		resolver, err := NewSecretResolver().
+			SetLogger(logger).
+			SetClient(client).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		secret, err := models.ParseSecret("secret:" + name + "/my-secret#my-key")
+		Expect(err).ToNot(HaveOccurred())
+		err = resolver.Resolve(ctx, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Value()).To(Equal("my-value"))
+	})
+})
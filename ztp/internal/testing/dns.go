@@ -18,18 +18,23 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/miekg/dns"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
 	. "github.com/onsi/gomega"
 )
 
-// DNSServer is a simple DNS server intendef for use in tests.
+// DNSServer is a simple DNS server intendef for use in tests. It also supports the RFC 2136 dynamic
+// updates, optionally signed with TSIG keys.
 type DNSServer struct {
 	server  *dns.Server
 	address string
+	lock    *sync.Mutex
 	zones   []*dnsZone
+	keys    map[string]string
 }
 
 type dnsZone struct {
@@ -40,7 +45,10 @@ type dnsZone struct {
 
 func NewDNSServer() *DNSServer {
 	// Create the server:
-	s := &DNSServer{}
+	s := &DNSServer{
+		lock: &sync.Mutex{},
+		keys: map[string]string{},
+	}
 
 	// Add the default zones:
 	s.addReverseZone()
@@ -57,6 +65,16 @@ func NewDNSServer() *DNSServer {
 		Net:        "udp",
 		PacketConn: listener,
 		Handler:    dns.HandlerFunc(s.serve),
+		TsigSecret: s.keys,
+		MsgAcceptFunc: func(header dns.Header) dns.MsgAcceptAction {
+			// The default function rejects dynamic updates, so we need to accept them
+			// explicitly:
+			opcode := int(header.Bits>>11) & 0xF
+			if opcode == dns.OpcodeUpdate {
+				return dns.MsgAccept
+			}
+			return dns.DefaultMsgAcceptFunc(header)
+		},
 		NotifyStartedFunc: func() {
 			started.Store(true)
 		},
@@ -76,11 +94,25 @@ func NewDNSServer() *DNSServer {
 
 // AddZone adds a zone to the server. This must be done before adding any record for that zone.
 func (s *DNSServer) AddZone(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	s.addDirectZone(name)
 }
 
+// AddKey adds a TSIG key. When at least one key has been added dynamic updates will only be
+// accepted if they are signed with one of the keys. This must be done before sending any request
+// to the server.
+func (s *DNSServer) AddKey(name, secret string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys[dns.Fqdn(name)] = secret
+}
+
 // AddHost adds an A record and the corresponding PTR record to the server.
 func (s *DNSServer) AddHost(name, address string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	// We only support IPv4:
 	a := net.ParseIP(address).To4()
 	Expect(a).ToNot(BeNil())
@@ -226,6 +258,12 @@ func (s *DNSServer) addDirectZone(name string) {
 
 func (s *DNSServer) serve(w dns.ResponseWriter, r *dns.Msg) {
 	defer GinkgoRecover()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if r.Opcode == dns.OpcodeUpdate {
+		s.update(w, r)
+		return
+	}
 	if len(r.Question) != 1 {
 		s.sendError(w, r, nil)
 		return
@@ -248,6 +286,71 @@ func (s *DNSServer) serve(w dns.ResponseWriter, r *dns.Msg) {
 	s.sendError(w, r, zone)
 }
 
+// update processes a dynamic update request. Only the subset of RFC 2136 needed by the tests is
+// supported: prerequisites are ignored, and records can be added, or deleted by name and type or
+// by name, type and data.
+func (s *DNSServer) update(w dns.ResponseWriter, r *dns.Msg) {
+	// Check the signature:
+	if len(s.keys) > 0 && (r.IsTsig() == nil || w.TsigStatus() != nil) {
+		s.sendRcode(w, r, dns.RcodeNotAuth)
+		return
+	}
+
+	// Find the zone:
+	if len(r.Question) != 1 {
+		s.sendRcode(w, r, dns.RcodeFormatError)
+		return
+	}
+	zone := s.findZone(r.Question[0].Name)
+	if zone == nil || zone.name != r.Question[0].Name {
+		s.sendRcode(w, r, dns.RcodeNotZone)
+		return
+	}
+
+	// Apply the changes:
+	for _, change := range r.Ns {
+		header := change.Header()
+		var deleted func(dns.RR) bool
+		switch header.Class {
+		case dns.ClassANY:
+			deleted = func(record dns.RR) bool {
+				current := record.Header()
+				return current.Name == header.Name &&
+					(header.Rrtype == dns.TypeANY || current.Rrtype == header.Rrtype)
+			}
+		case dns.ClassNONE:
+			deleted = func(record dns.RR) bool {
+				clone := dns.Copy(change)
+				clone.Header().Class = dns.ClassINET
+				clone.Header().Ttl = record.Header().Ttl
+				return dns.IsDuplicate(record, clone)
+			}
+		default:
+			zone.records = append(zone.records, change)
+			continue
+		}
+		var kept []dns.RR
+		for _, record := range zone.records {
+			if !deleted(record) {
+				kept = append(kept, record)
+			}
+		}
+		zone.records = kept
+	}
+	s.sendRcode(w, r, dns.RcodeSuccess)
+}
+
+func (s *DNSServer) sendRcode(w dns.ResponseWriter, r *dns.Msg, rcode int) {
+	m := &dns.Msg{}
+	m.SetRcode(r, rcode)
+	if r.IsTsig() != nil && w.TsigStatus() == nil {
+		tsig := r.IsTsig()
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+	err := w.WriteMsg(m)
+	Expect(err).ToNot(HaveOccurred())
+}
+
 func (s *DNSServer) sendError(w dns.ResponseWriter, r *dns.Msg, zone *dnsZone) {
 	m := &dns.Msg{}
 	m.Authoritative = true
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package text
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff compares two texts line by line and returns the differences using the unified diff format,
+// with three lines of context around each change. The names are used in the header lines. The
+// result is the empty string if the texts are equal.
+func Diff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diffOps(diffLines(from), diffLines(to))
+	buffer := &strings.Builder{}
+	fmt.Fprintf(buffer, "--- %s\n", fromName)
+	fmt.Fprintf(buffer, "+++ %s\n", toName)
+	for _, hunk := range diffHunks(ops) {
+		diffWriteHunk(buffer, hunk)
+	}
+	return buffer.String()
+}
+
+// diffOp is an operation of the edit script that transforms the first text into the second. The
+// kind is ' ' for lines that are in both texts, '-' for lines that are only in the first and '+'
+// for lines that are only in the second. The from and to fields are the zero based positions in
+// the texts before applying the operation.
+type diffOp struct {
+	kind byte
+	line string
+	from int
+	to   int
+}
+
+// diffContext is the number of unchanged lines displayed before and after each change.
+const diffContext = 3
+
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOps calculates the edit script using the longest common subsequence of lines. This is
+// quadratic, but it is intended for small texts like the YAML representations of objects.
+func diffOps(from, to []string) []diffOp {
+	n, m := len(from), len(to)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && from[i] == to[j]:
+			ops = append(ops, diffOp{kind: ' ', line: from[i], from: i, to: j})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: from[i], from: i, to: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: to[j], from: i, to: j})
+			j++
+		}
+	}
+	return ops
+}
+
+// diffHunks groups the operations into hunks that contain the changes and the surrounding context.
+// Changes that are separated by less than twice the context are put in the same hunk.
+func diffHunks(ops []diffOp) [][]diffOp {
+	var hunks [][]diffOp
+	k := 0
+	for k < len(ops) {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for {
+			next := end + 1
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end-1 > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		hunks = append(hunks, ops[start:stop])
+		k = stop
+	}
+	return hunks
+}
+
+func diffWriteHunk(buffer *strings.Builder, hunk []diffOp) {
+	fromCount, toCount := 0, 0
+	for _, op := range hunk {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+	fromStart := hunk[0].from + 1
+	if fromCount == 0 {
+		fromStart--
+	}
+	toStart := hunk[0].to + 1
+	if toCount == 0 {
+		toStart--
+	}
+	fmt.Fprintf(buffer, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+	for _, op := range hunk {
+		fmt.Fprintf(buffer, "%c%s\n", op.kind, op.line)
+	}
+}
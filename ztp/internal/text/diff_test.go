@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package text
+
+import (
+	. "github.com/onsi/ginkgo/v2/dsl/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable(
+	"Diff",
+	func(from, to, expected string) {
+		actual := Diff("a", "b", from, to)
+		Expect(actual).To(Equal(expected))
+	},
+	Entry(
+		"Equal",
+		"x: 1\n",
+		"x: 1\n",
+		"",
+	),
+	Entry(
+		"Added to empty",
+		"",
+		"x: 1\n"+
+			"y: 2\n",
+		"--- a\n"+
+			"+++ b\n"+
+			"@@ -0,0 +1,2 @@\n"+
+			"+x: 1\n"+
+			"+y: 2\n",
+	),
+	Entry(
+		"Removed everything",
+		"x: 1\n"+
+			"y: 2\n",
+		"",
+		"--- a\n"+
+			"+++ b\n"+
+			"@@ -1,2 +0,0 @@\n"+
+			"-x: 1\n"+
+			"-y: 2\n",
+	),
+	Entry(
+		"Changed line with context",
+		"a: 1\n"+
+			"b: 2\n"+
+			"c: 3\n"+
+			"d: 4\n"+
+			"e: 5\n"+
+			"f: 6\n"+
+			"g: 7\n"+
+			"h: 8\n",
+		"a: 1\n"+
+			"b: 2\n"+
+			"c: 3\n"+
+			"d: 4\n"+
+			"e: 50\n"+
+			"f: 6\n"+
+			"g: 7\n"+
+			"h: 8\n",
+		"--- a\n"+
+			"+++ b\n"+
+			"@@ -2,7 +2,7 @@\n"+
+			" b: 2\n"+
+			" c: 3\n"+
+			" d: 4\n"+
+			"-e: 5\n"+
+			"+e: 50\n"+
+			" f: 6\n"+
+			" g: 7\n"+
+			" h: 8\n",
+	),
+	Entry(
+		"Separate hunks",
+		"a: 1\n"+
+			"b: 2\n"+
+			"c: 3\n"+
+			"d: 4\n"+
+			"e: 5\n"+
+			"f: 6\n"+
+			"g: 7\n"+
+			"h: 8\n"+
+			"i: 9\n"+
+			"j: 10\n",
+		"a: 10\n"+
+			"b: 2\n"+
+			"c: 3\n"+
+			"d: 4\n"+
+			"e: 5\n"+
+			"f: 6\n"+
+			"g: 7\n"+
+			"h: 8\n"+
+			"i: 9\n"+
+			"j: 100\n",
+		"--- a\n"+
+			"+++ b\n"+
+			"@@ -1,4 +1,4 @@\n"+
+			"-a: 1\n"+
+			"+a: 10\n"+
+			" b: 2\n"+
+			" c: 3\n"+
+			" d: 4\n"+
+			"@@ -7,4 +7,4 @@\n"+
+			" g: 7\n"+
+			" h: 8\n"+
+			" i: 9\n"+
+			"-j: 10\n"+
+			"+j: 100\n",
+	),
+)
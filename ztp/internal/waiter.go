@@ -0,0 +1,541 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaiterBuilder contains the data and logic needed to create an object that knows how to wait for
+// Kubernetes API objects to reach a desired state. Don't create instances of this type directly,
+// use the NewWaiter function instead.
+type WaiterBuilder struct {
+	logger    logr.Logger
+	client    clnt.WithWatch
+	timeout   time.Duration
+	listeners []func(*WaiterEvent)
+}
+
+// Waiter knows how to wait for Kubernetes API objects to reach a desired state. Each wait is a
+// stage with its own timeout, and when that timeout expires the returned error contains a snapshot
+// of the objects that were being waited for. Don't create instances of this type directly, use the
+// NewWaiter function instead.
+type Waiter struct {
+	logger    logr.Logger
+	client    clnt.WithWatch
+	timeout   time.Duration
+	listeners []func(*WaiterEvent)
+}
+
+// WaitStage describes something that the waiter should wait for.
+type WaitStage struct {
+	// Name describes what is being waited for, for example "hosts of cluster 'my' to be
+	// provisioned". It is used in the error message when the stage times out.
+	Name string
+
+	// Timeout is the maximum time to wait for this stage. If it is zero the default timeout of
+	// the waiter will be used. Note that the deadline of the context is always honored.
+	Timeout time.Duration
+
+	// Objects are the objects that are being waited for. Only the kind, namespace and name are
+	// needed. The current state of these objects is passed to the check function, and it is also
+	// included in the diagnostic snapshot when the stage times out.
+	Objects []*unstructured.Unstructured
+
+	// Check is called periodically with the current state of the objects, in the same order
+	// than the Objects field and with nil for the objects that don't exist. It should return
+	// true when the wait is completed. If it returns an error the wait ends with that error.
+	Check func(ctx context.Context, objects []*unstructured.Unstructured) (done bool, err error)
+}
+
+// WaiterEventType represents the type of a waiter event.
+type WaiterEventType string
+
+const (
+	// WaiterStageStarted indicates that the waiter started waiting for a stage.
+	WaiterStageStarted WaiterEventType = "StageStarted"
+
+	// WaiterStageCompleted indicates that the check function of the stage returned true.
+	WaiterStageCompleted WaiterEventType = "StageCompleted"
+
+	// WaiterStageTimedOut indicates that the timeout of the stage or the deadline of the
+	// context expired. The error field of the event will contain the *WaitError.
+	WaiterStageTimedOut WaiterEventType = "StageTimedOut"
+
+	// WaiterStageFailed indicates that the check function of the stage returned an error.
+	WaiterStageFailed WaiterEventType = "StageFailed"
+)
+
+// WaiterEvent represents an event generated by the waiter to inform of the transitions of the
+// stages.
+type WaiterEvent struct {
+	Type    WaiterEventType
+	Stage   string
+	Objects []*unstructured.Unstructured
+	Elapsed time.Duration
+	Error   error
+}
+
+// WaitError is the error returned by the waiter when a stage times out. It contains a snapshot of
+// the state of the objects that were being waited for.
+type WaitError struct {
+	// Stage is the name of the stage that timed out.
+	Stage string
+
+	// Elapsed is the time that the waiter waited before giving up.
+	Elapsed time.Duration
+
+	// Snapshots contains the last observed state of the objects of the stage.
+	Snapshots []*WaitSnapshot
+}
+
+// WaitSnapshot contains the last observed state of an object.
+type WaitSnapshot struct {
+	// Reference contains the kind, namespace and name of the object.
+	Reference *unstructured.Unstructured
+
+	// Object is the last observed state of the object, or nil if it doesn't exist.
+	Object *unstructured.Unstructured
+
+	// Conditions are the conditions from the status of the object.
+	Conditions []WaitCondition
+
+	// Events are the most recent Kubernetes events related to the object, oldest first.
+	Events []WaitEvent
+}
+
+// WaitCondition is a condition of an object included in a snapshot.
+type WaitCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// WaitEvent is a Kubernetes event included in a snapshot.
+type WaitEvent struct {
+	Type    string
+	Reason  string
+	Message string
+	Count   int32
+	Time    time.Time
+}
+
+// NewWaiter creates a builder that can then be used to create an object that knows how to wait
+// for Kubernetes API objects.
+func NewWaiter() *WaiterBuilder {
+	return &WaiterBuilder{}
+}
+
+// SetLogger sets the logger that the waiter will use to write to the log. This is mandatory.
+func (b *WaiterBuilder) SetLogger(value logr.Logger) *WaiterBuilder {
+	b.logger = value
+	return b
+}
+
+// SetClient sets the Kubernetes API client that the waiter will use. This is mandatory.
+func (b *WaiterBuilder) SetClient(value clnt.WithWatch) *WaiterBuilder {
+	b.client = value
+	return b
+}
+
+// SetTimeout sets the default timeout for the stages that don't have their own. The default is
+// zero, which means that only the deadline of the context is used.
+func (b *WaiterBuilder) SetTimeout(value time.Duration) *WaiterBuilder {
+	b.timeout = value
+	return b
+}
+
+// SetListener sets a function that will be called when the waiter starts waiting for a stage and
+// when that stage completes, fails or times out. This is optional.
+func (b *WaiterBuilder) SetListener(value func(*WaiterEvent)) *WaiterBuilder {
+	b.listeners = []func(*WaiterEvent){value}
+	return b
+}
+
+// AddListener adds a function that will be called when the waiter starts waiting for a stage and
+// when that stage completes, fails or times out. This is optional.
+func (b *WaiterBuilder) AddListener(value func(*WaiterEvent)) *WaiterBuilder {
+	b.listeners = append(b.listeners, value)
+	return b
+}
+
+// SetFlags sets the command line flags that indicate how to configure the waiter. This is
+// optional.
+func (b *WaiterBuilder) SetFlags(flags *pflag.FlagSet) *WaiterBuilder {
+	if flags.Changed(waiterTimeoutFlagName) {
+		value, err := flags.GetDuration(waiterTimeoutFlagName)
+		if err == nil {
+			b.timeout = value
+		}
+	}
+	return b
+}
+
+// Build uses the data stored in the builder to create a new waiter.
+func (b *WaiterBuilder) Build() (result *Waiter, err error) {
+	// Check parameters:
+	if b.logger.GetSink() == nil {
+		err = errors.New("logger is mandatory")
+		return
+	}
+	if b.client == nil {
+		err = errors.New("client is mandatory")
+		return
+	}
+	if b.timeout < 0 {
+		err = fmt.Errorf("timeout should be zero or positive, but it is %s", b.timeout)
+		return
+	}
+
+	// Create and populate the object:
+	result = &Waiter{
+		logger:    b.logger,
+		client:    b.client,
+		timeout:   b.timeout,
+		listeners: slices.Clone(b.listeners),
+	}
+	return
+}
+
+// Wait waits till the check function of the stage returns true, the check function returns an
+// error, or the timeout expires. In the last case the returned error will be a *WaitError
+// containing the snapshot of the objects of the stage.
+func (w *Waiter) Wait(ctx context.Context, stage *WaitStage) error {
+	// Calculate the timeout:
+	start := time.Now()
+	timeout := stage.Timeout
+	if timeout == 0 {
+		timeout = w.timeout
+	}
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	w.logger.V(1).Info(
+		"Waiting",
+		"stage", stage.Name,
+		"timeout", timeout,
+	)
+	w.fireEvent(&WaiterEvent{
+		Type:    WaiterStageStarted,
+		Stage:   stage.Name,
+		Objects: stage.Objects,
+	})
+
+	// Check periodically:
+	settings := backoff.NewExponentialBackOff()
+	settings.InitialInterval = waiterInitialInterval
+	settings.MaxInterval = waiterMaxInterval
+	settings.MaxElapsedTime = 0
+	operation := func() error {
+		current, err := w.fetchObjects(waitCtx, stage.Objects)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		done, err := stage.Check(waitCtx, current)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if !done {
+			return errWaiterPending
+		}
+		return nil
+	}
+	err := backoff.Retry(operation, backoff.WithContext(settings, waitCtx))
+	if err == nil {
+		w.logger.V(1).Info(
+			"Wait completed",
+			"stage", stage.Name,
+			"elapsed", time.Since(start),
+		)
+		w.fireEvent(&WaiterEvent{
+			Type:    WaiterStageCompleted,
+			Stage:   stage.Name,
+			Objects: stage.Objects,
+			Elapsed: time.Since(start),
+		})
+		return nil
+	}
+	if waitCtx.Err() == nil {
+		w.fireEvent(&WaiterEvent{
+			Type:    WaiterStageFailed,
+			Stage:   stage.Name,
+			Objects: stage.Objects,
+			Elapsed: time.Since(start),
+			Error:   err,
+		})
+		return err
+	}
+
+	// If we are here the stage timed out, either because of its own timeout or because of the
+	// deadline of the context, so we need to take the snapshot. Note that the context may be
+	// already expired, so we need a new one for that.
+	snapshotCtx, cancel := context.WithTimeout(context.Background(), waiterSnapshotTimeout)
+	defer cancel()
+	result := &WaitError{
+		Stage:   stage.Name,
+		Elapsed: time.Since(start).Round(time.Second),
+	}
+	for _, object := range stage.Objects {
+		snapshot, err := w.takeSnapshot(snapshotCtx, object)
+		if err != nil {
+			w.logger.Error(
+				err,
+				"Failed to take snapshot",
+				"kind", object.GetKind(),
+				"namespace", object.GetNamespace(),
+				"name", object.GetName(),
+			)
+			continue
+		}
+		result.Snapshots = append(result.Snapshots, snapshot)
+	}
+	w.fireEvent(&WaiterEvent{
+		Type:    WaiterStageTimedOut,
+		Stage:   stage.Name,
+		Objects: stage.Objects,
+		Elapsed: result.Elapsed,
+		Error:   result,
+	})
+	return result
+}
+
+func (w *Waiter) fireEvent(event *WaiterEvent) {
+	for _, listener := range w.listeners {
+		listener(event)
+	}
+}
+
+func (w *Waiter) fetchObjects(ctx context.Context,
+	objects []*unstructured.Unstructured) (results []*unstructured.Unstructured, err error) {
+	results = make([]*unstructured.Unstructured, len(objects))
+	for i, object := range objects {
+		results[i], err = w.fetchObject(ctx, object)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (w *Waiter) fetchObject(ctx context.Context,
+	object *unstructured.Unstructured) (result *unstructured.Unstructured, err error) {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(object.GroupVersionKind())
+	err = w.client.Get(ctx, clnt.ObjectKeyFromObject(object), current)
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	result = current
+	return
+}
+
+func (w *Waiter) takeSnapshot(ctx context.Context,
+	object *unstructured.Unstructured) (result *WaitSnapshot, err error) {
+	snapshot := &WaitSnapshot{
+		Reference: object,
+	}
+	snapshot.Object, err = w.fetchObject(ctx, object)
+	if err != nil {
+		return
+	}
+	if snapshot.Object != nil {
+		snapshot.Conditions = w.findConditions(snapshot.Object)
+	}
+	snapshot.Events, err = w.findEvents(ctx, object)
+	if err != nil {
+		return
+	}
+	result = snapshot
+	return
+}
+
+func (w *Waiter) findConditions(object *unstructured.Unstructured) []WaitCondition {
+	items, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+	var results []WaitCondition
+	for _, item := range items {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		condition := WaitCondition{}
+		condition.Type, _ = fields["type"].(string)
+		condition.Status, _ = fields["status"].(string)
+		condition.Reason, _ = fields["reason"].(string)
+		condition.Message, _ = fields["message"].(string)
+		results = append(results, condition)
+	}
+	return results
+}
+
+// findEvents finds the most recent Kubernetes events related to the given object. Events of
+// cluster scoped objects are in the 'default' namespace.
+func (w *Waiter) findEvents(ctx context.Context,
+	object *unstructured.Unstructured) (results []WaitEvent, err error) {
+	namespace := object.GetNamespace()
+	if namespace == "" {
+		namespace = "default"
+	}
+	list := &corev1.EventList{}
+	err = w.client.List(ctx, list, clnt.InNamespace(namespace))
+	if err != nil {
+		return
+	}
+	var events []WaitEvent
+	for _, item := range list.Items {
+		involved := item.InvolvedObject
+		if involved.Kind != object.GetKind() || involved.Name != object.GetName() {
+			continue
+		}
+		event := WaitEvent{
+			Type:    item.Type,
+			Reason:  item.Reason,
+			Message: item.Message,
+			Count:   item.Count,
+			Time:    item.LastTimestamp.Time,
+		}
+		if event.Time.IsZero() {
+			event.Time = item.EventTime.Time
+		}
+		if event.Time.IsZero() {
+			event.Time = item.CreationTimestamp.Time
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	if len(events) > waiterMaxEvents {
+		events = events[len(events)-waiterMaxEvents:]
+	}
+	results = events
+	return
+}
+
+// Error returns the error message. The snapshot isn't included, use the Report method to get it.
+func (e *WaitError) Error() string {
+	return fmt.Sprintf(
+		"timed out after %s while waiting for %s",
+		e.Elapsed, e.Stage,
+	)
+}
+
+// Timeout returns true, so that os.IsTimeout recognizes this error.
+func (e *WaitError) Timeout() bool {
+	return true
+}
+
+// Report generates a human readable description of the snapshot, containing for each object the
+// conditions, the recent events and the last observed status.
+func (e *WaitError) Report() string {
+	buffer := &strings.Builder{}
+	for _, snapshot := range e.Snapshots {
+		reference := snapshot.Reference
+		name := reference.GetName()
+		if reference.GetNamespace() != "" {
+			name = fmt.Sprintf("%s/%s", reference.GetNamespace(), name)
+		}
+		fmt.Fprintf(buffer, "%s '%s':\n", reference.GetKind(), name)
+		if snapshot.Object == nil {
+			fmt.Fprintf(buffer, "  Doesn't exist\n")
+		}
+		if len(snapshot.Conditions) > 0 {
+			fmt.Fprintf(buffer, "  Conditions:\n")
+			for _, condition := range snapshot.Conditions {
+				fmt.Fprintf(buffer, "    %s=%s", condition.Type, condition.Status)
+				if condition.Reason != "" {
+					fmt.Fprintf(buffer, " %s", condition.Reason)
+				}
+				if condition.Message != "" {
+					fmt.Fprintf(buffer, ": %s", condition.Message)
+				}
+				fmt.Fprintf(buffer, "\n")
+			}
+		}
+		if len(snapshot.Events) > 0 {
+			fmt.Fprintf(buffer, "  Recent events:\n")
+			for _, event := range snapshot.Events {
+				fmt.Fprintf(buffer, "    %s %s: %s", event.Type, event.Reason, event.Message)
+				if event.Count > 1 {
+					fmt.Fprintf(buffer, " (x%d)", event.Count)
+				}
+				fmt.Fprintf(buffer, "\n")
+			}
+		}
+		if snapshot.Object != nil {
+			status, ok := snapshot.Object.Object["status"]
+			if ok {
+				fmt.Fprintf(buffer, "  Status:\n")
+				buffer.WriteString(e.indentYAML(status, "    "))
+			}
+		}
+	}
+	return buffer.String()
+}
+
+func (e *WaitError) indentYAML(value any, prefix string) string {
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	err := encoder.Encode(value)
+	if err != nil {
+		return fmt.Sprintf("%s%v\n", prefix, err)
+	}
+	lines := strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// errWaiterPending is used to tell the backoff loop that the stage isn't completed yet and that it
+// should try again.
+var errWaiterPending = errors.New("stage isn't completed yet")
+
+// waiterMaxEvents is the maximum number of Kubernetes events included in the snapshot of each
+// object.
+const waiterMaxEvents = 5
+
+// waiterSnapshotTimeout is the maximum time used to take the snapshot after a timeout.
+const waiterSnapshotTimeout = 30 * time.Second
+
+// Intervals used to check again the state of the objects:
+const (
+	waiterInitialInterval = time.Second
+	waiterMaxInterval     = 15 * time.Second
+)
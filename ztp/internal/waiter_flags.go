@@ -0,0 +1,35 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// AddWaiterFlags adds the flags that control how the waits are done to the given flag set.
+func AddWaiterFlags(set *pflag.FlagSet) {
+	_ = set.Duration(
+		waiterTimeoutFlagName,
+		0,
+		"Maximum time to wait for each stage, for example for an object to be healthy. "+
+			"When a stage times out a snapshot of the objects is displayed. Set to "+
+			"zero to only use the overall timeout of the command, if any.",
+	)
+}
+
+// Names of the flags:
+const (
+	waiterTimeoutFlagName = "stage-timeout"
+)
@@ -0,0 +1,272 @@
+/*
+Copyright 2023 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except in
+compliance with the License. You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under the License is
+distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing permissions and limitations under the
+License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clnt "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rh-ecosystem-edge/ztp-pipeline-relocatable/ztp/internal/logging"
+)
+
+var _ = Describe("Waiter", func() {
+	var (
+		ctx    context.Context
+		logger logr.Logger
+		client clnt.WithWatch
+		waiter *Waiter
+		object *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		logger, err = logging.NewLogger().
+			SetWriter(GinkgoWriter).
+			SetLevel(2).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		client = fake.NewClientBuilder().Build()
+		waiter, err = NewWaiter().
+			SetLogger(logger).
+			SetClient(client).
+			SetTimeout(100 * time.Millisecond).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		object = &unstructured.Unstructured{}
+		object.SetAPIVersion("example.com/v1")
+		object.SetKind("Example")
+		object.SetNamespace("my-ns")
+		object.SetName("my-example")
+	})
+
+	// createExample creates the example object with a status that has a condition.
+	createExample := func() {
+		copy := object.DeepCopy()
+		copy.Object["status"] = map[string]any{
+			"phase": "Pending",
+			"conditions": []any{
+				map[string]any{
+					"type":    "Ready",
+					"status":  "False",
+					"reason":  "Waiting",
+					"message": "Waiting for something",
+				},
+			},
+		}
+		err := client.Create(ctx, copy)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// createEvent creates an event for the example object.
+	createEvent := func(name, reason, message string, delta time.Duration) {
+		event := &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-ns",
+				Name:      name,
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Example",
+				Name: "my-example",
+			},
+			Type:          "Warning",
+			Reason:        reason,
+			Message:       message,
+			Count:         1,
+			LastTimestamp: metav1.NewTime(time.Now().Add(delta)),
+		}
+		err := client.Create(ctx, event)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("Can't be created without a logger", func() {
+		_, err := NewWaiter().
+			SetClient(client).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("logger is mandatory"))
+	})
+
+	It("Can't be created with a negative timeout", func() {
+		_, err := NewWaiter().
+			SetLogger(logger).
+			SetClient(client).
+			SetTimeout(-time.Second).
+			Build()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("timeout should be zero or positive, but it is -1s"))
+	})
+
+	It("Passes the current state of the objects to the check", func() {
+		createExample()
+		var phase string
+		err := waiter.Wait(ctx, &WaitStage{
+			Name:    "example",
+			Objects: []*unstructured.Unstructured{object},
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				Expect(current).To(HaveLen(1))
+				Expect(current[0]).ToNot(BeNil())
+				phase, _, _ = unstructured.NestedString(
+					current[0].Object, "status", "phase",
+				)
+				return true, nil
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal("Pending"))
+	})
+
+	It("Passes nil for objects that don't exist", func() {
+		err := waiter.Wait(ctx, &WaitStage{
+			Name:    "example",
+			Objects: []*unstructured.Unstructured{object},
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				Expect(current).To(HaveLen(1))
+				Expect(current[0]).To(BeNil())
+				return true, nil
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Returns the error of the check", func() {
+		err := waiter.Wait(ctx, &WaitStage{
+			Name: "example",
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				return false, errors.New("my error")
+			},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("my error"))
+	})
+
+	It("Informs listeners of the transitions of the stages", func() {
+		var events []*WaiterEvent
+		waiter, err := NewWaiter().
+			SetLogger(logger).
+			SetClient(client).
+			SetTimeout(100 * time.Millisecond).
+			SetListener(func(event *WaiterEvent) {
+				events = append(events, event)
+			}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Completed stage:
+		err = waiter.Wait(ctx, &WaitStage{
+			Name: "completed",
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				return true, nil
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		// Stage that times out:
+		err = waiter.Wait(ctx, &WaitStage{
+			Name:    "timed out",
+			Objects: []*unstructured.Unstructured{object},
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				return false, nil
+			},
+		})
+		Expect(err).To(HaveOccurred())
+
+		// Check the events:
+		Expect(events).To(HaveLen(4))
+		Expect(events[0].Type).To(Equal(WaiterStageStarted))
+		Expect(events[0].Stage).To(Equal("completed"))
+		Expect(events[1].Type).To(Equal(WaiterStageCompleted))
+		Expect(events[1].Stage).To(Equal("completed"))
+		Expect(events[2].Type).To(Equal(WaiterStageStarted))
+		Expect(events[2].Stage).To(Equal("timed out"))
+		Expect(events[3].Type).To(Equal(WaiterStageTimedOut))
+		Expect(events[3].Stage).To(Equal("timed out"))
+		Expect(events[3].Objects).To(ConsistOf(object))
+		Expect(events[3].Error).To(MatchError(err))
+	})
+
+	It("Takes a snapshot when the stage times out", func() {
+		createExample()
+		createEvent("second", "Second", "Second event", -time.Minute)
+		createEvent("first", "First", "First event", -time.Hour)
+		err := waiter.Wait(ctx, &WaitStage{
+			Name:    "example to be ready",
+			Objects: []*unstructured.Unstructured{object},
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				return false, nil
+			},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(os.IsTimeout(err)).To(BeTrue())
+		Expect(err.Error()).To(Equal(
+			"timed out after 0s while waiting for example to be ready",
+		))
+		var waitErr *WaitError
+		Expect(errors.As(err, &waitErr)).To(BeTrue())
+		Expect(waitErr.Report()).To(Equal(
+			"Example 'my-ns/my-example':\n" +
+				"  Conditions:\n" +
+				"    Ready=False Waiting: Waiting for something\n" +
+				"  Recent events:\n" +
+				"    Warning First: First event\n" +
+				"    Warning Second: Second event\n" +
+				"  Status:\n" +
+				"    conditions:\n" +
+				"      - message: Waiting for something\n" +
+				"        reason: Waiting\n" +
+				"        status: \"False\"\n" +
+				"        type: Ready\n" +
+				"    phase: Pending\n",
+		))
+	})
+
+	It("Reports objects that don't exist", func() {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := waiter.Wait(ctx, &WaitStage{
+			Name:    "example to be ready",
+			Timeout: time.Hour,
+			Objects: []*unstructured.Unstructured{object},
+			Check: func(ctx context.Context,
+				current []*unstructured.Unstructured) (bool, error) {
+				return false, nil
+			},
+		})
+		Expect(err).To(HaveOccurred())
+		var waitErr *WaitError
+		Expect(errors.As(err, &waitErr)).To(BeTrue())
+		Expect(waitErr.Report()).To(Equal(
+			"Example 'my-ns/my-example':\n" +
+				"  Doesn't exist\n",
+		))
+	})
+})
@@ -34,9 +34,11 @@ func main() {
 		SetIn(os.Stdin).
 		SetOut(os.Stdout).
 		SetErr(os.Stderr).
+		AddCommand(cmd.Config).
 		AddCommand(cmd.Create).
 		AddCommand(cmd.Delete).
 		AddCommand(cmd.Dev).
+		AddCommand(cmd.Validate).
 		AddCommand(cmd.Version).
 		Build()
 	if err != nil {